@@ -0,0 +1,88 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+
+	styx "github.com/dataptive/styx/pkg/client"
+	"github.com/dataptive/styx/pkg/compress"
+
+	"github.com/spf13/pflag"
+)
+
+const (
+	defaultHost = "http://localhost:7123"
+	tokenEnv    = "STYX_TOKEN"
+)
+
+// ClientFlags holds the options shared by commands connecting to a server.
+type ClientFlags struct {
+	Host        *string
+	Token       *string
+	TLSCA       *string
+	TLSCert     *string
+	TLSKey      *string
+	TLSInsecure *bool
+	Compression *string
+}
+
+func AddClientFlags(flags *pflag.FlagSet) (cf *ClientFlags) {
+
+	cf = &ClientFlags{
+		Host:        flags.StringP("host", "H", defaultHost, ""),
+		Token:       flags.String("token", os.Getenv(tokenEnv), ""),
+		TLSCA:       flags.String("tls-ca", "", ""),
+		TLSCert:     flags.String("tls-cert", "", ""),
+		TLSKey:      flags.String("tls-key", "", ""),
+		TLSInsecure: flags.Bool("tls-insecure", false, ""),
+		Compression: flags.String("compression", "", ""),
+	}
+
+	return cf
+}
+
+// NewClient returns a client configured from flags, exiting on error.
+func (cf *ClientFlags) NewClient() (c *styx.Client) {
+
+	options := styx.DefaultClientOptions
+	options.Token = *cf.Token
+	options.Compression = *cf.Compression
+
+	if !compress.Supported(options.Compression) {
+		DisplayError(compress.ErrUnsupportedCodec)
+	}
+
+	tlsOptions := styx.TLSOptions{
+		CAFile:             *cf.TLSCA,
+		CertFile:           *cf.TLSCert,
+		KeyFile:            *cf.TLSKey,
+		InsecureSkipVerify: *cf.TLSInsecure,
+	}
+
+	if tlsOptions != (styx.TLSOptions{}) {
+
+		tlsConfig, err := styx.LoadTLSConfig(tlsOptions)
+		if err != nil {
+			DisplayError(err)
+		}
+
+		options.TLSConfig = tlsConfig
+	}
+
+	c = styx.NewClientWithOptions(*cf.Host, options)
+
+	return c
+}
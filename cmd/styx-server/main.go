@@ -81,7 +81,7 @@ func main() {
 		cmd.DisplayError(err)
 	}
 
-	styxServer, err := server.NewServer(serverConfig)
+	styxServer, err := server.NewServer(serverConfig, *configPath)
 	if err != nil {
 		logger.Fatal(err)
 	}
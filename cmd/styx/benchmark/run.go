@@ -31,8 +31,18 @@ Usage: styx benchmark [OPTIONS]
 
 Run benchmarks
 
+Options:
+	-b, --batch string	Batch records in styx protocol messages [on|off|compare] (default "on")
+	-n, --count int		Records per benchmark (default 100M, 10M and 1M records of 10, 100 and 1000 bytes)
+
 Global Options:
 	-H, --host string 	Server to connect to (default "http://localhost:7123")
+	    --token string	API token (default $STYX_TOKEN)
+	    --tls-ca string	CA certificate to verify the server with
+	    --tls-cert string	Client certificate
+	    --tls-key string	Client certificate key
+	    --tls-insecure	Skip server certificate verification
+	    --compression string	Compress record streams [gzip|snappy]
 	-h, --help 		Display help
 `
 
@@ -60,22 +70,37 @@ func displayMetrics(prefix string, producedRecords int, producedBytes int, elaps
 	)
 }
 
-func benchmarkProduce(host string, name string, size int, count int) (err error) {
+func displayComparison(unbatched float64, batched float64) {
 
-	fmt.Printf("--------------------------------------------------------------------------------\n")
-	fmt.Printf("* benchmarking PRODUCE with %d records of size %d\n", count, size)
+	fmt.Printf("  batch speedup x%.2f (%.2f records/s without batch, %.2f records/s with batch)\n", batched/unbatched, unbatched, batched)
+}
 
-	c := client.NewClient(host)
+func batchString(batch bool) (s string) {
+
+	if batch {
+		return "with batch"
+	}
+
+	return "without batch"
+}
+
+func benchmarkProduce(c *client.Client, name string, size int, count int, batch bool) (rate float64, err error) {
+
+	fmt.Printf("--------------------------------------------------------------------------------\n")
+	fmt.Printf("* benchmarking PRODUCE with %d records of size %d %s\n", count, size, batchString(batch))
 
 	_, err = c.CreateLog(name, client.DefaultLogConfig)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer c.DeleteLog(name)
 
-	producer, err := c.NewProducer(name, client.DefaultProducerOptions)
+	options := client.DefaultProducerOptions
+	options.Batch = batch
+
+	producer, err := c.NewProducer(name, options)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer producer.Close()
 
@@ -91,7 +116,7 @@ func benchmarkProduce(host string, name string, size int, count int) (err error)
 	for {
 		n, err := producer.Write(&r)
 		if err != nil {
-			return err
+			return 0, err
 		}
 
 		producedRecords += 1
@@ -108,57 +133,66 @@ func benchmarkProduce(host string, name string, size int, count int) (err error)
 		}
 	}
 
-	producer.Flush()
+	err = producer.Flush()
+	if err != nil {
+		return 0, err
+	}
+
+	elapsed := time.Since(start)
+	fmt.Printf("\033[1K\r")
+	displayMetrics("produced", producedRecords, producedBytes, elapsed)
 
 	fmt.Printf("\n")
 	fmt.Printf("  done\n")
 
-	return nil
+	return float64(producedRecords) / elapsed.Seconds(), nil
 }
 
-func benchmarkConsume(host string, name string, size int, count int) (err error) {
+func benchmarkConsume(c *client.Client, name string, size int, count int, batch bool) (rate float64, err error) {
 
 	fmt.Printf("--------------------------------------------------------------------------------\n")
-	fmt.Printf("* benchmarking CONSUME with %d records of size %d\n", count, size)
+	fmt.Printf("* benchmarking CONSUME with %d records of size %d %s\n", count, size, batchString(batch))
 	fmt.Printf("  preparing log ...\n")
 
-	c := client.NewClient(host)
-
 	_, err = c.CreateLog(name, client.DefaultLogConfig)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer c.DeleteLog(name)
 
 	producer, err := c.NewProducer(name, client.DefaultProducerOptions)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	payload := make([]byte, size)
 	r := log.Record(payload)
 
-	for {
+	for i := 0; i < count; i++ {
 		_, err := producer.Write(&r)
 		if err != nil {
-			return err
+			return 0, err
 		}
+	}
 
-		count -= 1
-
-		if count == 0 {
-			break
-		}
+	err = producer.Flush()
+	if err != nil {
+		return 0, err
 	}
 
-	producer.Flush()
-	producer.Close()
+	err = producer.Close()
+	if err != nil {
+		return 0, err
+	}
 
 	fmt.Printf("  starting ...\n")
 
-	consumer, err := c.NewConsumer(name, client.DefaultConsumerParams, client.DefaultConsumerOptions)
+	options := client.DefaultConsumerOptions
+	options.Batch = batch
+
+	consumer, err := c.NewConsumer(name, client.DefaultConsumerParams, options)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer consumer.Close()
 
@@ -176,7 +210,7 @@ func benchmarkConsume(host string, name string, size int, count int) (err error)
 		}
 
 		if err != nil {
-			return err
+			return 0, err
 		}
 
 		consumedRecords += 1
@@ -189,10 +223,14 @@ func benchmarkConsume(host string, name string, size int, count int) (err error)
 		}
 	}
 
+	elapsed := time.Since(start)
+	fmt.Printf("\033[1K\r")
+	displayMetrics("consumed", consumedRecords, consumedBytes, elapsed)
+
 	fmt.Printf("\n")
 	fmt.Printf("  done\n")
 
-	return nil
+	return float64(consumedRecords) / elapsed.Seconds(), nil
 }
 
 func RunBenchmark(args []string) {
@@ -200,7 +238,9 @@ func RunBenchmark(args []string) {
 	logName := "benchmark"
 
 	runOpts := pflag.NewFlagSet("benchmark", pflag.ContinueOnError)
-	host := runOpts.StringP("host", "H", "http://localhost:7123", "")
+	batch := runOpts.StringP("batch", "b", "on", "")
+	count := runOpts.IntP("count", "n", 0, "")
+	clientFlags := cmd.AddClientFlags(runOpts)
 	isHelp := runOpts.BoolP("help", "h", false, "")
 	runOpts.Usage = func() {
 		cmd.DisplayUsage(cmd.MisuseCode, benchmarkRunUsage)
@@ -215,6 +255,21 @@ func RunBenchmark(args []string) {
 		cmd.DisplayUsage(cmd.SuccessCode, benchmarkRunUsage)
 	}
 
+	var batchModes []bool
+
+	switch *batch {
+	case "on":
+		batchModes = []bool{true}
+	case "off":
+		batchModes = []bool{false}
+	case "compare":
+		batchModes = []bool{false, true}
+	default:
+		cmd.DisplayUsage(cmd.MisuseCode, benchmarkRunUsage)
+	}
+
+	c := clientFlags.NewClient()
+
 	fmt.Printf("%s\n", benchmarkLogo)
 
 	params := [][]int{
@@ -223,18 +278,36 @@ func RunBenchmark(args []string) {
 		{1000, 1000000},
 	}
 
-	for _, param := range params {
-		err = benchmarkProduce(*host, logName, param[0], param[1])
-		if err != nil {
-			cmd.DisplayError(err)
+	if *count > 0 {
+		for _, param := range params {
+			param[1] = *count
 		}
 	}
 
-	for _, param := range params {
-		err = benchmarkConsume(*host, logName, param[0], param[1])
-		if err != nil {
-			cmd.DisplayError(err)
-		}
+	benchmarks := []func(*client.Client, string, int, int, bool) (float64, error){
+		benchmarkProduce,
+		benchmarkConsume,
 	}
 
+	for _, benchmark := range benchmarks {
+
+		for _, param := range params {
+
+			rates := []float64{}
+
+			for _, batchMode := range batchModes {
+
+				rate, err := benchmark(c, logName, param[0], param[1], batchMode)
+				if err != nil {
+					cmd.DisplayError(err)
+				}
+
+				rates = append(rates, rate)
+			}
+
+			if len(rates) == 2 {
+				displayComparison(rates[0], rates[1])
+			}
+		}
+	}
 }
@@ -18,7 +18,6 @@ import (
 	"os"
 
 	"github.com/dataptive/styx/cmd"
-	styx "github.com/dataptive/styx/pkg/client"
 
 	"github.com/spf13/pflag"
 )
@@ -30,13 +29,19 @@ Backup log
 
 Global Options:
 	-H, --host string 	Server to connect to (default "http://localhost:7123")
+	    --token string	API token (default $STYX_TOKEN)
+	    --tls-ca string	CA certificate to verify the server with
+	    --tls-cert string	Client certificate
+	    --tls-key string	Client certificate key
+	    --tls-insecure	Skip server certificate verification
+	    --compression string	Compress record streams [gzip|snappy]
 	-h, --help 		Display help
 `
 
 func BackupLog(args []string) {
 
 	backupOpts := pflag.NewFlagSet("logs backup", pflag.ContinueOnError)
-	host := backupOpts.StringP("host", "H", "http://localhost:7123", "")
+	clientFlags := cmd.AddClientFlags(backupOpts)
 	isHelp := backupOpts.BoolP("help", "h", false, "")
 	backupOpts.Usage = func() {
 		cmd.DisplayUsage(cmd.MisuseCode, logsBackupUsage)
@@ -51,7 +56,7 @@ func BackupLog(args []string) {
 		cmd.DisplayUsage(cmd.SuccessCode, logsBackupUsage)
 	}
 
-	client := styx.NewClient(*host)
+	client := clientFlags.NewClient()
 
 	if backupOpts.NArg() != 1 {
 		cmd.DisplayUsage(cmd.MisuseCode, logsBackupUsage)
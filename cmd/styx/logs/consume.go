@@ -38,12 +38,19 @@ Options:
 	-w, --whence string	Reference from which position is computed [origin|start|end] (default "start")
 	-n, --count int		Maximum count of records to consume (cannot be used in association with --follow)
 	-F, --follow 		Wait for new records when reaching end of stream
+	--filter string		Only consume records matching filter [prefix:BYTES|regexp:EXPR|json:FIELD==VALUE]
 	-u, --unbuffered	Do not buffer reads
 	-b, --binary		Output binary records
 	-l, --line-ending   	Specify line-ending [cr|lf|crlf] for non binary record output
 
 Global Options:
 	-H, --host string 	Server to connect to (default "http://localhost:7123")
+	    --token string	API token (default $STYX_TOKEN)
+	    --tls-ca string	CA certificate to verify the server with
+	    --tls-cert string	Client certificate
+	    --tls-key string	Client certificate key
+	    --tls-insecure	Skip server certificate verification
+	    --compression string	Compress record streams [gzip|snappy]
 	-h, --help 		Display help
 `
 
@@ -58,10 +65,11 @@ func Consume(args []string) {
 	position := consumeOpts.Int64P("position", "P", styx.DefaultConsumerParams.Position, "")
 	count := consumeOpts.Int64P("count", "n", styx.DefaultConsumerParams.Count, "")
 	follow := consumeOpts.BoolP("follow", "F", styx.DefaultConsumerParams.Follow, "")
+	recordFilter := consumeOpts.String("filter", styx.DefaultConsumerParams.Filter, "")
 	unbuffered := consumeOpts.BoolP("unbuffered", "u", false, "")
 	binary := consumeOpts.BoolP("binary", "b", false, "")
 	lineEnding := consumeOpts.StringP("line-ending", "l", "lf", "")
-	host := consumeOpts.StringP("host", "H", "http://localhost:7123", "")
+	clientFlags := cmd.AddClientFlags(consumeOpts)
 	isHelp := consumeOpts.BoolP("help", "h", false, "")
 	consumeOpts.Usage = func() {
 		cmd.DisplayUsage(cmd.MisuseCode, logsConsumeUsage)
@@ -82,14 +90,17 @@ func Consume(args []string) {
 
 	name := consumeOpts.Args()[0]
 
-	client := styx.NewClient(*host)
+	client := clientFlags.NewClient()
 
-	logInfo, err := client.GetLog(name)
-	if err != nil {
-		cmd.DisplayError(err)
-	}
+	// Raw styx protocol listeners do not serve log details, the
+	// stream then ends on its own when reaching the end of the log.
+	if !*follow && *count == -1 && !styx.IsStyxURL(*clientFlags.Host) {
+
+		logInfo, err := client.GetLog(name)
+		if err != nil {
+			cmd.DisplayError(err)
+		}
 
-	if !*follow && *count == -1 {
 		count = &logInfo.RecordCount
 	}
 
@@ -98,6 +109,7 @@ func Consume(args []string) {
 		Position: *position,
 		Count:    *count,
 		Follow:   *follow,
+		Filter:   *recordFilter,
 	}
 
 	consumer, err := client.NewConsumer(name, params, styx.DefaultConsumerOptions)
@@ -24,7 +24,8 @@ import (
 const logsCreateUsage = `
 Usage: styx logs create NAME [OPTIONS]
 
-Create a new log
+Create a new log, options left unset defaulting to the settings of the
+namespaces holding the log
 
 Options:
 	--max-record-size bytes 	Maximum record size
@@ -35,10 +36,20 @@ Options:
 	--log-max-count records 	Expire oldest segment when log exceeds this number of records
 	--log-max-size bytes 		Expire oldest segment when log exceeds this size
 	--log-max-age seconds 		Expire oldest segment when log exceeds this age
+	--owner string			Team or person owning the log
+	--description string		Description of the log
+	--schema-url string		URL of the schema of the log records
+	-l, --label name=value		Label the log, may be repeated
 
 Global Options:
 	-f, --format string		Output format [text|json] (default "text")
 	-H, --host string 		Server to connect to (default "http://localhost:7123")
+	    --token string		API token (default $STYX_TOKEN)
+	    --tls-ca string		CA certificate to verify the server with
+	    --tls-cert string		Client certificate
+	    --tls-key string		Client certificate key
+	    --tls-insecure		Skip server certificate verification
+	    --compression string	Compress record streams [gzip|snappy]
 	-h, --help 			Display help
 `
 
@@ -53,16 +64,20 @@ end_position:	{{.EndPosition}}
 func CreateLog(args []string) {
 
 	createOpts := pflag.NewFlagSet("logs create", pflag.ContinueOnError)
-	maxRecordSize := createOpts.Int("max-record-size", styx.DefaultLogConfig.MaxRecordSize, "")
-	indexAfterSize := createOpts.Int64("index-after-size", styx.DefaultLogConfig.IndexAfterSize, "")
-	segmentMaxCount := createOpts.Int64("segment-max-count", styx.DefaultLogConfig.SegmentMaxCount, "")
-	segmentMaxSize := createOpts.Int64("segment-max-size", styx.DefaultLogConfig.SegmentMaxSize, "")
-	segmentMaxAge := createOpts.Int64("segment-max-age", styx.DefaultLogConfig.SegmentMaxAge, "")
-	logMaxCount := createOpts.Int64("log-max-count", styx.DefaultLogConfig.LogMaxCount, "")
-	logMaxSize := createOpts.Int64("log-max-size", styx.DefaultLogConfig.LogMaxSize, "")
-	logMaxAge := createOpts.Int64("log-max-age", styx.DefaultLogConfig.LogMaxAge, "")
+	maxRecordSize := createOpts.Int("max-record-size", 0, "")
+	indexAfterSize := createOpts.Int64("index-after-size", 0, "")
+	segmentMaxCount := createOpts.Int64("segment-max-count", 0, "")
+	segmentMaxSize := createOpts.Int64("segment-max-size", 0, "")
+	segmentMaxAge := createOpts.Int64("segment-max-age", 0, "")
+	logMaxCount := createOpts.Int64("log-max-count", 0, "")
+	logMaxSize := createOpts.Int64("log-max-size", 0, "")
+	logMaxAge := createOpts.Int64("log-max-age", 0, "")
+	owner := createOpts.String("owner", "", "")
+	description := createOpts.String("description", "", "")
+	schemaURL := createOpts.String("schema-url", "", "")
+	labels := createOpts.StringArrayP("label", "l", []string{}, "")
 	format := createOpts.StringP("format", "f", "text", "")
-	host := createOpts.StringP("host", "H", "http://localhost:7123", "")
+	clientFlags := cmd.AddClientFlags(createOpts)
 	isHelp := createOpts.BoolP("help", "h", false, "")
 	createOpts.Usage = func() {
 		cmd.DisplayUsage(cmd.MisuseCode, logsCreateUsage)
@@ -81,7 +96,12 @@ func CreateLog(args []string) {
 		cmd.DisplayUsage(cmd.MisuseCode, logsCreateUsage)
 	}
 
-	client := styx.NewClient(*host)
+	labelValues, valid := parseLabels(*labels)
+	if !valid {
+		cmd.DisplayUsage(cmd.MisuseCode, logsCreateUsage)
+	}
+
+	client := clientFlags.NewClient()
 
 	name := createOpts.Args()[0]
 	config := styx.LogConfig{
@@ -95,7 +115,14 @@ func CreateLog(args []string) {
 		LogMaxAge:       *logMaxAge,
 	}
 
-	log, err := client.CreateLog(name, config)
+	metadata := styx.LogMetadata{
+		Owner:       *owner,
+		Description: *description,
+		SchemaURL:   *schemaURL,
+		Labels:      labelValues,
+	}
+
+	log, err := client.CreateLogWithMetadata(name, config, metadata)
 	if err != nil {
 		cmd.DisplayError(err)
 	}
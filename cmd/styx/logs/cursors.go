@@ -0,0 +1,80 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs
+
+import (
+	"github.com/dataptive/styx/cmd"
+
+	"github.com/spf13/pflag"
+)
+
+const logsCursorsUsage = `
+Usage: styx logs cursors NAME [OPTIONS]
+
+List cursors committed by the consumers of a log
+
+Global Options:
+	-f, --format string	Output format [text|json] (default "text")
+	-H, --host string 	Server to connect to (default "http://localhost:7123")
+	    --token string	API token (default $STYX_TOKEN)
+	    --tls-ca string	CA certificate to verify the server with
+	    --tls-cert string	Client certificate
+	    --tls-key string	Client certificate key
+	    --tls-insecure	Skip server certificate verification
+	    --compression string	Compress record streams [gzip|snappy]
+	-h, --help 		Display help
+`
+
+const logsCursorsTmpl = `NAME	POSITION
+{{range .}}{{.Name}}	{{.Position}}
+{{end}}`
+
+func ListCursors(args []string) {
+
+	cursorsOpts := pflag.NewFlagSet("logs cursors", pflag.ContinueOnError)
+	format := cursorsOpts.StringP("format", "f", "default", "")
+	clientFlags := cmd.AddClientFlags(cursorsOpts)
+	isHelp := cursorsOpts.BoolP("help", "h", false, "")
+	cursorsOpts.Usage = func() {
+		cmd.DisplayUsage(cmd.MisuseCode, logsCursorsUsage)
+	}
+
+	err := cursorsOpts.Parse(args)
+	if err != nil {
+		cmd.DisplayUsage(cmd.MisuseCode, logsCursorsUsage)
+	}
+
+	if *isHelp {
+		cmd.DisplayUsage(cmd.SuccessCode, logsCursorsUsage)
+	}
+
+	client := clientFlags.NewClient()
+
+	if cursorsOpts.NArg() != 1 {
+		cmd.DisplayUsage(cmd.MisuseCode, logsCursorsUsage)
+	}
+
+	cursors, err := client.ListCursors(cursorsOpts.Arg(0))
+	if err != nil {
+		cmd.DisplayError(err)
+	}
+
+	if *format == "json" {
+		cmd.DisplayAsJSON(cursors)
+		return
+	}
+
+	cmd.DisplayAsDefault(logsCursorsTmpl, cursors)
+}
@@ -16,7 +16,6 @@ package logs
 
 import (
 	"github.com/dataptive/styx/cmd"
-	styx "github.com/dataptive/styx/pkg/client"
 
 	"github.com/spf13/pflag"
 )
@@ -28,13 +27,19 @@ Delete a log
 
 Global Options:
 	-H, --host string 	Server to connect to (default "http://localhost:7123")
+	    --token string	API token (default $STYX_TOKEN)
+	    --tls-ca string	CA certificate to verify the server with
+	    --tls-cert string	Client certificate
+	    --tls-key string	Client certificate key
+	    --tls-insecure	Skip server certificate verification
+	    --compression string	Compress record streams [gzip|snappy]
 	-h, --help 		Display help
 `
 
 func DeleteLog(args []string) {
 
 	deleteOpts := pflag.NewFlagSet("logs delete", pflag.ContinueOnError)
-	host := deleteOpts.StringP("host", "H", "http://localhost:7123", "")
+	clientFlags := cmd.AddClientFlags(deleteOpts)
 	isHelp := deleteOpts.BoolP("help", "h", false, "")
 	deleteOpts.Usage = func() {
 		cmd.DisplayUsage(cmd.MisuseCode, logsDeleteUsage)
@@ -49,7 +54,7 @@ func DeleteLog(args []string) {
 		cmd.DisplayUsage(cmd.SuccessCode, logsDeleteUsage)
 	}
 
-	client := styx.NewClient(*host)
+	client := clientFlags.NewClient()
 
 	if deleteOpts.NArg() != 1 {
 		cmd.DisplayUsage(cmd.MisuseCode, logsDeleteUsage)
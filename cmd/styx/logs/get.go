@@ -16,7 +16,6 @@ package logs
 
 import (
 	"github.com/dataptive/styx/cmd"
-	styx "github.com/dataptive/styx/pkg/client"
 
 	"github.com/spf13/pflag"
 )
@@ -29,6 +28,12 @@ Show log details
 Global Options:
 	-f, --format string	Output format [text|json] (default "text")
 	-H, --host string 	Server to connect to (default "http://localhost:7123")
+	    --token string	API token (default $STYX_TOKEN)
+	    --tls-ca string	CA certificate to verify the server with
+	    --tls-cert string	Client certificate
+	    --tls-key string	Client certificate key
+	    --tls-insecure	Skip server certificate verification
+	    --compression string	Compress record streams [gzip|snappy]
 	-h, --help 		Display help
 `
 
@@ -38,12 +43,21 @@ record_count:	{{.RecordCount}}
 file_size:	{{.FileSize}}
 start_position:	{{.StartPosition}}
 end_position:	{{.EndPosition}}
+write_records_rate:	{{printf "%.1f" .WriteRecordsRate}}
+write_bytes_rate:	{{printf "%.1f" .WriteBytesRate}}
+read_records_rate:	{{printf "%.1f" .ReadRecordsRate}}
+read_bytes_rate:	{{printf "%.1f" .ReadBytesRate}}
+owner:	{{.Owner}}
+description:	{{.Description}}
+schema_url:	{{.SchemaURL}}
+labels:	{{range $name, $value := .Labels}}{{$name}}={{$value}} {{end}}
+last_scan:	{{with .LastScan}}{{.Status}} at {{.Time}}{{if .Error}} ({{.Error}}){{end}}{{end}}
 `
 
 func GetLog(args []string) {
 
 	getOpts := pflag.NewFlagSet("logs get", pflag.ContinueOnError)
-	host := getOpts.StringP("host", "H", "http://localhost:7123", "")
+	clientFlags := cmd.AddClientFlags(getOpts)
 	format := getOpts.StringP("format", "f", "text", "")
 	isHelp := getOpts.BoolP("help", "h", false, "")
 	getOpts.Usage = func() {
@@ -59,7 +73,7 @@ func GetLog(args []string) {
 		cmd.DisplayUsage(cmd.SuccessCode, logsGetUsage)
 	}
 
-	client := styx.NewClient(*host)
+	client := clientFlags.NewClient()
 
 	if getOpts.NArg() != 1 {
 		cmd.DisplayUsage(cmd.MisuseCode, logsGetUsage)
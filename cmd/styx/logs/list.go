@@ -29,10 +29,20 @@ Usage: styx logs list [OPTIONS]
 
 List available logs
 
+Options:
+	-n, --namespace string	Only list logs nested in namespace
+	-l, --label name=value	Only list logs with label, value being optional, may be repeated
+
 Global Options:
 	-w, --watch		Display and update informations about logs
 	-f, --format string	Output format [text|json] (default "text")
 	-H, --host string 	Server to connect to (default "http://localhost:7123")
+	    --token string	API token (default $STYX_TOKEN)
+	    --tls-ca string	CA certificate to verify the server with
+	    --tls-cert string	Client certificate
+	    --tls-key string	Client certificate key
+	    --tls-insecure	Skip server certificate verification
+	    --compression string	Compress record streams [gzip|snappy]
 	-h, --help 		Display help
 `
 
@@ -43,9 +53,11 @@ const logsListTmpl = `NAME	STATUS	RECORD COUNT	FILE SIZE	START POSITION	END POSI
 func ListLogs(args []string) {
 
 	listOpts := pflag.NewFlagSet("logs list", pflag.ContinueOnError)
+	namespace := listOpts.StringP("namespace", "n", "", "")
+	labels := listOpts.StringArrayP("label", "l", []string{}, "")
 	watch := listOpts.BoolP("watch", "w", false, "")
 	format := listOpts.StringP("format", "f", "default", "")
-	host := listOpts.StringP("host", "H", "http://localhost:7123", "")
+	clientFlags := cmd.AddClientFlags(listOpts)
 	isHelp := listOpts.BoolP("help", "h", false, "")
 	listOpts.Usage = func() {
 		cmd.DisplayUsage(cmd.MisuseCode, logsListUsage)
@@ -60,14 +72,19 @@ func ListLogs(args []string) {
 		cmd.DisplayUsage(cmd.SuccessCode, logsListUsage)
 	}
 
-	client := styx.NewClient(*host)
+	client := clientFlags.NewClient()
 
 	if listOpts.NArg() != 0 {
 		cmd.DisplayUsage(cmd.MisuseCode, logsListUsage)
 	}
 
+	params := styx.ListLogsParams{
+		Namespace: *namespace,
+		Labels:    *labels,
+	}
+
 	for {
-		logs, err := client.ListLogs()
+		logs, err := client.ListLogsWithParams(params)
 		if err != nil {
 			cmd.DisplayError(err)
 		}
@@ -0,0 +1,120 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs
+
+import (
+	"strings"
+
+	"github.com/dataptive/styx/cmd"
+	styx "github.com/dataptive/styx/pkg/client"
+
+	"github.com/spf13/pflag"
+)
+
+const logsMetadataUsage = `
+Usage: styx logs metadata NAME [OPTIONS]
+
+Replace the metadata of a log, options left unset being cleared
+
+Options:
+	    --owner string		Team or person owning the log
+	    --description string	Description of the log
+	    --schema-url string		URL of the schema of the log records
+	-l, --label name=value		Label the log, may be repeated
+
+Global Options:
+	-f, --format string		Output format [text|json] (default "text")
+	-H, --host string 		Server to connect to (default "http://localhost:7123")
+	    --token string		API token (default $STYX_TOKEN)
+	    --tls-ca string		CA certificate to verify the server with
+	    --tls-cert string		Client certificate
+	    --tls-key string		Client certificate key
+	    --tls-insecure		Skip server certificate verification
+	    --compression string	Compress record streams [gzip|snappy]
+	-h, --help 			Display help
+`
+
+func SetLogMetadata(args []string) {
+
+	metadataOpts := pflag.NewFlagSet("logs metadata", pflag.ContinueOnError)
+	owner := metadataOpts.String("owner", "", "")
+	description := metadataOpts.String("description", "", "")
+	schemaURL := metadataOpts.String("schema-url", "", "")
+	labels := metadataOpts.StringArrayP("label", "l", []string{}, "")
+	format := metadataOpts.StringP("format", "f", "text", "")
+	clientFlags := cmd.AddClientFlags(metadataOpts)
+	isHelp := metadataOpts.BoolP("help", "h", false, "")
+	metadataOpts.Usage = func() {
+		cmd.DisplayUsage(cmd.MisuseCode, logsMetadataUsage)
+	}
+
+	err := metadataOpts.Parse(args)
+	if err != nil {
+		cmd.DisplayUsage(cmd.MisuseCode, logsMetadataUsage)
+	}
+
+	if *isHelp {
+		cmd.DisplayUsage(cmd.SuccessCode, logsMetadataUsage)
+	}
+
+	if metadataOpts.NArg() != 1 {
+		cmd.DisplayUsage(cmd.MisuseCode, logsMetadataUsage)
+	}
+
+	labelValues, valid := parseLabels(*labels)
+	if !valid {
+		cmd.DisplayUsage(cmd.MisuseCode, logsMetadataUsage)
+	}
+
+	client := clientFlags.NewClient()
+
+	metadata := styx.LogMetadata{
+		Owner:       *owner,
+		Description: *description,
+		SchemaURL:   *schemaURL,
+		Labels:      labelValues,
+	}
+
+	log, err := client.SetLogMetadata(metadataOpts.Args()[0], metadata)
+	if err != nil {
+		cmd.DisplayError(err)
+	}
+
+	if *format == "json" {
+		cmd.DisplayAsJSON(log)
+		return
+	}
+
+	cmd.DisplayAsDefault(logsGetTmpl, log)
+}
+
+// parseLabels parses "name=value" labels, valid being false when one is
+// malformed.
+func parseLabels(labels []string) (values map[string]string, valid bool) {
+
+	values = make(map[string]string, len(labels))
+
+	for _, label := range labels {
+
+		parts := strings.SplitN(label, "=", 2)
+		if len(parts) != 2 {
+			return nil, false
+		}
+
+		values[parts[0]] = parts[1]
+	}
+
+	return values, true
+}
@@ -40,6 +40,12 @@ Options:
 
 Global Options:
 	-H, --host string 	Server to connect to (default "http://localhost:7123")
+	    --token string	API token (default $STYX_TOKEN)
+	    --tls-ca string	CA certificate to verify the server with
+	    --tls-cert string	Client certificate
+	    --tls-key string	Client certificate key
+	    --tls-insecure	Skip server certificate verification
+	    --compression string	Compress record streams [gzip|snappy]
 	-h, --help 		Display help
 `
 
@@ -53,7 +59,7 @@ func Produce(args []string) {
 	unbuffered := produceOpts.BoolP("unbuffered", "u", false, "")
 	binary := produceOpts.BoolP("binary", "b", false, "")
 	lineEnding := produceOpts.StringP("line-ending", "l", "lf", "")
-	host := produceOpts.StringP("host", "H", "http://localhost:7123", "")
+	clientFlags := cmd.AddClientFlags(produceOpts)
 	isHelp := produceOpts.BoolP("help", "h", false, "")
 	produceOpts.Usage = func() {
 		cmd.DisplayUsage(cmd.MisuseCode, logsProduceUsage)
@@ -74,13 +80,12 @@ func Produce(args []string) {
 
 	name := produceOpts.Args()[0]
 
-	client := styx.NewClient(*host)
+	client := clientFlags.NewClient()
 
 	producer, err := client.NewProducer(name, styx.DefaultProducerOptions)
 	if err != nil {
 		cmd.DisplayError(err)
 	}
-	defer producer.Close()
 
 	var reader recio.Reader
 	var decoder recio.Decoder
@@ -142,4 +147,9 @@ func Produce(args []string) {
 	if err != nil {
 		cmd.DisplayError(err)
 	}
+
+	err = producer.Close()
+	if err != nil {
+		cmd.DisplayError(err)
+	}
 }
@@ -0,0 +1,88 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs
+
+import (
+	"github.com/dataptive/styx/cmd"
+
+	"github.com/spf13/pflag"
+)
+
+const logsRenameUsage = `
+Usage: styx logs rename NAME NEW_NAME [OPTIONS]
+
+Rename a log, possibly moving it to another namespace
+
+Options:
+	    --alias-ttl seconds	Keep the log reachable under its previous name for this duration
+
+Global Options:
+	-f, --format string	Output format [text|json] (default "text")
+	-H, --host string 	Server to connect to (default "http://localhost:7123")
+	    --token string	API token (default $STYX_TOKEN)
+	    --tls-ca string	CA certificate to verify the server with
+	    --tls-cert string	Client certificate
+	    --tls-key string	Client certificate key
+	    --tls-insecure	Skip server certificate verification
+	    --compression string	Compress record streams [gzip|snappy]
+	-h, --help 		Display help
+`
+
+const logsRenameTmpl = `name:	{{.Name}}
+status:	{{.Status}}
+record_count:	{{.RecordCount}}
+file_size:	{{.FileSize}}
+start_position:	{{.StartPosition}}
+end_position:	{{.EndPosition}}
+`
+
+func RenameLog(args []string) {
+
+	renameOpts := pflag.NewFlagSet("logs rename", pflag.ContinueOnError)
+	aliasTTL := renameOpts.Int("alias-ttl", 0, "")
+	format := renameOpts.StringP("format", "f", "text", "")
+	clientFlags := cmd.AddClientFlags(renameOpts)
+	isHelp := renameOpts.BoolP("help", "h", false, "")
+	renameOpts.Usage = func() {
+		cmd.DisplayUsage(cmd.MisuseCode, logsRenameUsage)
+	}
+
+	err := renameOpts.Parse(args)
+	if err != nil {
+		cmd.DisplayUsage(cmd.MisuseCode, logsRenameUsage)
+	}
+
+	if *isHelp {
+		cmd.DisplayUsage(cmd.SuccessCode, logsRenameUsage)
+	}
+
+	if renameOpts.NArg() != 2 {
+		cmd.DisplayUsage(cmd.MisuseCode, logsRenameUsage)
+	}
+
+	client := clientFlags.NewClient()
+
+	log, err := client.RenameLog(renameOpts.Args()[0], renameOpts.Args()[1], *aliasTTL)
+	if err != nil {
+		cmd.DisplayError(err)
+	}
+
+	if *format == "json" {
+		cmd.DisplayAsJSON(log)
+		return
+	}
+
+	cmd.DisplayAsDefault(logsRenameTmpl, log)
+}
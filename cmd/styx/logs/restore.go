@@ -18,7 +18,6 @@ import (
 	"os"
 
 	"github.com/dataptive/styx/cmd"
-	styx "github.com/dataptive/styx/pkg/client"
 
 	"github.com/spf13/pflag"
 )
@@ -30,12 +29,18 @@ Restore log
 
 Global Options:
 	-H, --host string 	Server to connect to (default "http://localhost:7123")
+	    --token string	API token (default $STYX_TOKEN)
+	    --tls-ca string	CA certificate to verify the server with
+	    --tls-cert string	Client certificate
+	    --tls-key string	Client certificate key
+	    --tls-insecure	Skip server certificate verification
+	    --compression string	Compress record streams [gzip|snappy]
 	-h, --help 		Display help
 `
 
 func RestoreLog(args []string) {
 	restoreOpts := pflag.NewFlagSet("logs backup", pflag.ContinueOnError)
-	host := restoreOpts.StringP("host", "H", "http://localhost:7123", "")
+	clientFlags := cmd.AddClientFlags(restoreOpts)
 	isHelp := restoreOpts.BoolP("help", "h", false, "")
 	restoreOpts.Usage = func() {
 		cmd.DisplayUsage(cmd.MisuseCode, logsRestoreUsage)
@@ -50,7 +55,7 @@ func RestoreLog(args []string) {
 		cmd.DisplayUsage(cmd.SuccessCode, logsRestoreUsage)
 	}
 
-	client := styx.NewClient(*host)
+	client := clientFlags.NewClient()
 
 	if restoreOpts.NArg() != 1 {
 		cmd.DisplayUsage(cmd.MisuseCode, logsRestoreUsage)
@@ -0,0 +1,83 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs
+
+import (
+	"github.com/dataptive/styx/cmd"
+
+	"github.com/spf13/pflag"
+)
+
+const logsScanUsage = `
+Usage: styx logs scan NAME [OPTIONS]
+
+Check and repair the files of a log, and reopen it
+
+Global Options:
+	-f, --format string	Output format [text|json] (default "text")
+	-H, --host string 	Server to connect to (default "http://localhost:7123")
+	    --token string	API token (default $STYX_TOKEN)
+	    --tls-ca string	CA certificate to verify the server with
+	    --tls-cert string	Client certificate
+	    --tls-key string	Client certificate key
+	    --tls-insecure	Skip server certificate verification
+	    --compression string	Compress record streams [gzip|snappy]
+	-h, --help 		Display help
+`
+
+const logsScanTmpl = `name:	{{.Name}}
+status:	{{.Status}}
+{{with .LastScan}}scan_time:	{{.Time}}
+scan_status:	{{.Status}}
+scan_error:	{{.Error}}
+{{end}}`
+
+func ScanLog(args []string) {
+
+	scanOpts := pflag.NewFlagSet("logs scan", pflag.ContinueOnError)
+	format := scanOpts.StringP("format", "f", "text", "")
+	clientFlags := cmd.AddClientFlags(scanOpts)
+	isHelp := scanOpts.BoolP("help", "h", false, "")
+	scanOpts.Usage = func() {
+		cmd.DisplayUsage(cmd.MisuseCode, logsScanUsage)
+	}
+
+	err := scanOpts.Parse(args)
+	if err != nil {
+		cmd.DisplayUsage(cmd.MisuseCode, logsScanUsage)
+	}
+
+	if *isHelp {
+		cmd.DisplayUsage(cmd.SuccessCode, logsScanUsage)
+	}
+
+	client := clientFlags.NewClient()
+
+	if scanOpts.NArg() != 1 {
+		cmd.DisplayUsage(cmd.MisuseCode, logsScanUsage)
+	}
+
+	log, err := client.ScanLog(scanOpts.Arg(0))
+	if err != nil {
+		cmd.DisplayError(err)
+	}
+
+	if *format == "json" {
+		cmd.DisplayAsJSON(log)
+		return
+	}
+
+	cmd.DisplayAsDefault(logsScanTmpl, log)
+}
@@ -0,0 +1,98 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dataptive/styx/cmd"
+
+	"github.com/spf13/pflag"
+)
+
+const logsSessionsUsage = `
+Usage: styx logs sessions NAME [OPTIONS]
+
+List consumers connected to a log
+
+Global Options:
+	-w, --watch		Display and update informations about consumers
+	-f, --format string	Output format [text|json] (default "text")
+	-H, --host string 	Server to connect to (default "http://localhost:7123")
+	    --token string	API token (default $STYX_TOKEN)
+	    --tls-ca string	CA certificate to verify the server with
+	    --tls-cert string	Client certificate
+	    --tls-key string	Client certificate key
+	    --tls-insecure	Skip server certificate verification
+	    --compression string	Compress record streams [gzip|snappy]
+	-h, --help 		Display help
+`
+
+const logsSessionsTmpl = `ID	CLIENT	REMOTE ADDRESS	SENT POSITION	PROCESSED POSITION	LAG	CURSOR
+{{range .}}{{.ID}}	{{.Client}}	{{.RemoteAddr}}	{{.SentPosition}}	{{.ProcessedPosition}}	{{.Lag}}	{{.Cursor}}
+{{end}}`
+
+func ListSessions(args []string) {
+
+	sessionsOpts := pflag.NewFlagSet("logs sessions", pflag.ContinueOnError)
+	watch := sessionsOpts.BoolP("watch", "w", false, "")
+	format := sessionsOpts.StringP("format", "f", "default", "")
+	clientFlags := cmd.AddClientFlags(sessionsOpts)
+	isHelp := sessionsOpts.BoolP("help", "h", false, "")
+	sessionsOpts.Usage = func() {
+		cmd.DisplayUsage(cmd.MisuseCode, logsSessionsUsage)
+	}
+
+	err := sessionsOpts.Parse(args)
+	if err != nil {
+		cmd.DisplayUsage(cmd.MisuseCode, logsSessionsUsage)
+	}
+
+	if *isHelp {
+		cmd.DisplayUsage(cmd.SuccessCode, logsSessionsUsage)
+	}
+
+	client := clientFlags.NewClient()
+
+	if sessionsOpts.NArg() != 1 {
+		cmd.DisplayUsage(cmd.MisuseCode, logsSessionsUsage)
+	}
+
+	for {
+		sessions, err := client.ListSessions(sessionsOpts.Arg(0))
+		if err != nil {
+			cmd.DisplayError(err)
+		}
+
+		if *watch {
+			// Clear terminal
+			fmt.Printf("\033[H\033[2J")
+		}
+
+		if *format == "json" {
+			cmd.DisplayAsJSON(sessions)
+
+		} else {
+			cmd.DisplayAsDefault(logsSessionsTmpl, sessions)
+		}
+
+		if *watch {
+			time.Sleep(1 * time.Second)
+		} else {
+			return
+		}
+	}
+}
@@ -16,7 +16,6 @@ package logs
 
 import (
 	"github.com/dataptive/styx/cmd"
-	styx "github.com/dataptive/styx/pkg/client"
 
 	"github.com/spf13/pflag"
 )
@@ -28,13 +27,19 @@ Truncate a log
 
 Global Options:
 	-H, --host string 	Server to connect to (default "http://localhost:7123")
+	    --token string	API token (default $STYX_TOKEN)
+	    --tls-ca string	CA certificate to verify the server with
+	    --tls-cert string	Client certificate
+	    --tls-key string	Client certificate key
+	    --tls-insecure	Skip server certificate verification
+	    --compression string	Compress record streams [gzip|snappy]
 	-h, --help 		Display help
 `
 
 func TruncateLog(args []string) {
 
 	truncateOpts := pflag.NewFlagSet("logs truncate", pflag.ContinueOnError)
-	host := truncateOpts.StringP("host", "H", "http://localhost:7123", "")
+	clientFlags := cmd.AddClientFlags(truncateOpts)
 	isHelp := truncateOpts.BoolP("help", "h", false, "")
 	truncateOpts.Usage = func() {
 		cmd.DisplayUsage(cmd.MisuseCode, logsTruncateUsage)
@@ -49,7 +54,7 @@ func TruncateLog(args []string) {
 		cmd.DisplayUsage(cmd.SuccessCode, logsTruncateUsage)
 	}
 
-	client := styx.NewClient(*host)
+	client := clientFlags.NewClient()
 
 	if truncateOpts.NArg() != 1 {
 		cmd.DisplayUsage(cmd.MisuseCode, logsTruncateUsage)
@@ -0,0 +1,101 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs
+
+import (
+	"github.com/dataptive/styx/cmd"
+	styx "github.com/dataptive/styx/pkg/client"
+
+	"github.com/spf13/pflag"
+)
+
+const (
+	// Number of seconds each poll waits for events.
+	watchTimeout = 30
+)
+
+const logsWatchUsage = `
+Usage: styx logs watch [OPTIONS]
+
+Watch log lifecycle events, such as creations, deletions and status changes
+
+Options:
+	    --since int 	Start after the event with this ID, only new events being watched by default
+
+Global Options:
+	-f, --format string	Output format [text|json] (default "text")
+	-H, --host string 	Server to connect to (default "http://localhost:7123")
+	    --token string	API token (default $STYX_TOKEN)
+	    --tls-ca string	CA certificate to verify the server with
+	    --tls-cert string	Client certificate
+	    --tls-key string	Client certificate key
+	    --tls-insecure	Skip server certificate verification
+	    --compression string	Compress record streams [gzip|snappy]
+	-h, --help 		Display help
+`
+
+const logsWatchTmpl = `{{range .}}{{.ID}} {{.Time.Format "2006-01-02T15:04:05.000Z07:00"}} {{.Type}} {{.Name}} {{.Status}}{{if .PreviousStatus}} (was {{.PreviousStatus}}){{end}}{{if .PreviousName}} (was {{.PreviousName}}){{end}}
+{{end}}`
+
+func WatchEvents(args []string) {
+
+	watchOpts := pflag.NewFlagSet("logs watch", pflag.ContinueOnError)
+	since := watchOpts.Int64("since", -1, "")
+	format := watchOpts.StringP("format", "f", "text", "")
+	clientFlags := cmd.AddClientFlags(watchOpts)
+	isHelp := watchOpts.BoolP("help", "h", false, "")
+	watchOpts.Usage = func() {
+		cmd.DisplayUsage(cmd.MisuseCode, logsWatchUsage)
+	}
+
+	err := watchOpts.Parse(args)
+	if err != nil {
+		cmd.DisplayUsage(cmd.MisuseCode, logsWatchUsage)
+	}
+
+	if *isHelp {
+		cmd.DisplayUsage(cmd.SuccessCode, logsWatchUsage)
+	}
+
+	client := clientFlags.NewClient()
+
+	if watchOpts.NArg() != 0 {
+		cmd.DisplayUsage(cmd.MisuseCode, logsWatchUsage)
+	}
+
+	params := styx.ListEventsParams{
+		Since:   *since,
+		Timeout: watchTimeout,
+	}
+
+	for {
+		events, lastID, err := client.ListEvents(params)
+		if err != nil {
+			cmd.DisplayError(err)
+		}
+
+		params.Since = lastID
+
+		if *format == "json" {
+			for _, event := range events {
+				cmd.DisplayAsJSON(event)
+			}
+
+			continue
+		}
+
+		cmd.DisplayAsDefault(logsWatchTmpl, events)
+	}
+}
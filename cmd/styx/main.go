@@ -20,6 +20,8 @@ import (
 	"github.com/dataptive/styx/cmd"
 	"github.com/dataptive/styx/cmd/styx/benchmark"
 	"github.com/dataptive/styx/cmd/styx/logs"
+	"github.com/dataptive/styx/cmd/styx/namespaces"
+	"github.com/dataptive/styx/cmd/styx/server"
 )
 
 const (
@@ -30,11 +32,19 @@ A command line interface (CLI) for the Styx API.
 
 Commands:
 	logs 		Manage logs
+	namespaces	Manage namespaces
+	server		Manage the server
 	benchmark	Run benchmarks
 
 Global Options:
 	-f, --format string 	Output format [text|json] (default "text")
 	-H, --host string 	Server to connect to (default "http://localhost:7123")
+	    --token string	API token (default $STYX_TOKEN)
+	    --tls-ca string	CA certificate to verify the server with
+	    --tls-cert string	Client certificate
+	    --tls-key string	Client certificate key
+	    --tls-insecure	Skip server certificate verification
+	    --compression string	Compress record streams [gzip|snappy]
 	-h, --help 		Display help
 `
 
@@ -48,15 +58,71 @@ Commands:
 	create			Create a new log
 	get			Show log details
 	delete			Delete a log
+	rename			Rename a log
+	metadata		Set log metadata
 	truncate                Truncate a log
+	scan			Check and repair the files of a log
+	sessions		List consumers connected to a log
+	cursors			List cursors committed to a log
 	backup			Backup a log
 	restore			Restore a log
 	produce			Produce records to a log
 	consume			Consume records from a log
+	watch			Watch log lifecycle events
 
 Global Options:
 	-f, --format string	Output format [text|json] (default "text")
 	-H, --host string 	Server to connect to (default "http://localhost:7123")
+	    --token string	API token (default $STYX_TOKEN)
+	    --tls-ca string	CA certificate to verify the server with
+	    --tls-cert string	Client certificate
+	    --tls-key string	Client certificate key
+	    --tls-insecure	Skip server certificate verification
+	    --compression string	Compress record streams [gzip|snappy]
+	-h, --help 		Display help
+`
+
+	namespacesUsage = `
+Usage: styx namespaces COMMAND
+
+Manage namespaces
+
+Commands:
+	list			List namespaces
+	get			Show namespace details
+	set			Create a namespace or replace its settings
+	delete			Delete a namespace and all its logs
+
+Global Options:
+	-f, --format string	Output format [text|json] (default "text")
+	-H, --host string 	Server to connect to (default "http://localhost:7123")
+	    --token string	API token (default $STYX_TOKEN)
+	    --tls-ca string	CA certificate to verify the server with
+	    --tls-cert string	Client certificate
+	    --tls-key string	Client certificate key
+	    --tls-insecure	Skip server certificate verification
+	    --compression string	Compress record streams [gzip|snappy]
+	-h, --help 		Display help
+`
+
+	serverUsage = `
+Usage: styx server COMMAND
+
+Manage the server
+
+Commands:
+	status			Show server status
+	reload			Reload the server config file
+
+Global Options:
+	-f, --format string	Output format [text|json] (default "text")
+	-H, --host string 	Server to connect to (default "http://localhost:7123")
+	    --token string	API token (default $STYX_TOKEN)
+	    --tls-ca string	CA certificate to verify the server with
+	    --tls-cert string	Client certificate
+	    --tls-key string	Client certificate key
+	    --tls-insecure	Skip server certificate verification
+	    --compression string	Compress record streams [gzip|snappy]
 	-h, --help 		Display help
 `
 )
@@ -87,8 +153,18 @@ func main() {
 			logs.GetLog(args[1:])
 		case "delete":
 			logs.DeleteLog(args[1:])
+		case "rename":
+			logs.RenameLog(args[1:])
+		case "metadata":
+			logs.SetLogMetadata(args[1:])
 		case "truncate":
 			logs.TruncateLog(args[1:])
+		case "scan":
+			logs.ScanLog(args[1:])
+		case "sessions":
+			logs.ListSessions(args[1:])
+		case "cursors":
+			logs.ListCursors(args[1:])
 		case "backup":
 			logs.BackupLog(args[1:])
 		case "restore":
@@ -97,6 +173,8 @@ func main() {
 			logs.Produce(args[1:])
 		case "consume":
 			logs.Consume(args[1:])
+		case "watch":
+			logs.WatchEvents(args[1:])
 		case "--help":
 			cmd.DisplayUsage(cmd.SuccessCode, logsUsage)
 		case "-h":
@@ -105,6 +183,52 @@ func main() {
 			cmd.DisplayUsage(cmd.MisuseCode, logsUsage)
 		}
 
+	case "namespaces":
+
+		if len(args) < 2 {
+			cmd.DisplayUsage(cmd.MisuseCode, namespacesUsage)
+		}
+
+		args = args[1:]
+
+		switch args[0] {
+		case "list":
+			namespaces.ListNamespaces(args[1:])
+		case "get":
+			namespaces.GetNamespace(args[1:])
+		case "set":
+			namespaces.SetNamespace(args[1:])
+		case "delete":
+			namespaces.DeleteNamespace(args[1:])
+		case "--help":
+			cmd.DisplayUsage(cmd.SuccessCode, namespacesUsage)
+		case "-h":
+			cmd.DisplayUsage(cmd.SuccessCode, namespacesUsage)
+		default:
+			cmd.DisplayUsage(cmd.MisuseCode, namespacesUsage)
+		}
+
+	case "server":
+
+		if len(args) < 2 {
+			cmd.DisplayUsage(cmd.MisuseCode, serverUsage)
+		}
+
+		args = args[1:]
+
+		switch args[0] {
+		case "status":
+			server.GetStatus(args[1:])
+		case "reload":
+			server.Reload(args[1:])
+		case "--help":
+			cmd.DisplayUsage(cmd.SuccessCode, serverUsage)
+		case "-h":
+			cmd.DisplayUsage(cmd.SuccessCode, serverUsage)
+		default:
+			cmd.DisplayUsage(cmd.MisuseCode, serverUsage)
+		}
+
 	case "benchmark":
 
 		args = args[1:]
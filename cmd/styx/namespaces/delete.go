@@ -0,0 +1,67 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespaces
+
+import (
+	"github.com/dataptive/styx/cmd"
+
+	"github.com/spf13/pflag"
+)
+
+const namespacesDeleteUsage = `
+Usage: styx namespaces delete NAME [OPTIONS]
+
+Delete a namespace along with all the logs and namespaces it holds
+
+Global Options:
+	-H, --host string 	Server to connect to (default "http://localhost:7123")
+	    --token string	API token (default $STYX_TOKEN)
+	    --tls-ca string	CA certificate to verify the server with
+	    --tls-cert string	Client certificate
+	    --tls-key string	Client certificate key
+	    --tls-insecure	Skip server certificate verification
+	    --compression string	Compress record streams [gzip|snappy]
+	-h, --help 		Display help
+`
+
+func DeleteNamespace(args []string) {
+
+	deleteOpts := pflag.NewFlagSet("namespaces delete", pflag.ContinueOnError)
+	clientFlags := cmd.AddClientFlags(deleteOpts)
+	isHelp := deleteOpts.BoolP("help", "h", false, "")
+	deleteOpts.Usage = func() {
+		cmd.DisplayUsage(cmd.MisuseCode, namespacesDeleteUsage)
+	}
+
+	err := deleteOpts.Parse(args)
+	if err != nil {
+		cmd.DisplayUsage(cmd.MisuseCode, namespacesDeleteUsage)
+	}
+
+	if *isHelp {
+		cmd.DisplayUsage(cmd.SuccessCode, namespacesDeleteUsage)
+	}
+
+	client := clientFlags.NewClient()
+
+	if deleteOpts.NArg() != 1 {
+		cmd.DisplayUsage(cmd.MisuseCode, namespacesDeleteUsage)
+	}
+
+	err = client.DeleteNamespace(deleteOpts.Args()[0])
+	if err != nil {
+		cmd.DisplayError(err)
+	}
+}
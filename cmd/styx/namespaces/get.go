@@ -0,0 +1,91 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespaces
+
+import (
+	"github.com/dataptive/styx/cmd"
+
+	"github.com/spf13/pflag"
+)
+
+const namespacesGetUsage = `
+Usage: styx namespaces get NAME [OPTIONS]
+
+Show namespace details
+
+Global Options:
+	-f, --format string	Output format [text|json] (default "text")
+	-H, --host string 	Server to connect to (default "http://localhost:7123")
+	    --token string	API token (default $STYX_TOKEN)
+	    --tls-ca string	CA certificate to verify the server with
+	    --tls-cert string	Client certificate
+	    --tls-key string	Client certificate key
+	    --tls-insecure	Skip server certificate verification
+	    --compression string	Compress record streams [gzip|snappy]
+	-h, --help 		Display help
+`
+
+const namespacesGetTmpl = `name:	{{.Name}}
+log_count:	{{.LogCount}}
+usage:	{{.Usage}}
+max_logs:	{{.MaxLogs}}
+max_usage:	{{.MaxUsage}}
+max_record_size:	{{.LogConfig.MaxRecordSize}}
+index_after_size:	{{.LogConfig.IndexAfterSize}}
+segment_max_count:	{{.LogConfig.SegmentMaxCount}}
+segment_max_size:	{{.LogConfig.SegmentMaxSize}}
+segment_max_age:	{{.LogConfig.SegmentMaxAge}}
+log_max_count:	{{.LogConfig.LogMaxCount}}
+log_max_size:	{{.LogConfig.LogMaxSize}}
+log_max_age:	{{.LogConfig.LogMaxAge}}
+`
+
+func GetNamespace(args []string) {
+
+	getOpts := pflag.NewFlagSet("namespaces get", pflag.ContinueOnError)
+	clientFlags := cmd.AddClientFlags(getOpts)
+	format := getOpts.StringP("format", "f", "text", "")
+	isHelp := getOpts.BoolP("help", "h", false, "")
+	getOpts.Usage = func() {
+		cmd.DisplayUsage(cmd.MisuseCode, namespacesGetUsage)
+	}
+
+	err := getOpts.Parse(args)
+	if err != nil {
+		cmd.DisplayUsage(cmd.MisuseCode, namespacesGetUsage)
+	}
+
+	if *isHelp {
+		cmd.DisplayUsage(cmd.SuccessCode, namespacesGetUsage)
+	}
+
+	client := clientFlags.NewClient()
+
+	if getOpts.NArg() != 1 {
+		cmd.DisplayUsage(cmd.MisuseCode, namespacesGetUsage)
+	}
+
+	namespace, err := client.GetNamespace(getOpts.Args()[0])
+	if err != nil {
+		cmd.DisplayError(err)
+	}
+
+	if *format == "json" {
+		cmd.DisplayAsJSON(namespace)
+		return
+	}
+
+	cmd.DisplayAsDefault(namespacesGetTmpl, namespace)
+}
@@ -0,0 +1,80 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespaces
+
+import (
+	"github.com/dataptive/styx/cmd"
+
+	"github.com/spf13/pflag"
+)
+
+const namespacesListUsage = `
+Usage: styx namespaces list [OPTIONS]
+
+List namespaces
+
+Global Options:
+	-f, --format string	Output format [text|json] (default "text")
+	-H, --host string 	Server to connect to (default "http://localhost:7123")
+	    --token string	API token (default $STYX_TOKEN)
+	    --tls-ca string	CA certificate to verify the server with
+	    --tls-cert string	Client certificate
+	    --tls-key string	Client certificate key
+	    --tls-insecure	Skip server certificate verification
+	    --compression string	Compress record streams [gzip|snappy]
+	-h, --help 		Display help
+`
+
+const namespacesListTmpl = `NAME	LOG COUNT	USAGE	MAX LOGS	MAX USAGE
+{{range .}}{{.Name}}	{{.LogCount}}	{{.Usage}}	{{.MaxLogs}}	{{.MaxUsage}}
+{{end}}`
+
+func ListNamespaces(args []string) {
+
+	listOpts := pflag.NewFlagSet("namespaces list", pflag.ContinueOnError)
+	format := listOpts.StringP("format", "f", "default", "")
+	clientFlags := cmd.AddClientFlags(listOpts)
+	isHelp := listOpts.BoolP("help", "h", false, "")
+	listOpts.Usage = func() {
+		cmd.DisplayUsage(cmd.MisuseCode, namespacesListUsage)
+	}
+
+	err := listOpts.Parse(args)
+	if err != nil {
+		cmd.DisplayUsage(cmd.MisuseCode, namespacesListUsage)
+	}
+
+	if *isHelp {
+		cmd.DisplayUsage(cmd.SuccessCode, namespacesListUsage)
+	}
+
+	client := clientFlags.NewClient()
+
+	if listOpts.NArg() != 0 {
+		cmd.DisplayUsage(cmd.MisuseCode, namespacesListUsage)
+	}
+
+	namespaces, err := client.ListNamespaces()
+	if err != nil {
+		cmd.DisplayError(err)
+	}
+
+	if *format == "json" {
+		cmd.DisplayAsJSON(namespaces)
+		return
+	}
+
+	cmd.DisplayAsDefault(namespacesListTmpl, namespaces)
+}
@@ -0,0 +1,132 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespaces
+
+import (
+	"github.com/dataptive/styx/cmd"
+	styx "github.com/dataptive/styx/pkg/client"
+
+	"github.com/spf13/pflag"
+)
+
+const namespacesSetUsage = `
+Usage: styx namespaces set NAME [OPTIONS]
+
+Create a namespace or replace its settings. Log options are the defaults of
+logs created in the namespace, options left unset defaulting to the settings
+of the parent namespaces.
+
+Options:
+	--max-logs count		Maximum number of logs nested in the namespace
+	--max-usage bytes		Reject writes when logs nested in the namespace exceed this size
+	--max-record-size bytes 	Maximum record size
+	--index-after-size bytes 	Write a segment index entry after every size
+	--segment-max-count records	Create a new segment when current segment exceeds this number of records
+	--segment-max-size bytes	Create a new segment when current segment exceeds this size
+	--segment-max-age seconds	Create a new segment when current segment exceeds this age
+	--log-max-count records 	Expire oldest segment when log exceeds this number of records
+	--log-max-size bytes 		Expire oldest segment when log exceeds this size
+	--log-max-age seconds 		Expire oldest segment when log exceeds this age
+
+Global Options:
+	-f, --format string		Output format [text|json] (default "text")
+	-H, --host string 	Server to connect to (default "http://localhost:7123")
+	    --token string	API token (default $STYX_TOKEN)
+	    --tls-ca string	CA certificate to verify the server with
+	    --tls-cert string	Client certificate
+	    --tls-key string	Client certificate key
+	    --tls-insecure	Skip server certificate verification
+	    --compression string	Compress record streams [gzip|snappy]
+	-h, --help 			Display help
+`
+
+const namespacesSetTmpl = `name:	{{.Name}}
+log_count:	{{.LogCount}}
+usage:	{{.Usage}}
+max_logs:	{{.MaxLogs}}
+max_usage:	{{.MaxUsage}}
+max_record_size:	{{.LogConfig.MaxRecordSize}}
+index_after_size:	{{.LogConfig.IndexAfterSize}}
+segment_max_count:	{{.LogConfig.SegmentMaxCount}}
+segment_max_size:	{{.LogConfig.SegmentMaxSize}}
+segment_max_age:	{{.LogConfig.SegmentMaxAge}}
+log_max_count:	{{.LogConfig.LogMaxCount}}
+log_max_size:	{{.LogConfig.LogMaxSize}}
+log_max_age:	{{.LogConfig.LogMaxAge}}
+`
+
+func SetNamespace(args []string) {
+
+	setOpts := pflag.NewFlagSet("namespaces set", pflag.ContinueOnError)
+	maxLogs := setOpts.Int64("max-logs", 0, "")
+	maxUsage := setOpts.Int64("max-usage", 0, "")
+	maxRecordSize := setOpts.Int("max-record-size", 0, "")
+	indexAfterSize := setOpts.Int64("index-after-size", 0, "")
+	segmentMaxCount := setOpts.Int64("segment-max-count", 0, "")
+	segmentMaxSize := setOpts.Int64("segment-max-size", 0, "")
+	segmentMaxAge := setOpts.Int64("segment-max-age", 0, "")
+	logMaxCount := setOpts.Int64("log-max-count", 0, "")
+	logMaxSize := setOpts.Int64("log-max-size", 0, "")
+	logMaxAge := setOpts.Int64("log-max-age", 0, "")
+	format := setOpts.StringP("format", "f", "text", "")
+	clientFlags := cmd.AddClientFlags(setOpts)
+	isHelp := setOpts.BoolP("help", "h", false, "")
+	setOpts.Usage = func() {
+		cmd.DisplayUsage(cmd.MisuseCode, namespacesSetUsage)
+	}
+
+	err := setOpts.Parse(args)
+	if err != nil {
+		cmd.DisplayUsage(cmd.MisuseCode, namespacesSetUsage)
+	}
+
+	if *isHelp {
+		cmd.DisplayUsage(cmd.SuccessCode, namespacesSetUsage)
+	}
+
+	if setOpts.NArg() != 1 {
+		cmd.DisplayUsage(cmd.MisuseCode, namespacesSetUsage)
+	}
+
+	client := clientFlags.NewClient()
+
+	name := setOpts.Args()[0]
+	config := styx.NamespaceConfig{
+		LogConfig: styx.LogConfig{
+			MaxRecordSize:   *maxRecordSize,
+			IndexAfterSize:  *indexAfterSize,
+			SegmentMaxCount: *segmentMaxCount,
+			SegmentMaxSize:  *segmentMaxSize,
+			SegmentMaxAge:   *segmentMaxAge,
+			LogMaxCount:     *logMaxCount,
+			LogMaxSize:      *logMaxSize,
+			LogMaxAge:       *logMaxAge,
+		},
+		MaxLogs:  *maxLogs,
+		MaxUsage: *maxUsage,
+	}
+
+	namespace, err := client.SetNamespace(name, config)
+	if err != nil {
+		cmd.DisplayError(err)
+	}
+
+	if *format == "json" {
+		cmd.DisplayAsJSON(namespace)
+		return
+	}
+
+	cmd.DisplayAsDefault(namespacesSetTmpl, namespace)
+}
@@ -0,0 +1,79 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"github.com/dataptive/styx/cmd"
+
+	"github.com/spf13/pflag"
+)
+
+const serverReloadUsage = `
+Usage: styx server reload [OPTIONS]
+
+Reload the server config file
+
+Global Options:
+	-f, --format string	Output format [text|json] (default "text")
+	-H, --host string 	Server to connect to (default "http://localhost:7123")
+	    --token string	API token (default $STYX_TOKEN)
+	    --tls-ca string	CA certificate to verify the server with
+	    --tls-cert string	Client certificate
+	    --tls-key string	Client certificate key
+	    --tls-insecure	Skip server certificate verification
+	    --compression string	Compress record streams [gzip|snappy]
+	-h, --help 		Display help
+`
+
+const serverReloadTmpl = `{{range .RestartRequired}}restart required:	{{.}}
+{{end}}`
+
+func Reload(args []string) {
+
+	reloadOpts := pflag.NewFlagSet("server reload", pflag.ContinueOnError)
+	clientFlags := cmd.AddClientFlags(reloadOpts)
+	format := reloadOpts.StringP("format", "f", "text", "")
+	isHelp := reloadOpts.BoolP("help", "h", false, "")
+	reloadOpts.Usage = func() {
+		cmd.DisplayUsage(cmd.MisuseCode, serverReloadUsage)
+	}
+
+	err := reloadOpts.Parse(args)
+	if err != nil {
+		cmd.DisplayUsage(cmd.MisuseCode, serverReloadUsage)
+	}
+
+	if *isHelp {
+		cmd.DisplayUsage(cmd.SuccessCode, serverReloadUsage)
+	}
+
+	client := clientFlags.NewClient()
+
+	if reloadOpts.NArg() != 0 {
+		cmd.DisplayUsage(cmd.MisuseCode, serverReloadUsage)
+	}
+
+	reloaded, err := client.Reload()
+	if err != nil {
+		cmd.DisplayError(err)
+	}
+
+	if *format == "json" {
+		cmd.DisplayAsJSON(reloaded)
+		return
+	}
+
+	cmd.DisplayAsDefault(serverReloadTmpl, reloaded)
+}
@@ -0,0 +1,86 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"github.com/dataptive/styx/cmd"
+
+	"github.com/spf13/pflag"
+)
+
+const serverStatusUsage = `
+Usage: styx server status [OPTIONS]
+
+Show server status
+
+Global Options:
+	-f, --format string	Output format [text|json] (default "text")
+	-H, --host string 	Server to connect to (default "http://localhost:7123")
+	    --token string	API token (default $STYX_TOKEN)
+	    --tls-ca string	CA certificate to verify the server with
+	    --tls-cert string	Client certificate
+	    --tls-key string	Client certificate key
+	    --tls-insecure	Skip server certificate verification
+	    --compression string	Compress record streams [gzip|snappy]
+	-h, --help 		Display help
+`
+
+const serverStatusTmpl = `version:	{{.Version}}
+uptime:	{{.Uptime}}
+ready:	{{.Ready}}
+connections:	{{.Connections}}
+storage_status:	{{.Storage.Status}}
+storage_usage:	{{.Storage.Usage}}
+storage_free:	{{.Storage.Free}}
+{{range $status, $count := .Logs}}logs_{{$status}}:	{{$count}}
+{{end}}`
+
+func GetStatus(args []string) {
+
+	statusOpts := pflag.NewFlagSet("server status", pflag.ContinueOnError)
+	clientFlags := cmd.AddClientFlags(statusOpts)
+	format := statusOpts.StringP("format", "f", "text", "")
+	isHelp := statusOpts.BoolP("help", "h", false, "")
+	statusOpts.Usage = func() {
+		cmd.DisplayUsage(cmd.MisuseCode, serverStatusUsage)
+	}
+
+	err := statusOpts.Parse(args)
+	if err != nil {
+		cmd.DisplayUsage(cmd.MisuseCode, serverStatusUsage)
+	}
+
+	if *isHelp {
+		cmd.DisplayUsage(cmd.SuccessCode, serverStatusUsage)
+	}
+
+	client := clientFlags.NewClient()
+
+	if statusOpts.NArg() != 0 {
+		cmd.DisplayUsage(cmd.MisuseCode, serverStatusUsage)
+	}
+
+	status, err := client.GetStatus()
+	if err != nil {
+		cmd.DisplayError(err)
+	}
+
+	if *format == "json" {
+		cmd.DisplayAsJSON(status)
+		return
+	}
+
+	cmd.DisplayAsDefault(serverStatusTmpl, status)
+}
@@ -0,0 +1,272 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logman
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/dataptive/styx/pkg/logger"
+)
+
+const (
+	aliasesFilename = "aliases"
+)
+
+// alias keeps a renamed log reachable under its previous name until it
+// expires.
+type alias struct {
+	target  string
+	expires time.Time
+}
+
+// aliasEntry is an alias as persisted in a file next to the config of the log
+// it points to, so that aliases survive restarts.
+type aliasEntry struct {
+	Name    string    `json:"name"`
+	Expires time.Time `json:"expires"`
+}
+
+// ResolveName returns the name of the log an unexpired alias points to, or
+// name itself when it isn't an alias.
+func (lm *LogManager) ResolveName(name string) (resolved string) {
+
+	lm.logsLock.Lock()
+	defer lm.logsLock.Unlock()
+
+	_, exists := lm.logs[name]
+	if exists {
+		return name
+	}
+
+	ml := lm.resolveAlias(name)
+	if ml == nil {
+		return name
+	}
+
+	return ml.name
+}
+
+// resolveAlias returns the log an unexpired alias points to.
+func (lm *LogManager) resolveAlias(name string) (ml *Log) {
+
+	a, exists := lm.aliases[name]
+	if !exists || time.Now().After(a.expires) {
+		return nil
+	}
+
+	return lm.lookupLog(a.target)
+}
+
+// addAlias makes name point to target for ttl, retargeting the aliases of
+// name so that they follow successive renames.
+func (lm *LogManager) addAlias(name string, target string, ttl time.Duration) {
+
+	for _, a := range lm.aliases {
+		if a.target == name {
+			a.target = target
+		}
+	}
+
+	if ttl <= 0 {
+		return
+	}
+
+	lm.aliases[name] = &alias{
+		target:  target,
+		expires: time.Now().Add(ttl),
+	}
+}
+
+// dropAlias drops the alias name, returning the log it pointed to or an
+// empty string when there was none.
+func (lm *LogManager) dropAlias(name string) (target string) {
+
+	a, exists := lm.aliases[name]
+	if !exists {
+		return ""
+	}
+
+	delete(lm.aliases, name)
+
+	return a.target
+}
+
+// dropAliases drops expired aliases.
+func (lm *LogManager) dropAliases(now time.Time) {
+
+	targets := []string{}
+
+	lm.aliasesLock.Lock()
+	defer lm.aliasesLock.Unlock()
+
+	lm.logsLock.Lock()
+
+	for name, a := range lm.aliases {
+
+		if now.Before(a.expires) {
+			continue
+		}
+
+		logger.Infof("logman: alias \"%s\" of log \"%s\" expired", name, a.target)
+
+		delete(lm.aliases, name)
+
+		targets = append(targets, a.target)
+	}
+
+	files := lm.snapshotAliases(targets...)
+
+	lm.logsLock.Unlock()
+
+	lm.writeAliases(files)
+}
+
+// aliasesFile holds the aliases pointing to a log, as taken along with the
+// changes made to them, to be written next to its config.
+type aliasesFile struct {
+	target  string
+	entries []aliasEntry
+}
+
+// snapshotAliases returns the aliases pointing to each target, skipping
+// empty names and logs that were deleted or renamed along with their aliases
+// file. The lock is held.
+func (lm *LogManager) snapshotAliases(targets ...string) (files []aliasesFile) {
+
+	for _, target := range targets {
+
+		_, exists := lm.logs[target]
+		if target == "" || !exists {
+			continue
+		}
+
+		entries := []aliasEntry{}
+
+		for name, a := range lm.aliases {
+
+			if a.target != target {
+				continue
+			}
+
+			entries = append(entries, aliasEntry{
+				Name:    name,
+				Expires: a.expires,
+			})
+		}
+
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Name < entries[j].Name
+		})
+
+		files = append(files, aliasesFile{
+			target:  target,
+			entries: entries,
+		})
+	}
+
+	return files
+}
+
+// writeAliases persists aliases files once the lock is released. Callers hold
+// aliasesLock from before taking their snapshot, so that files are written in
+// the order aliases changed and always hold the latest aliases.
+func (lm *LogManager) writeAliases(files []aliasesFile) {
+
+	for _, file := range files {
+
+		err := dumpAliases(filepath.Join(lm.config.DataDirectory, file.target), file.entries)
+		if err != nil {
+			logger.Warn("logman:", err)
+		}
+	}
+}
+
+// restoreAliases registers the unexpired aliases persisted next to the config
+// of target, unless their name was taken by another log since.
+func (lm *LogManager) restoreAliases(target string) (err error) {
+
+	entries, err := loadAliases(filepath.Join(lm.config.DataDirectory, target))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	for _, entry := range entries {
+
+		_, exists := lm.logs[entry.Name]
+		if exists || now.After(entry.Expires) {
+			continue
+		}
+
+		lm.aliases[entry.Name] = &alias{
+			target:  target,
+			expires: entry.Expires,
+		}
+	}
+
+	return nil
+}
+
+func loadAliases(pathname string) (entries []aliasEntry, err error) {
+
+	data, err := ioutil.ReadFile(filepath.Join(pathname, aliasesFilename))
+	if os.IsNotExist(err) {
+		return []aliasEntry{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	entries = []aliasEntry{}
+
+	err = json.Unmarshal(data, &entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// dumpAliases writes the aliases to a temporary file renamed over the previous
+// one, so that a crash never leaves a partially written file.
+func dumpAliases(pathname string, entries []aliasEntry) (err error) {
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	filename := filepath.Join(pathname, aliasesFilename)
+	tmpFilename := filename + ".tmp"
+
+	err = ioutil.WriteFile(tmpFilename, data, os.FileMode(0644))
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmpFilename, filename)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -26,4 +26,9 @@ type Config struct {
 	DataDirectory   string
 	ReadBufferSize  int
 	WriteBufferSize int
+	LogLimits       Limits
+	ClientLimits    Limits
+	Quota           Quota
+	Templates       []Template
+	DefaultTemplate *Template
 }
@@ -0,0 +1,167 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logman
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	cursorsFilename = "cursors"
+)
+
+var (
+	ErrInvalidCursor = errors.New("logman: invalid cursor name")
+)
+
+// cursors holds the named positions committed by the consumers of a log,
+// persisted in a file next to the log config.
+type cursors struct {
+	pathname  string
+	positions map[string]int64
+	loaded    bool
+	lock      sync.Mutex
+}
+
+func newCursors(path string) (c *cursors) {
+
+	c = &cursors{
+		pathname:  filepath.Join(path, cursorsFilename),
+		positions: map[string]int64{},
+		loaded:    false,
+	}
+
+	return c
+}
+
+func (c *cursors) list() (positions map[string]int64, err error) {
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	err = c.load()
+	if err != nil {
+		return nil, err
+	}
+
+	positions = make(map[string]int64, len(c.positions))
+
+	for name, position := range c.positions {
+		positions[name] = position
+	}
+
+	return positions, nil
+}
+
+func (c *cursors) commit(name string, position int64) (err error) {
+
+	valid := segmentRegexp.MatchString(name)
+	if !valid {
+		return ErrInvalidCursor
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	err = c.load()
+	if err != nil {
+		return err
+	}
+
+	previous, exists := c.positions[name]
+	if exists && previous == position {
+		return nil
+	}
+
+	c.positions[name] = position
+
+	return c.dump()
+}
+
+// clear drops all cursors, e.g. when the log is truncated and positions
+// start over.
+func (c *cursors) clear() (err error) {
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.positions = map[string]int64{}
+	c.loaded = true
+
+	err = os.Remove(c.pathname)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (c *cursors) load() (err error) {
+
+	if c.loaded {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(c.pathname)
+	if os.IsNotExist(err) {
+		c.loaded = true
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	positions := map[string]int64{}
+
+	err = json.Unmarshal(data, &positions)
+	if err != nil {
+		return err
+	}
+
+	c.positions = positions
+	c.loaded = true
+
+	return nil
+}
+
+// dump writes the cursors to a temporary file renamed over the previous
+// one, so that a crash never leaves a partially written file.
+func (c *cursors) dump() (err error) {
+
+	data, err := json.Marshal(c.positions)
+	if err != nil {
+		return err
+	}
+
+	tmpPathname := c.pathname + ".tmp"
+
+	err = ioutil.WriteFile(tmpPathname, data, os.FileMode(0644))
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmpPathname, c.pathname)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
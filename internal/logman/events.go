@@ -0,0 +1,174 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logman
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// Number of the most recent events kept for watchers to catch up.
+	eventsRetention = 1024
+)
+
+type EventType string
+
+const (
+	EventCreated       EventType = "created"
+	EventDeleted       EventType = "deleted"
+	EventTruncated     EventType = "truncated"
+	EventRestored      EventType = "restored"
+	EventRenamed       EventType = "renamed"
+	EventStatusChanged EventType = "status_changed"
+
+	// Reset is not about a log, it tells watchers that events
+	// following the one they asked for were lost, for instance
+	// because the server restarted or they fell too far behind.
+	EventReset EventType = "reset"
+)
+
+// Event reports a change in the lifecycle of a log. IDs start from the boot
+// time of the manager in microseconds and increase with each event published,
+// so that IDs of a previous run are lower. Status is the status of the log
+// following the change, PreviousStatus being set on status changes and
+// PreviousName on renames.
+type Event struct {
+	ID             int64
+	Time           time.Time
+	Type           EventType
+	Name           string
+	Status         LogStatus
+	PreviousStatus LogStatus
+	PreviousName   string
+}
+
+// eventLog holds the most recent events, waking up watchers each time one is
+// published.
+type eventLog struct {
+	events  []Event
+	lastID  int64
+	changed chan struct{}
+	lock    sync.Mutex
+}
+
+func newEventLog() (el *eventLog) {
+
+	el = &eventLog{
+		events:  []Event{},
+		lastID:  time.Now().UnixNano() / int64(time.Microsecond),
+		changed: make(chan struct{}),
+	}
+
+	return el
+}
+
+func (el *eventLog) publish(event Event) {
+
+	el.lock.Lock()
+	defer el.lock.Unlock()
+
+	el.lastID++
+
+	event.ID = el.lastID
+	event.Time = time.Now()
+
+	el.events = append(el.events, event)
+
+	if len(el.events) > eventsRetention {
+		el.events = append([]Event{}, el.events[len(el.events)-eventsRetention:]...)
+	}
+
+	close(el.changed)
+	el.changed = make(chan struct{})
+}
+
+// since returns the retained events following the event with ID id, and a
+// channel closed when the next one is published. The events are preceded by
+// a reset event when some following id were lost, or when id is unknown.
+func (el *eventLog) since(id int64) (events []Event, changed <-chan struct{}) {
+
+	el.lock.Lock()
+	defer el.lock.Unlock()
+
+	events = []Event{}
+
+	first := el.lastID + 1
+	if len(el.events) > 0 {
+		first = el.events[0].ID
+	}
+
+	if id > el.lastID || id < first-1 {
+
+		events = append(events, Event{
+			ID:   first - 1,
+			Time: time.Now(),
+			Type: EventReset,
+		})
+
+		id = first - 1
+	}
+
+	for _, event := range el.events {
+
+		if event.ID > id {
+			events = append(events, event)
+		}
+	}
+
+	return events, el.changed
+}
+
+func (el *eventLog) last() (id int64) {
+
+	el.lock.Lock()
+	defer el.lock.Unlock()
+
+	return el.lastID
+}
+
+// LastEventID returns the ID of the last event published, events following
+// it being those to come.
+func (lm *LogManager) LastEventID() (id int64) {
+
+	return lm.events.last()
+}
+
+// Events returns the retained events following the event with ID id, waiting
+// up to timeout for one to be published when there are none yet. It returns
+// no events when cancel is closed before, and fails with ErrClosed once the
+// manager is closed.
+func (lm *LogManager) Events(id int64, timeout time.Duration, cancel <-chan struct{}) (events []Event, err error) {
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		events, changed := lm.events.since(id)
+		if len(events) > 0 {
+			return events, nil
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return events, nil
+		case <-cancel:
+			return events, nil
+		case <-lm.stop:
+			return nil, ErrClosed
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logman
+
+// CountLogs returns the number of logs in each status.
+func (lm *LogManager) CountLogs() (counts map[LogStatus]int) {
+
+	counts = make(map[LogStatus]int)
+
+	for _, ml := range lm.ListLogs() {
+		counts[ml.Status()]++
+	}
+
+	return counts
+}
+
+// UnavailableLogs returns the names of the logs which are not in StatusOK,
+// among those matching one of patterns, or one of their namespaces doing so.
+// All logs are checked when patterns is empty.
+func (lm *LogManager) UnavailableLogs(patterns []string) (names []string) {
+
+	names = []string{}
+
+	for _, ml := range lm.ListLogs() {
+
+		if ml.Status() == StatusOK {
+			continue
+		}
+
+		required := len(patterns) == 0
+
+		for _, pattern := range patterns {
+			if matchName(pattern, ml.name) {
+				required = true
+				break
+			}
+		}
+
+		if required {
+			names = append(names, ml.name)
+		}
+	}
+
+	return names
+}
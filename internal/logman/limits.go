@@ -0,0 +1,293 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logman
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	usageInterval   = 1 * time.Second
+	clientIdleDelay = 1 * time.Minute
+)
+
+// Limits caps the rate of records going in and out of a log, or produced and
+// consumed by a client, in records and bytes per second. Zero values mean
+// unlimited.
+type Limits struct {
+	WriteRecordsRate int64
+	WriteBytesRate   int64
+	ReadRecordsRate  int64
+	ReadBytesRate    int64
+}
+
+// bucket is a token bucket allowing rate tokens per second with bursts of up
+// to one second worth of tokens. Its tokens go negative when records are
+// reserved faster than allowed, the resulting debt being the delay callers
+// must wait.
+type bucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate int64) (b *bucket) {
+
+	if rate <= 0 {
+		return nil
+	}
+
+	b = &bucket{
+		rate:   float64(rate),
+		tokens: float64(rate),
+		last:   time.Now(),
+	}
+
+	return b
+}
+
+func (b *bucket) reserve(now time.Time, n float64) (delay time.Duration) {
+
+	if b == nil {
+		return 0
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+
+	b.last = now
+	b.tokens -= n
+
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+
+	return delay
+}
+
+func (b *bucket) full(now time.Time) (full bool) {
+
+	if b == nil {
+		return true
+	}
+
+	return b.tokens+now.Sub(b.last).Seconds()*b.rate >= b.rate
+}
+
+// rateLimit caps a flow of records both in records and in bytes per second.
+type rateLimit struct {
+	records  *bucket
+	bytes    *bucket
+	lastUsed time.Time
+	lock     sync.Mutex
+}
+
+func newRateLimit(recordsRate int64, bytesRate int64) (rl *rateLimit) {
+
+	rl = &rateLimit{
+		records:  newBucket(recordsRate),
+		bytes:    newBucket(bytesRate),
+		lastUsed: time.Now(),
+	}
+
+	return rl
+}
+
+func (rl *rateLimit) reserve(size int) (delay time.Duration) {
+
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	now := time.Now()
+	rl.lastUsed = now
+
+	delay = rl.records.reserve(now, 1)
+
+	bytesDelay := rl.bytes.reserve(now, float64(size))
+	if bytesDelay > delay {
+		delay = bytesDelay
+	}
+
+	return delay
+}
+
+func (rl *rateLimit) idle(now time.Time) (idle bool) {
+
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	if now.Sub(rl.lastUsed) < clientIdleDelay {
+		return false
+	}
+
+	return rl.records.full(now) && rl.bytes.full(now)
+}
+
+// meter measures the rate of a flow of records.
+type meter struct {
+	records     int64
+	bytes       int64
+	recordsRate float64
+	bytesRate   float64
+	last        time.Time
+	lock        sync.Mutex
+}
+
+func newMeter() (m *meter) {
+
+	m = &meter{
+		last: time.Now(),
+	}
+
+	return m
+}
+
+func (m *meter) add(size int) {
+
+	atomic.AddInt64(&m.records, 1)
+	atomic.AddInt64(&m.bytes, int64(size))
+}
+
+// update computes the rates since the previous update.
+func (m *meter) update(now time.Time) {
+
+	records := atomic.SwapInt64(&m.records, 0)
+	bytes := atomic.SwapInt64(&m.bytes, 0)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	elapsed := now.Sub(m.last).Seconds()
+	m.last = now
+
+	if elapsed <= 0 {
+		return
+	}
+
+	m.recordsRate = float64(records) / elapsed
+	m.bytesRate = float64(bytes) / elapsed
+}
+
+func (m *meter) rates() (recordsRate float64, bytesRate float64) {
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.recordsRate, m.bytesRate
+}
+
+type clientLimit struct {
+	write *rateLimit
+	read  *rateLimit
+}
+
+// clientLimits holds the write and read rate limits of each client, created
+// on first use and dropped once idle.
+type clientLimits struct {
+	limits  Limits
+	clients map[string]*clientLimit
+	lock    sync.Mutex
+}
+
+func newClientLimits(limits Limits) (cl *clientLimits) {
+
+	cl = &clientLimits{
+		limits:  limits,
+		clients: make(map[string]*clientLimit),
+	}
+
+	return cl
+}
+
+func (cl *clientLimits) enabled() (enabled bool) {
+
+	return cl.limits != Limits{}
+}
+
+func (cl *clientLimits) get(client string, write bool) (rl *rateLimit) {
+
+	cl.lock.Lock()
+	defer cl.lock.Unlock()
+
+	limit, exists := cl.clients[client]
+	if !exists {
+		limit = &clientLimit{
+			write: newRateLimit(cl.limits.WriteRecordsRate, cl.limits.WriteBytesRate),
+			read:  newRateLimit(cl.limits.ReadRecordsRate, cl.limits.ReadBytesRate),
+		}
+
+		cl.clients[client] = limit
+	}
+
+	if write {
+		return limit.write
+	}
+
+	return limit.read
+}
+
+func (cl *clientLimits) dropIdle(now time.Time) {
+
+	cl.lock.Lock()
+	defer cl.lock.Unlock()
+
+	for client, limit := range cl.clients {
+		if limit.write.idle(now) && limit.read.idle(now) {
+			delete(cl.clients, client)
+		}
+	}
+}
+
+// streamLimiter implements log.Limiter for a single writer or reader,
+// metering its records and throttling them against both the limits of the
+// log and the limits of the client. Writers also reject records while their
+// log is full.
+type streamLimiter struct {
+	meter   *meter
+	limit   *rateLimit
+	clients *clientLimits
+	full    func() (full bool)
+	client  string
+	write   bool
+}
+
+func (sl *streamLimiter) Reserve(size int) (delay time.Duration, err error) {
+
+	if sl.full != nil && sl.full() {
+		return 0, ErrInsufficientStorage
+	}
+
+	sl.meter.add(size)
+
+	delay = sl.limit.reserve(size)
+
+	// Client limits are looked up on each record as
+	// they are dropped when idle.
+	if sl.clients.enabled() {
+
+		clientDelay := sl.clients.get(sl.client, sl.write).reserve(size)
+		if clientDelay > delay {
+			delay = clientDelay
+		}
+	}
+
+	return delay, nil
+}
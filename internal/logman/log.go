@@ -19,36 +19,65 @@ import (
 	"path/filepath"
 	"regexp"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/dataptive/styx/internal/metrics"
 	"github.com/dataptive/styx/pkg/log"
+	"github.com/dataptive/styx/pkg/logger"
 	"github.com/dataptive/styx/pkg/recio"
 )
 
 type LogStatus string
 
+// Logs are opened or created in StatusOpening, and restored in
+// StatusRestoring. Once open, a log is either ok, corrupt or tainted, from
+// which a lifecycle operation moves it to StatusScanning, StatusClosing when
+// truncated, renamed or closed along with the manager, or StatusDeleting.
+// Operations on a log in a transient status fail with ErrUnavailable, and
+// closed or deleted logs are left in StatusUnknown.
 const (
-	StatusOK       LogStatus = "ok"
-	StatusCorrupt  LogStatus = "corrupt"
-	StatusTainted  LogStatus = "tainted"
-	StatusScanning LogStatus = "scanning"
-	StatusUnknown  LogStatus = "unknown"
+	StatusOK        LogStatus = "ok"
+	StatusCorrupt   LogStatus = "corrupt"
+	StatusTainted   LogStatus = "tainted"
+	StatusOpening   LogStatus = "opening"
+	StatusScanning  LogStatus = "scanning"
+	StatusClosing   LogStatus = "closing"
+	StatusDeleting  LogStatus = "deleting"
+	StatusRestoring LogStatus = "restoring"
+	StatusUnknown   LogStatus = "unknown"
 )
 
 var (
-	logNameRegexp = regexp.MustCompile(`^[a-zA-Z\d_\-]+$`)
+	// Log names are made of segments separated by slashes, all segments
+	// but the last naming the namespaces holding the log.
+	logNameRegexp = regexp.MustCompile(`^[a-zA-Z\d_\-]+(/[a-zA-Z\d_\-]+)*$`)
+	segmentRegexp = regexp.MustCompile(`^[a-zA-Z\d_\-]+$`)
 )
 
 type LogInfo struct {
-	Name          string
-	Status        LogStatus
-	RecordCount   int64
-	FileSize      int64
-	StartPosition int64
-	EndPosition   int64
+	Name             string
+	Status           LogStatus
+	RecordCount      int64
+	FileSize         int64
+	StartPosition    int64
+	EndPosition      int64
+	WriteRecordsRate float64
+	WriteBytesRate   float64
+	ReadRecordsRate  float64
+	ReadBytesRate    float64
+	Owner            string
+	Description      string
+	SchemaURL        string
+	Labels           map[string]string
+	LastScan         *ScanResult
 }
 
+// Log is a log managed along with its status. Its lock guards the status, the
+// last scan and the open log, lifecycle operations holding it only to change
+// them. Status changes are published as events.
 type Log struct {
+	overQuota        int32
 	path             string
 	name             string
 	options          log.Options
@@ -59,25 +88,61 @@ type Log struct {
 	writer           *log.LogWriter
 	fanin            *log.Fanin
 	lock             sync.RWMutex
+	statusCond       *sync.Cond
 	reporter         metrics.Reporter
 	listenerChan     chan log.Stat
 	listenerClose    chan struct{}
+	writeLimit       *rateLimit
+	readLimit        *rateLimit
+	writeMeter       *meter
+	readMeter        *meter
+	clients          *clientLimits
+	storage          *storage
+	sessions         *sessionRegistry
+	cursors          *cursors
+	metadata         Metadata
+	lastScan         *ScanResult
+	events           *eventLog
 }
 
-func (ml *Log) NewWriter(ioMode recio.IOMode) (fw *log.FaninWriter, err error) {
+// NewWriter returns a writer throttled by the write limits of the log and
+// of the named client. Writes fail with ErrInsufficientStorage past the hard
+// storage threshold.
+func (ml *Log) NewWriter(client string, ioMode recio.IOMode) (fw *log.FaninWriter, err error) {
 
-	if ml.Status() != StatusOK {
+	ml.lock.RLock()
+	defer ml.lock.RUnlock()
+
+	if ml.status != StatusOK {
 		return nil, ErrUnavailable
 	}
 
+	if ml.full() {
+		return nil, ErrInsufficientStorage
+	}
+
 	fw = log.NewFaninWriter(ml.fanin, ioMode)
 
+	fw.SetLimiter(&streamLimiter{
+		meter:   ml.writeMeter,
+		limit:   ml.writeLimit,
+		clients: ml.clients,
+		full:    ml.full,
+		client:  client,
+		write:   true,
+	})
+
 	return fw, nil
 }
 
-func (ml *Log) NewReader(follow bool, ioMode recio.IOMode) (lr *log.LogReader, err error) {
+// NewReader returns a reader throttled by the read limits of the log and of
+// the named client.
+func (ml *Log) NewReader(client string, follow bool, ioMode recio.IOMode) (lr *log.LogReader, err error) {
 
-	if ml.Status() != StatusOK {
+	ml.lock.RLock()
+	defer ml.lock.RUnlock()
+
+	if ml.status != StatusOK {
 		return nil, ErrUnavailable
 	}
 
@@ -86,9 +151,35 @@ func (ml *Log) NewReader(follow bool, ioMode recio.IOMode) (lr *log.LogReader, e
 		return nil, err
 	}
 
+	lr.SetLimiter(&streamLimiter{
+		meter:   ml.readMeter,
+		limit:   ml.readLimit,
+		clients: ml.clients,
+		full:    nil,
+		client:  client,
+		write:   false,
+	})
+
 	return lr, nil
 }
 
+// full reports whether writes must be rejected, storage being past its hard
+// threshold or a namespace holding the log past its max usage.
+func (ml *Log) full() (full bool) {
+
+	return ml.storage.full() || atomic.LoadInt32(&ml.overQuota) == 1
+}
+
+func (ml *Log) setOverQuota(over bool) {
+
+	value := int32(0)
+	if over {
+		value = 1
+	}
+
+	atomic.StoreInt32(&ml.overQuota, value)
+}
+
 func (ml *Log) Status() (status LogStatus) {
 
 	ml.lock.RLock()
@@ -100,41 +191,81 @@ func (ml *Log) Status() (status LogStatus) {
 
 func (ml *Log) Stat() (logInfo LogInfo) {
 
-	status := ml.Status()
+	ml.lock.RLock()
+	status := ml.status
+	l := ml.log
+	lastScan := ml.lastScan
+	ml.lock.RUnlock()
+
+	metadata := ml.Metadata()
 
 	if status != StatusOK {
 		logInfo = LogInfo{
-			Name:   ml.name,
-			Status: ml.status,
+			Name:        ml.name,
+			Status:      status,
+			Owner:       metadata.Owner,
+			Description: metadata.Description,
+			SchemaURL:   metadata.SchemaURL,
+			Labels:      metadata.Labels,
+			LastScan:    lastScan,
 		}
 
 		return logInfo
 	}
 
-	fileInfo := ml.log.Stat()
+	fileInfo := l.Stat()
 
 	recordCount := fileInfo.EndPosition - fileInfo.StartPosition
 	fileSize := fileInfo.EndOffset - fileInfo.StartOffset
 
+	writeRecordsRate, writeBytesRate := ml.writeMeter.rates()
+	readRecordsRate, readBytesRate := ml.readMeter.rates()
+
 	logInfo = LogInfo{
-		Name:          ml.name,
-		Status:        status,
-		RecordCount:   recordCount,
-		FileSize:      fileSize,
-		StartPosition: fileInfo.StartPosition,
-		EndPosition:   fileInfo.EndPosition,
+		Name:             ml.name,
+		Status:           status,
+		RecordCount:      recordCount,
+		FileSize:         fileSize,
+		StartPosition:    fileInfo.StartPosition,
+		EndPosition:      fileInfo.EndPosition,
+		WriteRecordsRate: writeRecordsRate,
+		WriteBytesRate:   writeBytesRate,
+		ReadRecordsRate:  readRecordsRate,
+		ReadBytesRate:    readBytesRate,
+		Owner:            metadata.Owner,
+		Description:      metadata.Description,
+		SchemaURL:        metadata.SchemaURL,
+		Labels:           metadata.Labels,
+		LastScan:         lastScan,
 	}
 
 	return logInfo
 }
 
+// Cursors returns the positions committed to the named cursors of the log.
+func (ml *Log) Cursors() (positions map[string]int64, err error) {
+
+	return ml.cursors.list()
+}
+
+// CommitCursor sets the named cursor of the log to position.
+func (ml *Log) CommitCursor(name string, position int64) (err error) {
+
+	return ml.cursors.commit(name, position)
+}
+
 func (ml *Log) Backup(w io.Writer) (err error) {
 
-	if ml.Status() != StatusOK {
+	ml.lock.RLock()
+	status := ml.status
+	l := ml.log
+	ml.lock.RUnlock()
+
+	if status != StatusOK {
 		return ErrUnavailable
 	}
 
-	err = ml.log.Backup(w)
+	err = l.Backup(w)
 	if err != nil {
 		return err
 	}
@@ -142,12 +273,8 @@ func (ml *Log) Backup(w io.Writer) (err error) {
 	return nil
 }
 
-func createLog(path, name string, config log.Config, options log.Options, readBufferSize int, writerBufferSize int, reporter metrics.Reporter) (ml *Log, err error) {
-
-	valid := logNameRegexp.MatchString(name)
-	if !valid {
-		return nil, ErrInvalidName
-	}
+// newLog returns a log in StatusOpening, yet to be created or opened.
+func newLog(path, name string, options log.Options, readBufferSize int, writerBufferSize int, reporter metrics.Reporter, limits Limits, clients *clientLimits, storage *storage, sessions *sessionRegistry, events *eventLog) (ml *Log) {
 
 	ml = &Log{
 		path:             path,
@@ -155,213 +282,285 @@ func createLog(path, name string, config log.Config, options log.Options, readBu
 		options:          options,
 		readBufferSize:   readBufferSize,
 		writerBufferSize: writerBufferSize,
-		status:           StatusUnknown,
+		status:           StatusOpening,
 		reporter:         reporter,
 		listenerChan:     make(chan log.Stat, 1),
 		listenerClose:    make(chan struct{}),
+		writeLimit:       newRateLimit(limits.WriteRecordsRate, limits.WriteBytesRate),
+		readLimit:        newRateLimit(limits.ReadRecordsRate, limits.ReadBytesRate),
+		writeMeter:       newMeter(),
+		readMeter:        newMeter(),
+		clients:          clients,
+		storage:          storage,
+		sessions:         sessions,
+		cursors:          newCursors(filepath.Join(path, name)),
+		metadata:         Metadata{}.copy(),
+		events:           events,
+	}
+
+	ml.statusCond = sync.NewCond(&ml.lock)
+
+	return ml
+}
+
+// begin moves the log to the transient status of a lifecycle operation,
+// failing with ErrUnavailable while another one is in progress.
+func (ml *Log) begin(status LogStatus) (err error) {
+
+	ml.lock.Lock()
+	defer ml.lock.Unlock()
+
+	if !ml.idle() {
+		return ErrUnavailable
+	}
+
+	ml.changeStatus(status)
+
+	return nil
+}
+
+// setStatus moves the log to status, waking up those waiting for a change.
+func (ml *Log) setStatus(status LogStatus) {
+
+	ml.lock.Lock()
+	defer ml.lock.Unlock()
+
+	ml.changeStatus(status)
+	ml.statusCond.Broadcast()
+}
+
+// changeStatus moves the log to status and publishes the change, the lock
+// being held.
+func (ml *Log) changeStatus(status LogStatus) {
+
+	previous := ml.status
+	ml.status = status
+
+	if status == previous {
+		return
+	}
+
+	ml.events.publish(Event{
+		Type:           EventStatusChanged,
+		Name:           ml.name,
+		Status:         status,
+		PreviousStatus: previous,
+	})
+}
+
+// idle reports whether no lifecycle operation is in progress, the lock being
+// held.
+func (ml *Log) idle() (idle bool) {
+
+	switch ml.status {
+	case StatusOK, StatusCorrupt, StatusTainted:
+		return true
+	}
+
+	return false
+}
+
+// wait blocks while the log is in status.
+func (ml *Log) wait(status LogStatus) {
+
+	ml.lock.Lock()
+	defer ml.lock.Unlock()
+
+	for ml.status == status {
+		ml.statusCond.Wait()
 	}
+}
+
+// create creates the files of the log and makes it available.
+func (ml *Log) create(config log.Config, metadata Metadata) (err error) {
 
-	pathname := filepath.Join(path, name)
+	pathname := filepath.Join(ml.path, ml.name)
 
-	l, err := log.Create(pathname, config, options)
+	l, err := log.Create(pathname, config, ml.options)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	writer, err := l.NewWriter(ml.writerBufferSize, recio.ModeAuto)
+	err = dumpMetadata(pathname, metadata)
 	if err != nil {
-		return nil, err
+		l.Close()
+		return err
 	}
 
-	ml.status = StatusOK
-	ml.log = l
-	ml.writer = writer
-	ml.fanin = log.NewFanin(writer)
+	ml.lock.Lock()
+	ml.metadata = metadata.copy()
+	ml.lock.Unlock()
 
-	stats := ml.log.Stat()
-	ml.reporter.ReportLogStats(ml.name, stats)
+	ml.reporter.ReportLogLabels(ml.name, metadata.Labels)
 
-	ml.log.Subscribe(ml.listenerChan)
+	return ml.attach(l)
+}
 
-	go ml.metricsListener()
+// open opens the files of the log and makes it available, leaving it corrupt
+// or tainted when they can't be opened.
+func (ml *Log) open() (err error) {
 
-	return ml, nil
-}
+	pathname := filepath.Join(ml.path, ml.name)
 
-func openLog(path, name string, options log.Options, readBufferSize int, writerBufferSize int, reporter metrics.Reporter) (ml *Log, err error) {
+	metadata, err := loadMetadata(pathname)
+	if err != nil {
+		logger.Warn("logman:", err)
+	}
 
-	valid := logNameRegexp.MatchString(name)
-	if !valid {
-		return nil, ErrInvalidName
+	lastScan, err := loadScanResult(pathname)
+	if err != nil {
+		logger.Warn("logman:", err)
 	}
 
-	ml = &Log{
-		path:             path,
-		name:             name,
-		options:          options,
-		readBufferSize:   readBufferSize,
-		writerBufferSize: writerBufferSize,
-		status:           StatusUnknown,
-		reporter:         reporter,
-		listenerChan:     make(chan log.Stat, 1),
-		listenerClose:    make(chan struct{}),
+	ml.lock.Lock()
+	ml.metadata = metadata.copy()
+	if lastScan != nil {
+		ml.lastScan = lastScan
 	}
+	ml.lock.Unlock()
 
-	pathname := filepath.Join(path, name)
+	ml.reporter.ReportLogLabels(ml.name, metadata.Labels)
+
+	l, err := log.Open(pathname, ml.options)
+	if err == log.ErrCorrupt {
+		ml.setStatus(StatusCorrupt)
+		return nil
+	}
 
-	l, err := log.Open(pathname, options)
 	if err != nil {
 
 		// TODO return err not exists (or other kind of error ?)
 
-		ml.status = StatusTainted
+		ml.setStatus(StatusTainted)
+		return nil
+	}
 
-		if err == log.ErrCorrupt {
-			ml.status = StatusCorrupt
-		}
+	return ml.attach(l)
+}
 
-		return ml, nil
-	}
+// attach makes an opened log available for writes and reads.
+func (ml *Log) attach(l *log.Log) (err error) {
 
 	writer, err := l.NewWriter(ml.writerBufferSize, recio.ModeAuto)
 	if err != nil {
-		return nil, err
+		l.Close()
+		ml.setStatus(StatusTainted)
+		return err
 	}
 
-	ml.status = StatusOK
-	ml.log = l
-	ml.writer = writer
-	ml.fanin = log.NewFanin(writer)
-
-	stats := ml.log.Stat()
+	stats := l.Stat()
 	ml.reporter.ReportLogStats(ml.name, stats)
 
-	ml.log.Subscribe(ml.listenerChan)
+	l.Subscribe(ml.listenerChan)
 
 	go ml.metricsListener()
 
-	return ml, nil
+	ml.lock.Lock()
+	defer ml.lock.Unlock()
+
+	ml.log = l
+	ml.writer = writer
+	ml.fanin = log.NewFanin(writer)
+	ml.changeStatus(StatusOK)
+	ml.statusCond.Broadcast()
+
+	return nil
 }
 
+// close closes the files of the log, leaving its status to the lifecycle
+// operation in progress.
 func (ml *Log) close() (err error) {
 
-	if ml.Status() != StatusOK {
+	ml.lock.Lock()
+	l, writer, fanin := ml.log, ml.writer, ml.fanin
+	ml.log, ml.writer, ml.fanin = nil, nil, nil
+	ml.lock.Unlock()
+
+	if l == nil {
 		return nil
 	}
 
-	ml.status = StatusUnknown
-
-	err = ml.fanin.Close()
+	err = fanin.Close()
 	if err != nil {
 		return err
 	}
 
-	err = ml.writer.Close()
+	err = writer.Close()
 	if err != nil {
 		return err
 	}
 
-	err = ml.log.Close()
+	err = l.Close()
 	if err != nil {
 		return err
 	}
 
-	ml.log.Unsubscribe(ml.listenerChan)
+	l.Unsubscribe(ml.listenerChan)
 	ml.listenerClose <- struct{}{}
 
 	return nil
 }
 
-func (ml *Log) metricsListener() {
-
-	for {
-		select {
-		case <-ml.listenerClose:
-		case stats := <-ml.listenerChan:
-			ml.reporter.ReportLogStats(ml.name, stats)
-		}
-	}
-}
-
-func (ml *Log) scan() {
-
-	pathname := filepath.Join(ml.path, ml.name)
+// shutdown waits for the lifecycle operation in progress on the log, but
+// scans which may last long, and closes it.
+func (ml *Log) shutdown() (err error) {
 
 	ml.lock.Lock()
-	ml.status = StatusScanning
 
-	// Make log unavailable during scan.
-	if ml.log != nil {
-		err := ml.log.Close()
-		if err != nil {
-			ml.status = StatusTainted
-			return
-		}
+	for !ml.idle() && ml.status != StatusScanning && ml.status != StatusUnknown {
+		ml.statusCond.Wait()
 	}
 
-	if ml.writer != nil {
-		err := ml.writer.Close()
-		if err != nil {
-			ml.status = StatusTainted
-			return
-		}
+	if !ml.idle() {
+		ml.lock.Unlock()
+		return nil
 	}
 
-	if ml.fanin != nil {
-		err := ml.fanin.Close()
-		if err != nil {
-			ml.status = StatusTainted
-			return
-		}
-	}
+	ml.changeStatus(StatusClosing)
 
 	ml.lock.Unlock()
 
-	// Perform log scan.
-	err := log.Scan(pathname)
+	err = ml.close()
 
-	ml.lock.Lock()
-	defer ml.lock.Unlock()
+	ml.setStatus(StatusUnknown)
 
-	if err != nil {
+	return err
+}
 
-		ml.status = StatusTainted
+func (ml *Log) metricsListener() {
 
-		if err == log.ErrCorrupt {
-			ml.status = StatusCorrupt
+	for {
+		select {
+		case <-ml.listenerClose:
+			return
+		case stats := <-ml.listenerChan:
+			ml.reporter.ReportLogStats(ml.name, stats)
 		}
-
-		return
 	}
+}
 
-	// Try to make log functionnal again.
-	l, err := log.Open(pathname, ml.options)
+func (ml *Log) dropOldestSegment() (dropped bool, err error) {
 
-	if err == log.ErrCorrupt {
-		ml.status = StatusCorrupt
-		return
-	}
+	ml.lock.RLock()
+	defer ml.lock.RUnlock()
 
-	if err != nil {
-		ml.status = StatusTainted
-		return
+	if ml.status != StatusOK {
+		return false, nil
 	}
 
-	writer, err := l.NewWriter(ml.writerBufferSize, recio.ModeAuto)
-	if err != nil {
-		ml.status = StatusTainted
-		return
-	}
+	return ml.log.DropOldestSegment()
+}
 
-	ml.status = StatusOK
-	ml.log = l
-	ml.writer = writer
-	ml.fanin = log.NewFanin(writer)
+// updateUsage measures the write and read rates of the log since the
+// previous update and reports them.
+func (ml *Log) updateUsage(now time.Time) {
 
-	stats := ml.log.Stat()
-	ml.reporter.ReportLogStats(ml.name, stats)
+	ml.writeMeter.update(now)
+	ml.readMeter.update(now)
 
-	ml.log.Subscribe(ml.listenerChan)
+	recordsRate, bytesRate := ml.writeMeter.rates()
+	ml.reporter.ReportLogThroughput(ml.name, "write", recordsRate, bytesRate)
 
-	go ml.metricsListener()
+	recordsRate, bytesRate = ml.readMeter.rates()
+	ml.reporter.ReportLogThroughput(ml.name, "read", recordsRate, bytesRate)
 }
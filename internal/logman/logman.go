@@ -17,8 +17,11 @@ package logman
 import (
 	"errors"
 	"io"
+	"os"
 	"path/filepath"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/dataptive/styx/internal/metrics"
 	"github.com/dataptive/styx/pkg/log"
@@ -30,14 +33,32 @@ var (
 	ErrNotExist    = errors.New("logman: log does not exist")
 	ErrUnavailable = errors.New("logman: log unavailable")
 	ErrInvalidName = errors.New("logman: invalid log name")
+
+	ErrInsufficientStorage = errors.New("logman: insufficient storage")
 )
 
+// LogManager holds the logs of the data directory. Its lock guards the logs,
+// namespaces and aliases registries and the log templates, and is never held
+// while logs are opened, closed or their files changed, so that lifecycle
+// operations on a log don't block traffic on others. Aliases files are
+// written under aliasesLock, which is taken before the lock when both are.
 type LogManager struct {
-	config   Config
-	logs     []*Log
-	logsLock sync.Mutex
-	reporter metrics.Reporter
-	closed   bool
+	config            Config
+	logs              map[string]*Log
+	namespaces        map[string]*namespace
+	settingNamespaces map[string]bool
+	aliases           map[string]*alias
+	logsLock          sync.Mutex
+	aliasesLock       sync.Mutex
+	reporter          metrics.Reporter
+	clients           *clientLimits
+	storage           *storage
+	sessions          *sessionRegistry
+	events            *eventLog
+	closed            bool
+	stop              chan struct{}
+	stopOnce          sync.Once
+	done              chan struct{}
 }
 
 func NewLogManager(config Config, reporter metrics.Reporter) (lm *LogManager, err error) {
@@ -45,25 +66,55 @@ func NewLogManager(config Config, reporter metrics.Reporter) (lm *LogManager, er
 	logger.Infof("logman: starting log manager (data_directory=%s)", config.DataDirectory)
 
 	lm = &LogManager{
-		config:   config,
-		reporter: reporter,
+		config:            config,
+		logs:              make(map[string]*Log),
+		namespaces:        make(map[string]*namespace),
+		settingNamespaces: make(map[string]bool),
+		aliases:           make(map[string]*alias),
+		reporter:          reporter,
+		clients:           newClientLimits(config.ClientLimits),
+		storage:           newStorage(),
+		sessions:          newSessionRegistry(reporter),
+		events:            newEventLog(),
+		stop:              make(chan struct{}),
+		done:              make(chan struct{}),
 	}
 
-	names, err := listLogs(lm.config.DataDirectory)
+	names, namespaces, err := listLogs(lm.config.DataDirectory)
 	if err != nil {
 		return nil, err
 	}
 
+	for _, name := range namespaces {
+
+		config, err := loadNamespaceConfig(filepath.Join(lm.config.DataDirectory, name))
+		if err != nil {
+			return nil, err
+		}
+
+		lm.namespaces[name] = &namespace{
+			name:   name,
+			config: config,
+		}
+	}
+
 	for _, name := range names {
 
 		logger.Debugf("logman: opening log %s", name)
 
-		ml, err := openLog(lm.config.DataDirectory, name, log.DefaultOptions, lm.config.ReadBufferSize, lm.config.WriteBufferSize, lm.reporter)
+		valid := logNameRegexp.MatchString(name)
+		if !valid {
+			return lm, ErrInvalidName
+		}
+
+		ml := lm.newLog(name)
+
+		err = ml.open()
 		if err != nil {
 			return lm, err
 		}
 
-		lm.logs = append(lm.logs, ml)
+		lm.logs[name] = ml
 
 		if ml.Status() != StatusOK {
 
@@ -73,6 +124,18 @@ func NewLogManager(config Config, reporter metrics.Reporter) (lm *LogManager, er
 		}
 	}
 
+	// Aliases are restored once all logs are known, so that
+	// those whose name was taken by a log are left out.
+	for _, name := range names {
+
+		err = lm.restoreAliases(name)
+		if err != nil {
+			logger.Warn("logman:", err)
+		}
+	}
+
+	go lm.monitor()
+
 	return lm, nil
 }
 
@@ -80,53 +143,75 @@ func (lm *LogManager) Close() (err error) {
 
 	logger.Infof("logman: stopping log manager")
 
+	lm.stopOnce.Do(func() {
+		close(lm.stop)
+		<-lm.done
+	})
+
 	lm.logsLock.Lock()
-	defer lm.logsLock.Unlock()
 
 	if lm.closed {
+		lm.logsLock.Unlock()
 		return nil
 	}
 
-	for _, ml := range lm.logs {
+	lm.closed = true
+
+	lm.logsLock.Unlock()
 
-		err = ml.close()
+	// No lifecycle operation starts once closed, wait for those in
+	// progress to complete before closing logs.
+	for _, ml := range lm.ListLogs() {
+
+		err = ml.shutdown()
 		if err != nil {
 			return err
 		}
 	}
 
-	lm.closed = true
-
 	return nil
 }
 
+// ListLogs returns all logs, sorted by name.
 func (lm *LogManager) ListLogs() (logs []*Log) {
 
 	lm.logsLock.Lock()
 	defer lm.logsLock.Unlock()
 
-	logs = lm.logs
+	logs = make([]*Log, 0, len(lm.logs))
+
+	for _, ml := range lm.logs {
+		logs = append(logs, ml)
+	}
+
+	sort.Slice(logs, func(i, j int) bool {
+		return logs[i].name < logs[j].name
+	})
 
 	return logs
 }
 
-func (lm *LogManager) CreateLog(name string, logConfig log.Config) (ml *Log, err error) {
-
-	lm.logsLock.Lock()
-	defer lm.logsLock.Unlock()
+func (lm *LogManager) CreateLog(name string, logConfig log.Config, metadata Metadata) (ml *Log, err error) {
 
 	logger.Infof("logman: creating log \"%s\"", name)
 
-	if lm.closed {
-		return nil, ErrClosed
+	err = metadata.validate()
+	if err != nil {
+		return nil, err
 	}
 
-	ml, err = createLog(lm.config.DataDirectory, name, logConfig, log.DefaultOptions, lm.config.ReadBufferSize, lm.config.WriteBufferSize, lm.reporter)
+	ml, err = lm.reserve(name, StatusOpening)
 	if err != nil {
 		return nil, err
 	}
 
-	lm.logs = append(lm.logs, ml)
+	err = ml.create(logConfig, metadata)
+	if err != nil {
+		lm.release(ml)
+		return nil, err
+	}
+
+	lm.publish(EventCreated, ml, "")
 
 	return ml, nil
 }
@@ -136,14 +221,13 @@ func (lm *LogManager) GetLog(name string) (ml *Log, err error) {
 	lm.logsLock.Lock()
 	defer lm.logsLock.Unlock()
 
-	found := false
-	for _, current := range lm.logs {
+	ml, found := lm.logs[name]
 
-		if current.name == name {
-			ml = current
-			found = true
-			break
-		}
+	// Renamed logs remain reachable under their
+	// previous name while their alias lasts.
+	if !found {
+		ml = lm.resolveAlias(name)
+		found = ml != nil
 	}
 
 	if !found {
@@ -155,157 +239,391 @@ func (lm *LogManager) GetLog(name string) (ml *Log, err error) {
 
 func (lm *LogManager) DeleteLog(name string) (err error) {
 
-	lm.logsLock.Lock()
-	defer lm.logsLock.Unlock()
-
 	logger.Infof("logman: deleting log \"%s\"", name)
 
-	if lm.closed {
-		return ErrClosed
+	ml, err := lm.beginLog(name, StatusDeleting)
+	if err != nil {
+		return err
 	}
 
-	valid := logNameRegexp.MatchString(name)
-	if !valid {
-		return ErrInvalidName
+	err = ml.close()
+	if err != nil {
+		ml.setStatus(StatusTainted)
+		return err
 	}
 
-	pos := -1
-	for i, ml := range lm.logs {
-		if ml.name == name {
-			pos = i
-			break
-		}
-	}
+	path := filepath.Join(lm.config.DataDirectory, name)
 
-	if pos == -1 {
-		return ErrNotExist
+	err = log.Delete(path)
+	if err != nil {
+		ml.setStatus(StatusTainted)
+		return err
 	}
 
-	ml := lm.logs[pos]
+	lm.release(ml)
 
-	err = ml.close()
+	lm.reporter.ReportLogLabels(name, nil)
+
+	lm.publish(EventDeleted, ml, "")
+
+	return nil
+}
+
+func (lm *LogManager) TruncateLog(name string) (err error) {
+
+	logger.Infof("logman: truncating log \"%s\"", name)
+
+	ml, err := lm.beginLog(name, StatusClosing)
 	if err != nil {
 		return err
 	}
 
-	lm.logs[pos] = lm.logs[len(lm.logs)-1]
-	lm.logs = lm.logs[:len(lm.logs)-1]
+	err = ml.close()
+	if err != nil {
+		ml.setStatus(StatusTainted)
+		return err
+	}
 
 	path := filepath.Join(lm.config.DataDirectory, name)
 
-	err = log.Delete(path)
+	err = log.Truncate(path)
 	if err != nil {
+		ml.setStatus(StatusTainted)
 		return err
 	}
 
-	return nil
+	// Positions start over, committed cursors are meaningless.
+	err = ml.cursors.clear()
+	if err != nil {
+		ml.setStatus(StatusTainted)
+		return err
+	}
+
+	ml.setStatus(StatusOpening)
+
+	err = ml.open()
+
+	lm.publish(EventTruncated, ml, "")
+
+	return err
 }
 
-func (lm *LogManager) TruncateLog(name string) (err error) {
+// RenameLog closes the named log, moves it to newName, possibly in another
+// namespace, and reopens it. When aliasTTL is positive, the log remains
+// reachable under its previous name for that duration.
+func (lm *LogManager) RenameLog(name string, newName string, aliasTTL time.Duration) (ml *Log, err error) {
+
+	logger.Infof("logman: renaming log \"%s\" to \"%s\"", name, newName)
 
 	lm.logsLock.Lock()
-	defer lm.logsLock.Unlock()
 
-	logger.Infof("logman: truncating log \"%s\"", name)
+	previous, newMl, err := lm.beginRename(name, newName)
+
+	lm.logsLock.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = previous.close()
+	if err != nil {
+		previous.setStatus(StatusTainted)
+		lm.release(newMl)
+		return nil, err
+	}
+
+	path := filepath.Join(lm.config.DataDirectory, name)
+	newPath := filepath.Join(lm.config.DataDirectory, newName)
+
+	renameErr := lm.makeParents(newName)
+	if renameErr == nil {
+		renameErr = log.Rename(path, newPath)
+	}
+	if renameErr != nil {
+		lm.release(newMl)
+
+		// Reopen the log under its previous name.
+		previous.setStatus(StatusOpening)
+
+		err = previous.open()
+		if err != nil {
+			logger.Warn("logman:", err)
+		}
+
+		return nil, renameErr
+	}
+
+	lm.release(previous)
+
+	lm.reporter.ReportLogLabels(name, nil)
+
+	lm.aliasesLock.Lock()
+	lm.logsLock.Lock()
+
+	dropped := lm.dropAlias(newName)
+	lm.addAlias(name, newName, aliasTTL)
+
+	files := lm.snapshotAliases(newName, dropped)
+
+	lm.logsLock.Unlock()
+
+	lm.writeAliases(files)
+
+	lm.aliasesLock.Unlock()
+
+	err = newMl.open()
+
+	lm.publish(EventRenamed, newMl, name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newMl, nil
+}
+
+// beginRename moves the named log to StatusClosing and reserves newName,
+// the lock being held.
+func (lm *LogManager) beginRename(name string, newName string) (ml *Log, newMl *Log, err error) {
 
 	if lm.closed {
-		return ErrClosed
+		return nil, nil, ErrClosed
 	}
 
-	valid := logNameRegexp.MatchString(name)
+	valid := logNameRegexp.MatchString(name) && logNameRegexp.MatchString(newName)
 	if !valid {
-		return ErrInvalidName
+		return nil, nil, ErrInvalidName
 	}
 
-	pos := -1
-	for i, ml := range lm.logs {
-		if ml.name == name {
-			pos = i
-			break
-		}
+	ml, exists := lm.logs[name]
+	if !exists {
+		return nil, nil, ErrNotExist
 	}
 
-	if pos == -1 {
-		return ErrNotExist
+	_, exists = lm.logs[newName]
+	if exists {
+		return nil, nil, log.ErrExist
 	}
 
-	ml := lm.logs[pos]
+	// Leave the log out while checking the new name, so that it
+	// doesn't count against the quota of its own namespace.
+	delete(lm.logs, name)
+
+	err = lm.prepareLogName(newName)
+
+	lm.logs[name] = ml
 
-	err = ml.close()
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	lm.logs[pos] = lm.logs[len(lm.logs)-1]
-	lm.logs = lm.logs[:len(lm.logs)-1]
+	err = ml.begin(StatusClosing)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	path := filepath.Join(lm.config.DataDirectory, name)
+	newMl = lm.newLog(newName)
+	lm.logs[newName] = newMl
 
-	err = log.Truncate(path)
+	return ml, newMl, nil
+}
+
+func (lm *LogManager) RestoreLog(name string, r io.Reader) (err error) {
+
+	logger.Infof("logman: restoring log \"%s\"", name)
+
+	ml, err := lm.reserve(name, StatusRestoring)
 	if err != nil {
 		return err
 	}
 
-	ml, err = openLog(lm.config.DataDirectory, name, log.DefaultOptions, lm.config.ReadBufferSize, lm.config.WriteBufferSize, lm.reporter)
+	pathname := filepath.Join(lm.config.DataDirectory, name)
+
+	err = log.Restore(pathname, r)
 	if err != nil {
+
+		// Drop partially restored files, unless they
+		// belong to a directory that was already there.
+		if err != log.ErrExist {
+			os.RemoveAll(pathname)
+		}
+
+		lm.release(ml)
 		return err
 	}
 
-	lm.logs = append(lm.logs, ml)
+	ml.setStatus(StatusOpening)
 
-	return nil
+	err = ml.open()
+
+	lm.publish(EventRestored, ml, "")
+
+	return err
 }
 
-func (lm *LogManager) RestoreLog(name string, r io.Reader) (err error) {
+// reserve registers a log under name in a transient status, for it to be
+// created or restored without holding the lock.
+func (lm *LogManager) reserve(name string, status LogStatus) (ml *Log, err error) {
+
+	lm.aliasesLock.Lock()
+	lm.logsLock.Lock()
+
+	ml, dropped, err := lm.beginReserve(name, status)
+
+	// The name no longer points to the log it was an alias of.
+	files := lm.snapshotAliases(dropped)
+
+	lm.logsLock.Unlock()
+
+	lm.writeAliases(files)
+
+	lm.aliasesLock.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = lm.makeParents(name)
+	if err != nil {
+		lm.release(ml)
+		return nil, err
+	}
+
+	return ml, nil
+}
+
+// beginReserve registers a log under name, dropping the alias the name may
+// have been, the lock being held.
+func (lm *LogManager) beginReserve(name string, status LogStatus) (ml *Log, dropped string, err error) {
 
 	if lm.closed {
-		return ErrClosed
+		return nil, "", ErrClosed
 	}
 
 	valid := logNameRegexp.MatchString(name)
 	if !valid {
-		return ErrInvalidName
+		return nil, "", ErrInvalidName
 	}
 
-	logger.Infof("logman: restoring log \"%s\"", name)
-
-	pathname := filepath.Join(lm.config.DataDirectory, name)
+	_, exists := lm.logs[name]
+	if exists {
+		return nil, "", log.ErrExist
+	}
 
-	err = log.Restore(pathname, r)
+	err = lm.prepareLogName(name)
 	if err != nil {
-		return err
+		return nil, "", err
 	}
 
+	ml = lm.newLog(name)
+	ml.status = status
+
+	lm.logs[name] = ml
+
+	dropped = lm.dropAlias(name)
+
+	return ml, dropped, nil
+}
+
+// release unregisters a log whose lifecycle ended, leaving it in
+// StatusUnknown.
+func (lm *LogManager) release(ml *Log) {
+
+	lm.logsLock.Lock()
+
+	if lm.logs[ml.name] == ml {
+		delete(lm.logs, ml.name)
+	}
+
+	lm.logsLock.Unlock()
+
+	ml.setStatus(StatusUnknown)
+}
+
+// beginLog moves the named log to the transient status of a lifecycle
+// operation.
+func (lm *LogManager) beginLog(name string, status LogStatus) (ml *Log, err error) {
+
 	lm.logsLock.Lock()
 	defer lm.logsLock.Unlock()
 
 	if lm.closed {
-		return ErrClosed
+		return nil, ErrClosed
+	}
+
+	valid := logNameRegexp.MatchString(name)
+	if !valid {
+		return nil, ErrInvalidName
 	}
 
-	ml, err := openLog(lm.config.DataDirectory, name, log.DefaultOptions, lm.config.ReadBufferSize, lm.config.WriteBufferSize, lm.reporter)
+	ml, exists := lm.logs[name]
+	if !exists {
+		return nil, ErrNotExist
+	}
+
+	err = ml.begin(status)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	lm.logs = append(lm.logs, ml)
+	return ml, nil
+}
 
-	return nil
+// publish publishes a lifecycle event of ml, previousName being set on
+// renames.
+func (lm *LogManager) publish(eventType EventType, ml *Log, previousName string) {
+
+	lm.events.publish(Event{
+		Type:         eventType,
+		Name:         ml.name,
+		Status:       ml.Status(),
+		PreviousName: previousName,
+	})
 }
 
-func listLogs(path string) (names []string, err error) {
+func (lm *LogManager) newLog(name string) (ml *Log) {
 
-	pattern := path + "/*"
+	return newLog(lm.config.DataDirectory, name, log.DefaultOptions, lm.config.ReadBufferSize, lm.config.WriteBufferSize, lm.reporter, lm.config.LogLimits, lm.clients, lm.storage, lm.sessions, lm.events)
+}
 
-	matches, err := filepath.Glob(pattern)
-	if err != nil {
-		return nil, err
+// monitor periodically measures the usage of logs and storage, and drops the
+// limits of idle clients.
+func (lm *LogManager) monitor() {
+
+	ticker := time.NewTicker(usageInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-lm.stop:
+			close(lm.done)
+			return
+
+		case now := <-ticker.C:
+
+			for _, ml := range lm.ListLogs() {
+				ml.updateUsage(now)
+			}
+
+			lm.clients.dropIdle(now)
+
+			lm.dropAliases(now)
+
+			lm.sessions.report()
+
+			lm.checkStorage()
+		}
 	}
+}
+
+// ListSessions returns the consumer connections reading any log.
+func (lm *LogManager) ListSessions() (sessions []SessionInfo) {
+
+	sessions = []SessionInfo{}
 
-	for _, match := range matches {
-		_, filename := filepath.Split(match)
-		names = append(names, filename)
+	for _, s := range lm.sessions.list() {
+		sessions = append(sessions, s.Info())
 	}
 
-	return names, nil
+	return sessions
 }
@@ -0,0 +1,699 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logman
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dataptive/styx/pkg/log"
+	"github.com/dataptive/styx/pkg/recio"
+)
+
+// Lifecycle operations on a log must neither block nor break traffic on
+// other logs, run these tests with -race.
+
+func TestLogManager_BusyLog(t *testing.T) {
+
+	lm := testLogManager_New(t, DefaultConfig)
+	defer lm.Close()
+
+	busy := testLogManager_Create(t, lm, "busy")
+	other := testLogManager_Create(t, lm, "other")
+
+	// Hold the log in a transient status as a long operation would.
+	err := busy.begin(StatusClosing)
+	if err != nil {
+		t.Fatalf("begin should have succeeded but failed with err = %s", err)
+	}
+
+	ml, err := lm.GetLog("other")
+	if err != nil || ml != other {
+		t.Fatalf("get should have returned the other log but failed with err = %v", err)
+	}
+
+	testLogManager_Write(t, other, 10)
+
+	if other.Stat().RecordCount != 10 {
+		t.Fatalf("other log should hold 10 records but holds %d", other.Stat().RecordCount)
+	}
+
+	_, err = busy.NewWriter("test", recio.ModeAuto)
+	if err != ErrUnavailable {
+		t.Fatalf("writer should have failed with err = %s but got %v", ErrUnavailable, err)
+	}
+
+	err = lm.DeleteLog("busy")
+	if err != ErrUnavailable {
+		t.Fatalf("delete should have failed with err = %s but got %v", ErrUnavailable, err)
+	}
+
+	err = lm.TruncateLog("busy")
+	if err != ErrUnavailable {
+		t.Fatalf("truncate should have failed with err = %s but got %v", ErrUnavailable, err)
+	}
+
+	_, err = lm.RenameLog("busy", "renamed", 0)
+	if err != ErrUnavailable {
+		t.Fatalf("rename should have failed with err = %s but got %v", ErrUnavailable, err)
+	}
+
+	err = lm.TruncateLog("other")
+	if err != nil {
+		t.Fatalf("truncate should have succeeded but failed with err = %s", err)
+	}
+
+	err = lm.DeleteLog("other")
+	if err != nil {
+		t.Fatalf("delete should have succeeded but failed with err = %s", err)
+	}
+
+	busy.setStatus(StatusOK)
+
+	logs := lm.ListLogs()
+	if len(logs) != 1 || logs[0] != busy {
+		t.Fatalf("list should have returned the busy log only but returned %d logs", len(logs))
+	}
+}
+
+func TestLogManager_ConcurrentCreate(t *testing.T) {
+
+	lm := testLogManager_New(t, DefaultConfig)
+	defer lm.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := lm.CreateLog("test", log.DefaultConfig, Metadata{})
+			errs <- err
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	created := 0
+	for err := range errs {
+
+		if err == nil {
+			created++
+			continue
+		}
+
+		if err != log.ErrExist {
+			t.Fatalf("create should have failed with err = %s but got %s", log.ErrExist, err)
+		}
+	}
+
+	if created != 1 {
+		t.Fatalf("log should have been created once but was created %d times", created)
+	}
+}
+
+func TestLogManager_ConcurrentGetOrCreate(t *testing.T) {
+
+	config := DefaultConfig
+	config.DefaultTemplate = &Template{
+		Pattern:    "*",
+		AutoCreate: true,
+	}
+
+	lm := testLogManager_New(t, config)
+	defer lm.Close()
+
+	var wg sync.WaitGroup
+	logs := make(chan *Log, 10)
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ml, err := lm.GetOrCreateLog("test")
+			if err != nil {
+				t.Errorf("get or create should have succeeded but failed with err = %s", err)
+				return
+			}
+
+			testLogManager_Write(t, ml, 1)
+
+			logs <- ml
+		}()
+	}
+
+	wg.Wait()
+	close(logs)
+
+	var first *Log
+	for ml := range logs {
+
+		if first == nil {
+			first = ml
+		}
+
+		if ml != first {
+			t.Fatal("get or create should have returned the same log to all writers")
+		}
+	}
+
+	if first == nil || first.Stat().RecordCount != 10 {
+		t.Fatal("log should hold a record from each writer")
+	}
+}
+
+func TestLogManager_SetTemplates(t *testing.T) {
+
+	lm := testLogManager_New(t, DefaultConfig)
+	defer lm.Close()
+
+	_, err := lm.GetOrCreateLog("events/test")
+	if err != ErrNotExist {
+		t.Fatalf("get or create should have failed with ErrNotExist but got err = %v", err)
+	}
+
+	lm.SetTemplates([]Template{{
+		Pattern:    "events",
+		AutoCreate: true,
+	}}, nil)
+
+	_, err = lm.GetOrCreateLog("events/test")
+	if err != nil {
+		t.Fatalf("get or create should have succeeded but failed with err = %s", err)
+	}
+
+	lm.SetTemplates(nil, nil)
+
+	_, err = lm.GetOrCreateLog("events/other")
+	if err != ErrNotExist {
+		t.Fatalf("get or create should have failed with ErrNotExist but got err = %v", err)
+	}
+}
+
+func TestLogManager_UnavailableLogs(t *testing.T) {
+
+	lm := testLogManager_New(t, DefaultConfig)
+	defer lm.Close()
+
+	corrupt := testLogManager_Create(t, lm, "a/test")
+	testLogManager_Create(t, lm, "b/test")
+
+	corrupt.setStatus(StatusCorrupt)
+	defer corrupt.setStatus(StatusOK)
+
+	counts := lm.CountLogs()
+	if counts[StatusOK] != 1 || counts[StatusCorrupt] != 1 {
+		t.Fatalf("log counts should hold one ok and one corrupt log but got %v", counts)
+	}
+
+	names := lm.UnavailableLogs(nil)
+	if len(names) != 1 || names[0] != "a/test" {
+		t.Fatalf("unavailable logs should be [a/test] but got %v", names)
+	}
+
+	names = lm.UnavailableLogs([]string{"b"})
+	if len(names) != 0 {
+		t.Fatalf("unavailable logs matching b should be empty but got %v", names)
+	}
+
+	names = lm.UnavailableLogs([]string{"a/*"})
+	if len(names) != 1 {
+		t.Fatalf("unavailable logs matching a/* should be [a/test] but got %v", names)
+	}
+}
+
+func TestLogManager_ScanLog(t *testing.T) {
+
+	lm := testLogManager_New(t, DefaultConfig)
+
+	ml := testLogManager_Create(t, lm, "test")
+	testLogManager_Write(t, ml, 10)
+
+	if ml.LastScan() != nil {
+		t.Fatalf("log should never have been scanned but got %v", ml.LastScan())
+	}
+
+	_, err := lm.ScanLog("test")
+	if err != nil {
+		t.Fatalf("scan should have succeeded but failed with err = %s", err)
+	}
+
+	logInfo := ml.Stat()
+	if logInfo.Status != StatusOK || logInfo.RecordCount != 10 {
+		t.Fatalf("log should be ok and hold 10 records but is %s and holds %d", logInfo.Status, logInfo.RecordCount)
+	}
+
+	if logInfo.LastScan == nil || logInfo.LastScan.Status != StatusOK || logInfo.LastScan.Error != "" {
+		t.Fatalf("last scan should be ok but got %v", logInfo.LastScan)
+	}
+
+	_, err = lm.ScanLog("other")
+	if err != ErrNotExist {
+		t.Fatalf("scan should have failed with err = %s but got %v", ErrNotExist, err)
+	}
+
+	err = lm.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The last scan is restored along with the log.
+	lm, err = NewLogManager(lm.config, nopReporter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lm.Close()
+
+	ml, err = lm.GetLog("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ml.LastScan() == nil || ml.LastScan().Status != StatusOK {
+		t.Fatalf("last scan should have been restored but got %v", ml.LastScan())
+	}
+}
+
+func TestLogManager_RenameAlias(t *testing.T) {
+
+	lm := testLogManager_New(t, DefaultConfig)
+
+	testLogManager_Create(t, lm, "first")
+
+	_, err := lm.RenameLog("first", "second", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = lm.RenameLog("second", "third", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = lm.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Aliases are restored along with the log they point to.
+	lm, err = NewLogManager(lm.config, nopReporter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lm.Close()
+
+	for _, name := range []string{"first", "second", "third"} {
+
+		ml, err := lm.GetLog(name)
+		if err != nil || ml.name != "third" {
+			t.Fatalf("%s should point to log third but got %v, err = %v", name, ml, err)
+		}
+
+		resolved := lm.ResolveName(name)
+		if resolved != "third" {
+			t.Fatalf("%s should resolve to third but got %s", name, resolved)
+		}
+	}
+
+	// Reusing a name drops its alias for good.
+	testLogManager_Create(t, lm, "first")
+
+	err = lm.DeleteLog("first")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = lm.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lm, err = NewLogManager(lm.config, nopReporter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lm.Close()
+
+	_, err = lm.GetLog("first")
+	if err != ErrNotExist {
+		t.Fatalf("get should have failed with err = %s but got %v", ErrNotExist, err)
+	}
+}
+
+func TestLogManager_Events(t *testing.T) {
+
+	lm := testLogManager_New(t, DefaultConfig)
+	defer lm.Close()
+
+	since := lm.LastEventID()
+
+	ml := testLogManager_Create(t, lm, "test")
+
+	err := lm.TruncateLog("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ml.setStatus(StatusCorrupt)
+	ml.setStatus(StatusOK)
+
+	err = lm.DeleteLog("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := lm.Events(since, 0, nil)
+	if err != nil {
+		t.Fatalf("events should have succeeded but failed with err = %s", err)
+	}
+
+	types := []EventType{}
+	corrupt := false
+
+	for _, event := range events {
+
+		if event.ID <= since || event.Name != "test" {
+			t.Fatalf("event should follow %d and be about log test but got %v", since, event)
+		}
+
+		since = event.ID
+
+		if event.Type == EventStatusChanged {
+			corrupt = corrupt || event.Status == StatusCorrupt
+			continue
+		}
+
+		types = append(types, event.Type)
+	}
+
+	expected := fmt.Sprint([]EventType{EventCreated, EventTruncated, EventDeleted})
+	if fmt.Sprint(types) != expected {
+		t.Fatalf("lifecycle events should be %s but got %v", expected, types)
+	}
+
+	if !corrupt {
+		t.Fatalf("events should report the log went corrupt")
+	}
+
+	// Watchers are woken up by the next event.
+	go lm.CreateLog("other", log.DefaultConfig, Metadata{})
+
+	events, err = lm.Events(since, 10*time.Second, nil)
+	if err != nil || len(events) == 0 {
+		t.Fatalf("events should have returned the next event but got %v, err = %v", events, err)
+	}
+
+	if events[0].ID != since+1 {
+		t.Fatalf("next event should have ID %d but got %d", since+1, events[0].ID)
+	}
+
+	since = events[len(events)-1].ID
+
+	// Logs of deleted namespaces are reported deleted.
+	_, err = lm.SetNamespace("ns", NamespaceConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testLogManager_Create(t, lm, "ns/first")
+	testLogManager_Create(t, lm, "ns/second")
+
+	err = lm.DeleteNamespace("ns")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err = lm.Events(since, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deleted := 0
+
+	for _, event := range events {
+		if event.Type == EventDeleted {
+			deleted++
+		}
+	}
+
+	if deleted != 2 {
+		t.Fatalf("events should report 2 deleted logs but got %v", events)
+	}
+
+	// Unknown or lost IDs are reported with a reset.
+	lastID := lm.LastEventID()
+
+	for _, id := range []int64{lastID + 1, 0} {
+
+		events, err = lm.Events(id, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(events) == 0 || events[0].Type != EventReset {
+			t.Fatalf("events following %d should start with a reset but got %v", id, events)
+		}
+
+		if events[len(events)-1].ID != lastID {
+			t.Fatalf("events following %d should end with %d but got %v", id, lastID, events)
+		}
+	}
+}
+
+func TestLogManager_ConcurrentLifecycle(t *testing.T) {
+
+	lm := testLogManager_New(t, DefaultConfig)
+	defer lm.Close()
+
+	stable := testLogManager_Create(t, lm, "stable")
+	testLogManager_Create(t, lm, "truncated")
+	testLogManager_Create(t, lm, "renamed-a")
+
+	var writer sync.WaitGroup
+	var operations sync.WaitGroup
+	done := make(chan struct{})
+
+	// Writes to the stable log must never fail while
+	// other logs go through lifecycle operations.
+	writer.Add(1)
+	go func() {
+		defer writer.Done()
+
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				testLogManager_Write(t, stable, 10)
+				stable.Stat()
+				lm.ListLogs()
+			}
+		}
+	}()
+
+	// Allowed errors are those of operations racing on the same log.
+	check := func(op string, err error) {
+
+		switch err {
+		case nil, ErrUnavailable, ErrNotExist, ErrNamespaceNotExist, log.ErrExist, log.ErrNotExist:
+			return
+		}
+
+		t.Errorf("%s failed with err = %s", op, err)
+	}
+
+	lifecycle := []func(i int){
+		func(i int) {
+			check("truncate", lm.TruncateLog("truncated"))
+		},
+		func(i int) {
+			_, err := lm.CreateLog("deleted", log.DefaultConfig, Metadata{})
+			check("create", err)
+			check("delete", lm.DeleteLog("deleted"))
+		},
+		func(i int) {
+			_, err := lm.RenameLog("renamed-a", "renamed-b", 0)
+			check("rename", err)
+			_, err = lm.RenameLog("renamed-b", "renamed-a", 0)
+			check("rename", err)
+		},
+		func(i int) {
+			_, err := lm.CreateLog(fmt.Sprintf("ns/log-%d", i), log.DefaultConfig, Metadata{})
+			check("create", err)
+			check("delete namespace", lm.DeleteNamespace("ns"))
+		},
+		func(i int) {
+			_, err := lm.SetNamespace("ns/nested", NamespaceConfig{})
+			check("set namespace", err)
+		},
+	}
+
+	// Run each operation from two goroutines racing on the same logs.
+	for _, operation := range lifecycle {
+		for j := 0; j < 2; j++ {
+			operations.Add(1)
+			go func(operation func(i int), j int) {
+				defer operations.Done()
+
+				for i := 0; i < 20; i++ {
+					operation(j*20 + i)
+				}
+			}(operation, j)
+		}
+	}
+
+	operations.Wait()
+
+	close(done)
+	writer.Wait()
+
+	for _, ml := range lm.ListLogs() {
+
+		status := ml.Status()
+		if status != StatusOK {
+			t.Fatalf("log %s should be ok once operations complete but is %s", ml.name, status)
+		}
+	}
+
+	for _, info := range lm.ListNamespaces() {
+
+		if lm.removed(info.Name) {
+			t.Fatalf("namespace %s should have a directory once operations complete", info.Name)
+		}
+	}
+}
+
+func TestLogManager_Close(t *testing.T) {
+
+	lm := testLogManager_New(t, DefaultConfig)
+
+	ml := testLogManager_Create(t, lm, "test")
+
+	err := lm.Close()
+	if err != nil {
+		t.Fatalf("close should have succeeded but failed with err = %s", err)
+	}
+
+	if ml.Status() != StatusUnknown {
+		t.Fatalf("log should be closed but is %s", ml.Status())
+	}
+
+	_, err = lm.CreateLog("other", log.DefaultConfig, Metadata{})
+	if err != ErrClosed {
+		t.Fatalf("create should have failed with err = %s but got %v", ErrClosed, err)
+	}
+
+	err = lm.TruncateLog("test")
+	if err != ErrClosed {
+		t.Fatalf("truncate should have failed with err = %s but got %v", ErrClosed, err)
+	}
+
+	err = lm.RestoreLog("other", nil)
+	if err != ErrClosed {
+		t.Fatalf("restore should have failed with err = %s but got %v", ErrClosed, err)
+	}
+}
+
+// Helper function creating a log manager over a temporary data directory.
+func testLogManager_New(t *testing.T, config Config) (lm *LogManager) {
+
+	config.DataDirectory = t.TempDir()
+
+	lm, err := NewLogManager(config, nopReporter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return lm
+}
+
+func testLogManager_Create(t *testing.T, lm *LogManager, name string) (ml *Log) {
+
+	ml, err := lm.CreateLog(name, log.DefaultConfig, Metadata{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ml
+}
+
+func testLogManager_Write(t *testing.T, ml *Log, recordCount int) {
+
+	fw, err := ml.NewWriter("test", recio.ModeAuto)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer fw.Close()
+
+	r := log.Record("payload")
+
+	for i := 0; i < recordCount; i++ {
+
+		_, err = fw.Write(&r)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	err = fw.Flush()
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+type nopReporter struct{}
+
+func (nopReporter) ReportLogStats(string, log.Stat) (err error) {
+
+	return nil
+}
+
+func (nopReporter) ReportLogThroughput(string, string, float64, float64) (err error) {
+
+	return nil
+}
+
+func (nopReporter) ReportStorageStats(int64, int64, int) (err error) {
+
+	return nil
+}
+
+func (nopReporter) ReportConsumerProgress(string, string, string, int64, int64) (err error) {
+
+	return nil
+}
+
+func (nopReporter) ReportConsumerClosed(string, string, string) (err error) {
+
+	return nil
+}
+
+func (nopReporter) ReportLogLabels(string, map[string]string) (err error) {
+
+	return nil
+}
+
+func (nopReporter) Close() (err error) {
+
+	return nil
+}
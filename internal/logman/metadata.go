@@ -0,0 +1,149 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logman
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+const (
+	metadataFilename = "metadata"
+)
+
+var (
+	ErrInvalidLabel = errors.New("logman: invalid label")
+
+	// Label names follow Prometheus conventions, so that
+	// they can be exported as metric labels.
+	labelNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z\d_]*$`)
+)
+
+// Metadata describes a log for its users, it is persisted in a file next to
+// the log config.
+type Metadata struct {
+	Owner       string            `json:"owner"`
+	Description string            `json:"description"`
+	SchemaURL   string            `json:"schema_url"`
+	Labels      map[string]string `json:"labels"`
+}
+
+// Metadata returns the metadata of the log.
+func (ml *Log) Metadata() (metadata Metadata) {
+
+	ml.lock.RLock()
+	defer ml.lock.RUnlock()
+
+	metadata = ml.metadata.copy()
+
+	return metadata
+}
+
+// SetMetadata replaces the metadata of the log.
+func (ml *Log) SetMetadata(metadata Metadata) (err error) {
+
+	err = metadata.validate()
+	if err != nil {
+		return err
+	}
+
+	metadata = metadata.copy()
+
+	ml.lock.Lock()
+	defer ml.lock.Unlock()
+
+	err = dumpMetadata(filepath.Join(ml.path, ml.name), metadata)
+	if err != nil {
+		return err
+	}
+
+	ml.metadata = metadata
+	ml.reporter.ReportLogLabels(ml.name, metadata.Labels)
+
+	return nil
+}
+
+func (m Metadata) validate() (err error) {
+
+	for name := range m.Labels {
+
+		valid := labelNameRegexp.MatchString(name)
+		if !valid {
+			return ErrInvalidLabel
+		}
+	}
+
+	return nil
+}
+
+func (m Metadata) copy() (c Metadata) {
+
+	c = m
+	c.Labels = make(map[string]string, len(m.Labels))
+
+	for name, value := range m.Labels {
+		c.Labels[name] = value
+	}
+
+	return c
+}
+
+func loadMetadata(pathname string) (metadata Metadata, err error) {
+
+	data, err := ioutil.ReadFile(filepath.Join(pathname, metadataFilename))
+	if os.IsNotExist(err) {
+		return metadata.copy(), nil
+	}
+
+	if err != nil {
+		return metadata, err
+	}
+
+	err = json.Unmarshal(data, &metadata)
+	if err != nil {
+		return metadata, err
+	}
+
+	return metadata.copy(), nil
+}
+
+// dumpMetadata writes the metadata to a temporary file renamed over the
+// previous one, so that a crash never leaves a partially written file.
+func dumpMetadata(pathname string, metadata Metadata) (err error) {
+
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+
+	filename := filepath.Join(pathname, metadataFilename)
+	tmpFilename := filename + ".tmp"
+
+	err = ioutil.WriteFile(tmpFilename, data, os.FileMode(0644))
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmpFilename, filename)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,685 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logman
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dataptive/styx/pkg/log"
+	"github.com/dataptive/styx/pkg/logger"
+)
+
+const (
+	namespaceFilename = "namespace"
+	namespaceDirPerm  = 0744
+)
+
+var (
+	ErrNamespaceNotExist = errors.New("logman: namespace does not exist")
+	ErrNameConflict      = errors.New("logman: name conflicts with a log or namespace")
+	ErrNamespaceQuota    = errors.New("logman: namespace quota exceeded")
+)
+
+// NamespaceConfig holds the settings of a namespace. Non zero fields of
+// LogConfig are the defaults of logs created in the namespace, nested
+// namespaces overriding the defaults of their parents. MaxLogs and MaxUsage
+// cap the number and the size of all logs nested in the namespace, zero
+// values meaning no limit.
+type NamespaceConfig struct {
+	LogConfig log.Config
+	MaxLogs   int64
+	MaxUsage  int64
+}
+
+type NamespaceInfo struct {
+	Name     string
+	LogCount int64
+	Usage    int64
+	Config   NamespaceConfig
+}
+
+// namespace is a directory of the data directory holding logs and nested
+// namespaces. Its usage is measured along with storage.
+type namespace struct {
+	name     string
+	config   NamespaceConfig
+	usage    int64
+	exceeded bool
+	deleting bool
+}
+
+// ListNamespaces returns all namespaces, sorted by name.
+func (lm *LogManager) ListNamespaces() (namespaces []NamespaceInfo) {
+
+	lm.logsLock.Lock()
+	defer lm.logsLock.Unlock()
+
+	namespaces = []NamespaceInfo{}
+
+	for _, ns := range lm.namespaces {
+		namespaces = append(namespaces, lm.namespaceInfo(ns))
+	}
+
+	sort.Slice(namespaces, func(i, j int) bool {
+		return namespaces[i].Name < namespaces[j].Name
+	})
+
+	return namespaces
+}
+
+func (lm *LogManager) GetNamespace(name string) (info NamespaceInfo, err error) {
+
+	lm.logsLock.Lock()
+	defer lm.logsLock.Unlock()
+
+	ns, exists := lm.namespaces[name]
+	if !exists {
+		return info, ErrNamespaceNotExist
+	}
+
+	return lm.namespaceInfo(ns), nil
+}
+
+// SetNamespace creates the named namespace, along with its parents, or
+// updates its config.
+func (lm *LogManager) SetNamespace(name string, config NamespaceConfig) (info NamespaceInfo, err error) {
+
+	logger.Infof("logman: setting namespace \"%s\"", name)
+
+	lm.logsLock.Lock()
+
+	err = lm.beginSetNamespace(name)
+
+	lm.logsLock.Unlock()
+
+	if err != nil {
+		return info, err
+	}
+
+	pathname := filepath.Join(lm.config.DataDirectory, name)
+
+	err = os.MkdirAll(pathname, os.FileMode(namespaceDirPerm))
+	if err == nil {
+		err = dumpNamespaceConfig(pathname, config)
+	}
+
+	lm.logsLock.Lock()
+	defer lm.logsLock.Unlock()
+
+	delete(lm.settingNamespaces, name)
+
+	if err != nil {
+		return info, err
+	}
+
+	lm.addNamespaces(append(parents(name), name))
+
+	ns := lm.namespaces[name]
+	ns.config = config
+
+	return lm.namespaceInfo(ns), nil
+}
+
+// beginSetNamespace checks the named namespace can be set and reserves its
+// name, so that no log takes it and neither it nor its parents are deleted
+// while its files are written. The lock is held.
+func (lm *LogManager) beginSetNamespace(name string) (err error) {
+
+	if lm.closed {
+		return ErrClosed
+	}
+
+	valid := logNameRegexp.MatchString(name)
+	if !valid {
+		return ErrInvalidName
+	}
+
+	if lm.lookupLog(name) != nil {
+		return ErrNameConflict
+	}
+
+	err = lm.checkParents(name)
+	if err != nil {
+		return err
+	}
+
+	err = lm.checkDeleting(append(parents(name), name))
+	if err != nil {
+		return err
+	}
+
+	if lm.settingNamespaces[name] {
+		return ErrUnavailable
+	}
+
+	lm.settingNamespaces[name] = true
+
+	return nil
+}
+
+// DeleteNamespace deletes the named namespace along with all the logs and
+// namespaces it holds. When some files can't be removed, the logs left are
+// kept in StatusTainted along with their namespaces.
+func (lm *LogManager) DeleteNamespace(name string) (err error) {
+
+	logger.Infof("logman: deleting namespace \"%s\"", name)
+
+	lm.logsLock.Lock()
+
+	logs, err := lm.beginDeleteNamespace(name)
+
+	lm.logsLock.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	// Files are removed whether logs close cleanly or not.
+	for _, ml := range logs {
+
+		err = ml.close()
+		if err != nil {
+			logger.Warn("logman:", err)
+		}
+	}
+
+	pathname := filepath.Join(lm.config.DataDirectory, name)
+
+	err = os.RemoveAll(pathname)
+
+	for _, ml := range logs {
+
+		// Logs whose files could not be removed are kept,
+		// but remain unavailable.
+		if err != nil && !lm.removed(ml.name) {
+			ml.setStatus(StatusTainted)
+			continue
+		}
+
+		lm.release(ml)
+		lm.reporter.ReportLogLabels(ml.name, nil)
+
+		lm.publish(EventDeleted, ml, "")
+	}
+
+	lm.logsLock.Lock()
+	defer lm.logsLock.Unlock()
+
+	for current, ns := range lm.namespaces {
+
+		if current != name && !contains(name, current) {
+			continue
+		}
+
+		// So are the namespaces holding them.
+		if err != nil && !lm.removed(current) {
+			ns.deleting = false
+			continue
+		}
+
+		delete(lm.namespaces, current)
+	}
+
+	return err
+}
+
+// removed reports whether the directory of the named log or namespace is
+// gone.
+func (lm *LogManager) removed(name string) (ok bool) {
+
+	_, err := os.Lstat(filepath.Join(lm.config.DataDirectory, name))
+
+	return os.IsNotExist(err)
+}
+
+// beginDeleteNamespace moves the logs nested in the named namespace to
+// StatusDeleting, and marks it and its nested namespaces deleting so that no
+// log is added to them meanwhile. The lock is held.
+func (lm *LogManager) beginDeleteNamespace(name string) (logs []*Log, err error) {
+
+	if lm.closed {
+		return nil, ErrClosed
+	}
+
+	valid := logNameRegexp.MatchString(name)
+	if !valid {
+		return nil, ErrInvalidName
+	}
+
+	ns, exists := lm.namespaces[name]
+	if !exists {
+		return nil, ErrNamespaceNotExist
+	}
+
+	if ns.deleting {
+		return nil, ErrUnavailable
+	}
+
+	for current := range lm.settingNamespaces {
+		if current == name || contains(name, current) {
+			return nil, ErrUnavailable
+		}
+	}
+
+	for _, ml := range lm.logs {
+		if contains(name, ml.name) {
+			logs = append(logs, ml)
+		}
+	}
+
+	// Operations beginning on logs hold the lock, checking all logs are
+	// idle first guarantees they all move to StatusDeleting.
+	for _, ml := range logs {
+
+		ml.lock.RLock()
+		idle := ml.idle()
+		ml.lock.RUnlock()
+
+		if !idle {
+			return nil, ErrUnavailable
+		}
+	}
+
+	for _, ml := range logs {
+		ml.begin(StatusDeleting)
+	}
+
+	for current, ns := range lm.namespaces {
+		if current == name || contains(name, current) {
+			ns.deleting = true
+		}
+	}
+
+	return logs, nil
+}
+
+// LogConfig returns the config of a log created with name, the defaults of
+// its namespaces applying over log.DefaultConfig.
+func (lm *LogManager) LogConfig(name string) (config log.Config) {
+
+	lm.logsLock.Lock()
+	defer lm.logsLock.Unlock()
+
+	config = log.DefaultConfig
+
+	for _, parent := range parents(name) {
+
+		ns, exists := lm.namespaces[parent]
+		if !exists {
+			continue
+		}
+
+		config = mergeConfig(config, ns.config.LogConfig)
+	}
+
+	return config
+}
+
+func (lm *LogManager) namespaceInfo(ns *namespace) (info NamespaceInfo) {
+
+	info = NamespaceInfo{
+		Name:     ns.name,
+		LogCount: lm.countLogs(ns.name),
+		Usage:    ns.usage,
+		Config:   ns.config,
+	}
+
+	return info
+}
+
+// addNamespaces registers the named namespaces unless they exist.
+func (lm *LogManager) addNamespaces(names []string) {
+
+	for _, current := range names {
+
+		_, exists := lm.namespaces[current]
+		if exists {
+			continue
+		}
+
+		lm.namespaces[current] = &namespace{
+			name: current,
+		}
+	}
+}
+
+// prepareLogName checks a log can be added with name, neither conflicting
+// with namespaces nor exceeding the max log count of its namespaces, and
+// registers its namespaces. Their directories are created by makeParents
+// once the lock is released.
+func (lm *LogManager) prepareLogName(name string) (err error) {
+
+	_, exists := lm.namespaces[name]
+	if exists || lm.settingNamespaces[name] {
+		return ErrNameConflict
+	}
+
+	err = lm.checkParents(name)
+	if err != nil {
+		return err
+	}
+
+	err = lm.checkDeleting(parents(name))
+	if err != nil {
+		return err
+	}
+
+	for _, parent := range parents(name) {
+
+		ns, exists := lm.namespaces[parent]
+		if !exists || ns.config.MaxLogs <= 0 {
+			continue
+		}
+
+		if lm.countLogs(parent) >= ns.config.MaxLogs {
+			return ErrNamespaceQuota
+		}
+	}
+
+	lm.addNamespaces(parents(name))
+
+	return nil
+}
+
+// makeParents creates the directories of the namespaces holding the named
+// log.
+func (lm *LogManager) makeParents(name string) (err error) {
+
+	dirname := filepath.Dir(filepath.Join(lm.config.DataDirectory, name))
+
+	err = os.MkdirAll(dirname, os.FileMode(namespaceDirPerm))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// checkParents fails when one of the namespaces holding name is a log.
+func (lm *LogManager) checkParents(name string) (err error) {
+
+	for _, parent := range parents(name) {
+		if lm.lookupLog(parent) != nil {
+			return ErrNameConflict
+		}
+	}
+
+	return nil
+}
+
+// checkDeleting fails with ErrUnavailable when one of the named namespaces
+// is being deleted.
+func (lm *LogManager) checkDeleting(names []string) (err error) {
+
+	for _, current := range names {
+
+		ns, exists := lm.namespaces[current]
+		if exists && ns.deleting {
+			return ErrUnavailable
+		}
+	}
+
+	return nil
+}
+
+func (lm *LogManager) lookupLog(name string) (ml *Log) {
+
+	return lm.logs[name]
+}
+
+func (lm *LogManager) countLogs(name string) (count int64) {
+
+	for _, ml := range lm.logs {
+		if contains(name, ml.name) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// checkNamespaces records the usage of namespaces and rejects writes to the
+// logs of namespaces exceeding their max usage.
+func (lm *LogManager) checkNamespaces(logs []*Log, usages map[string]int64) {
+
+	lm.logsLock.Lock()
+
+	exceeded := map[string]bool{}
+
+	for name, ns := range lm.namespaces {
+
+		ns.usage = usages[name]
+
+		over := ns.config.MaxUsage > 0 && ns.usage > ns.config.MaxUsage
+
+		if over && !ns.exceeded {
+			logger.Warnf("logman: namespace \"%s\" exceeds its max usage, rejecting writes (usage=%d)", name, ns.usage)
+		}
+
+		if !over && ns.exceeded {
+			logger.Infof("logman: namespace \"%s\" back under its max usage (usage=%d)", name, ns.usage)
+		}
+
+		ns.exceeded = over
+		exceeded[name] = over
+	}
+
+	lm.logsLock.Unlock()
+
+	for _, ml := range logs {
+
+		over := false
+		for _, parent := range parents(ml.name) {
+			over = over || exceeded[parent]
+		}
+
+		ml.setOverQuota(over)
+	}
+}
+
+// parents returns the namespaces holding name, outermost first.
+func parents(name string) (names []string) {
+
+	for i := 0; i < len(name); i++ {
+		if name[i] == '/' {
+			names = append(names, name[:i])
+		}
+	}
+
+	return names
+}
+
+// matchName reports whether the pattern matches the log name or one of its
+// namespaces.
+func matchName(pattern string, name string) (ok bool) {
+
+	for _, current := range append(parents(name), name) {
+
+		match, _ := path.Match(pattern, current)
+		if match {
+			return true
+		}
+	}
+
+	return false
+}
+
+// contains reports whether name is nested in the namespace.
+func contains(namespace string, name string) (ok bool) {
+
+	return strings.HasPrefix(name, namespace+"/")
+}
+
+func mergeConfig(config log.Config, defaults log.Config) (merged log.Config) {
+
+	merged = config
+
+	if defaults.MaxRecordSize != 0 {
+		merged.MaxRecordSize = defaults.MaxRecordSize
+	}
+
+	if defaults.IndexAfterSize != 0 {
+		merged.IndexAfterSize = defaults.IndexAfterSize
+	}
+
+	if defaults.SegmentMaxCount != 0 {
+		merged.SegmentMaxCount = defaults.SegmentMaxCount
+	}
+
+	if defaults.SegmentMaxSize != 0 {
+		merged.SegmentMaxSize = defaults.SegmentMaxSize
+	}
+
+	if defaults.SegmentMaxAge != 0 {
+		merged.SegmentMaxAge = defaults.SegmentMaxAge
+	}
+
+	if defaults.LogMaxCount != 0 {
+		merged.LogMaxCount = defaults.LogMaxCount
+	}
+
+	if defaults.LogMaxSize != 0 {
+		merged.LogMaxSize = defaults.LogMaxSize
+	}
+
+	if defaults.LogMaxAge != 0 {
+		merged.LogMaxAge = defaults.LogMaxAge
+	}
+
+	return merged
+}
+
+func loadNamespaceConfig(pathname string) (config NamespaceConfig, err error) {
+
+	data, err := ioutil.ReadFile(filepath.Join(pathname, namespaceFilename))
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+
+	if err != nil {
+		return config, err
+	}
+
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		return config, err
+	}
+
+	return config, nil
+}
+
+// dumpNamespaceConfig writes the config to a temporary file renamed over the
+// previous one, so that a crash never leaves a partially written file.
+func dumpNamespaceConfig(pathname string, config NamespaceConfig) (err error) {
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	filename := filepath.Join(pathname, namespaceFilename)
+	tmpFilename := filename + ".tmp"
+
+	err = ioutil.WriteFile(tmpFilename, data, os.FileMode(0644))
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmpFilename, filename)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// listLogs walks the data directory for logs and namespaces. Directories
+// holding files other than a namespace config are logs, other directories
+// are namespaces.
+func listLogs(root string) (names []string, namespaces []string, err error) {
+
+	var walk func(prefix string) (err error)
+
+	walk = func(prefix string) (err error) {
+
+		entries, err := ioutil.ReadDir(filepath.Join(root, prefix))
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+
+			if !entry.IsDir() {
+				continue
+			}
+
+			name := path.Join(prefix, entry.Name())
+
+			isLog, err := isLogDirectory(filepath.Join(root, name))
+			if err != nil {
+				return err
+			}
+
+			if isLog {
+				names = append(names, name)
+				continue
+			}
+
+			namespaces = append(namespaces, name)
+
+			err = walk(name)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	err = walk("")
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+
+	return names, namespaces, nil
+}
+
+func isLogDirectory(pathname string) (isLog bool, err error) {
+
+	entries, err := ioutil.ReadDir(pathname)
+	if err != nil {
+		return false, err
+	}
+
+	for _, entry := range entries {
+
+		if entry.IsDir() {
+			continue
+		}
+
+		if entry.Name() != namespaceFilename && entry.Name() != namespaceFilename+".tmp" {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,186 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logman
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dataptive/styx/pkg/log"
+	"github.com/dataptive/styx/pkg/logger"
+)
+
+const (
+	scanFilename = "scan"
+)
+
+// ScanResult records the outcome of the last scan of a log, it is persisted
+// in a file next to the log config. Status is StatusOK when the files of the
+// log were found sound or repaired, and Error details why they weren't.
+type ScanResult struct {
+	Time   time.Time `json:"time"`
+	Status LogStatus `json:"status"`
+	Error  string    `json:"error"`
+}
+
+// ScanLog closes the named log, checks and repairs its files, and reopens it.
+// The log is left corrupt or tainted when its files can't be repaired, the
+// outcome being recorded as its last scan.
+func (lm *LogManager) ScanLog(name string) (ml *Log, err error) {
+
+	logger.Infof("logman: scanning log \"%s\"", name)
+
+	ml, err = lm.beginLog(name, StatusScanning)
+	if err != nil {
+		return nil, err
+	}
+
+	ml.repair()
+
+	return ml, nil
+}
+
+// LastScan returns the outcome of the last scan of the log, or nil when it
+// was never scanned.
+func (ml *Log) LastScan() (result *ScanResult) {
+
+	ml.lock.RLock()
+	defer ml.lock.RUnlock()
+
+	return ml.lastScan
+}
+
+// scan repairs the files of a corrupt or tainted log, and makes it available
+// again.
+func (ml *Log) scan() {
+
+	err := ml.begin(StatusScanning)
+	if err != nil {
+		return
+	}
+
+	ml.repair()
+}
+
+// repair scans the files of a log in StatusScanning, records the outcome and
+// reopens the log when its files are sound.
+func (ml *Log) repair() {
+
+	// Make log unavailable during scan.
+	err := ml.close()
+	if err != nil {
+		ml.recordScan(StatusTainted, err)
+		ml.setStatus(StatusTainted)
+		return
+	}
+
+	// Perform log scan.
+	pathname := filepath.Join(ml.path, ml.name)
+
+	err = log.Scan(pathname)
+	if err == log.ErrCorrupt {
+		ml.recordScan(StatusCorrupt, err)
+		ml.setStatus(StatusCorrupt)
+		return
+	}
+
+	if err != nil {
+		ml.recordScan(StatusTainted, err)
+		ml.setStatus(StatusTainted)
+		return
+	}
+
+	ml.recordScan(StatusOK, nil)
+
+	// Try to make log functionnal again.
+	err = ml.open()
+	if err != nil {
+		logger.Warn("logman:", err)
+	}
+}
+
+// recordScan persists the outcome of a scan and keeps it as the last scan of
+// the log.
+func (ml *Log) recordScan(status LogStatus, scanErr error) {
+
+	result := &ScanResult{
+		Time:   time.Now(),
+		Status: status,
+	}
+
+	if scanErr != nil {
+		result.Error = scanErr.Error()
+
+		logger.Warnf("logman: scan of log \"%s\" failed (%s)", ml.name, result.Error)
+	}
+
+	err := dumpScanResult(filepath.Join(ml.path, ml.name), *result)
+	if err != nil {
+		logger.Warn("logman:", err)
+	}
+
+	ml.lock.Lock()
+	ml.lastScan = result
+	ml.lock.Unlock()
+}
+
+func loadScanResult(pathname string) (result *ScanResult, err error) {
+
+	data, err := ioutil.ReadFile(filepath.Join(pathname, scanFilename))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	result = &ScanResult{}
+
+	err = json.Unmarshal(data, result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// dumpScanResult writes the scan result to a temporary file renamed over the
+// previous one, so that a crash never leaves a partially written file.
+func dumpScanResult(pathname string, result ScanResult) (err error) {
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	filename := filepath.Join(pathname, scanFilename)
+	tmpFilename := filename + ".tmp"
+
+	err = ioutil.WriteFile(tmpFilename, data, os.FileMode(0644))
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmpFilename, filename)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,326 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logman
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dataptive/styx/internal/metrics"
+)
+
+const (
+	// maxPositionRuns bounds the memory used to resolve the progress of a
+	// consumer skipping records with a filter. Progress reports older than
+	// the tracked runs are ignored.
+	maxPositionRuns = 1 << 16
+)
+
+var (
+	ErrInvalidProgress = errors.New("logman: progress beyond records sent")
+)
+
+type SessionInfo struct {
+	ID                int64
+	Log               string
+	Client            string
+	RemoteAddr        string
+	StartedAt         time.Time
+	SentPosition      int64
+	ProcessedPosition int64
+	Lag               int64
+	Cursor            string
+	ProgressAt        time.Time
+}
+
+// Session tracks a consumer connection reading a log, along with the
+// position up to which the consumer reported having processed records.
+type Session struct {
+	id                int64
+	log               *Log
+	client            string
+	remoteAddr        string
+	startedAt         time.Time
+	sent              positionTracker
+	sentPosition      int64
+	startPosition     int64
+	processedPosition int64
+	cursor            string
+	progressAt        time.Time
+	registry          *sessionRegistry
+	lock              sync.Mutex
+}
+
+// NewSession registers a consumer connection of the named client reading
+// the log from position.
+func (ml *Log) NewSession(client string, remoteAddr string, position int64) (s *Session) {
+
+	s = &Session{
+		log:               ml,
+		client:            client,
+		remoteAddr:        remoteAddr,
+		startedAt:         time.Now(),
+		sentPosition:      position,
+		startPosition:     position,
+		processedPosition: position,
+		registry:          ml.sessions,
+	}
+
+	ml.sessions.add(s)
+
+	return s
+}
+
+// Sessions returns the consumer connections reading the log.
+func (ml *Log) Sessions() (sessions []SessionInfo) {
+
+	sessions = []SessionInfo{}
+
+	for _, s := range ml.sessions.list() {
+
+		if s.log != ml {
+			continue
+		}
+
+		sessions = append(sessions, s.Info())
+	}
+
+	return sessions
+}
+
+// Sent records that the record at position was sent to the consumer.
+func (s *Session) Sent(position int64) {
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.sent.add(position)
+	s.sentPosition = position + 1
+}
+
+// Progress records that the consumer processed the count first records sent
+// since the beginning of the connection, and commits the position following
+// them to the named cursor when not empty.
+func (s *Session) Progress(count int64, cursor string) (err error) {
+
+	s.lock.Lock()
+
+	if count > s.sent.count || count < 0 {
+		s.lock.Unlock()
+		return ErrInvalidProgress
+	}
+
+	position := s.startPosition
+
+	if count > 0 {
+
+		var ok bool
+
+		position, ok = s.sent.resolve(count)
+		if !ok {
+			// The report is older than the tracked positions.
+			s.lock.Unlock()
+			return nil
+		}
+	}
+
+	if position > s.processedPosition {
+		s.processedPosition = position
+	}
+
+	s.progressAt = time.Now()
+
+	if cursor != "" {
+		s.cursor = cursor
+	}
+
+	s.lock.Unlock()
+
+	if cursor == "" {
+		return nil
+	}
+
+	err = s.log.CommitCursor(cursor, position)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Session) Info() (info SessionInfo) {
+
+	endPosition := s.log.Stat().EndPosition
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	lag := endPosition - s.processedPosition
+	if lag < 0 {
+		lag = 0
+	}
+
+	info = SessionInfo{
+		ID:                s.id,
+		Log:               s.log.name,
+		Client:            s.client,
+		RemoteAddr:        s.remoteAddr,
+		StartedAt:         s.startedAt,
+		SentPosition:      s.sentPosition,
+		ProcessedPosition: s.processedPosition,
+		Lag:               lag,
+		Cursor:            s.cursor,
+		ProgressAt:        s.progressAt,
+	}
+
+	return info
+}
+
+// Close unregisters the session.
+func (s *Session) Close() {
+
+	s.registry.remove(s)
+}
+
+// sessionRegistry holds the sessions of all logs, shared by the log manager
+// and its logs.
+type sessionRegistry struct {
+	nextID   int64
+	sessions map[int64]*Session
+	reporter metrics.Reporter
+	lock     sync.Mutex
+}
+
+func newSessionRegistry(reporter metrics.Reporter) (sr *sessionRegistry) {
+
+	sr = &sessionRegistry{
+		nextID:   0,
+		sessions: map[int64]*Session{},
+		reporter: reporter,
+	}
+
+	return sr
+}
+
+func (sr *sessionRegistry) add(s *Session) {
+
+	sr.lock.Lock()
+	defer sr.lock.Unlock()
+
+	sr.nextID++
+	s.id = sr.nextID
+
+	sr.sessions[s.id] = s
+}
+
+func (sr *sessionRegistry) remove(s *Session) {
+
+	sr.lock.Lock()
+
+	_, exists := sr.sessions[s.id]
+	delete(sr.sessions, s.id)
+
+	sr.lock.Unlock()
+
+	if exists {
+		sr.reporter.ReportConsumerClosed(s.log.name, strconv.FormatInt(s.id, 10), s.client)
+	}
+}
+
+func (sr *sessionRegistry) list() (sessions []*Session) {
+
+	sr.lock.Lock()
+	defer sr.lock.Unlock()
+
+	sessions = make([]*Session, 0, len(sr.sessions))
+
+	for _, s := range sr.sessions {
+		sessions = append(sessions, s)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].id < sessions[j].id
+	})
+
+	return sessions
+}
+
+// report reports the processed position and lag of every session.
+func (sr *sessionRegistry) report() {
+
+	for _, s := range sr.list() {
+
+		info := s.Info()
+
+		sr.reporter.ReportConsumerProgress(info.Log, strconv.FormatInt(info.ID, 10), info.Client, info.ProcessedPosition, info.Lag)
+	}
+}
+
+// positionRun is a run of records sent at consecutive positions, count being
+// the number of records sent before its first one.
+type positionRun struct {
+	count    int64
+	position int64
+	length   int64
+}
+
+// positionTracker maps the number of records sent to a consumer to their
+// positions in the log, which are not contiguous when records are filtered.
+type positionTracker struct {
+	runs  []positionRun
+	count int64
+}
+
+func (pt *positionTracker) add(position int64) {
+
+	n := len(pt.runs)
+
+	if n > 0 && pt.runs[n-1].position+pt.runs[n-1].length == position {
+		pt.runs[n-1].length++
+		pt.count++
+		return
+	}
+
+	if n >= maxPositionRuns {
+		// Drop the oldest half at once to amortize the copy.
+		pt.runs = append(pt.runs[:0], pt.runs[n/2:]...)
+	}
+
+	pt.runs = append(pt.runs, positionRun{
+		count:    pt.count,
+		position: position,
+		length:   1,
+	})
+
+	pt.count++
+}
+
+// resolve returns the position following the count first records sent, and
+// forgets the runs preceding it.
+func (pt *positionTracker) resolve(count int64) (position int64, ok bool) {
+
+	for i, run := range pt.runs {
+
+		if count > run.count && count <= run.count+run.length {
+			pt.runs = pt.runs[i:]
+			return run.position + count - run.count, true
+		}
+	}
+
+	return 0, false
+}
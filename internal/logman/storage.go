@@ -0,0 +1,218 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logman
+
+import (
+	"sync"
+	"syscall"
+
+	"github.com/dataptive/styx/pkg/logger"
+)
+
+type StorageStatus string
+
+const (
+	StorageOK        StorageStatus = "ok"
+	StorageSoftLimit StorageStatus = "soft_limit"
+	StorageHardLimit StorageStatus = "hard_limit"
+)
+
+var (
+	storageLevels = map[StorageStatus]int{
+		StorageOK:        0,
+		StorageSoftLimit: 1,
+		StorageHardLimit: 2,
+	}
+)
+
+// Quota sets thresholds on the size of all logs and on the free space left
+// on the data directory filesystem. Past a soft threshold, the oldest
+// segments of expendable logs are dropped ahead of retention. Past a hard
+// threshold, writes are rejected. Zero values mean no threshold.
+type Quota struct {
+	SoftMaxUsage   int64
+	HardMaxUsage   int64
+	SoftMinFree    int64
+	HardMinFree    int64
+	ExpendableLogs []string
+}
+
+type StorageInfo struct {
+	Status StorageStatus
+	Usage  int64
+	Free   int64
+}
+
+// storage holds the latest storage state, shared with logs to reject writes
+// past the hard threshold.
+type storage struct {
+	info StorageInfo
+	lock sync.RWMutex
+}
+
+func newStorage() (s *storage) {
+
+	s = &storage{
+		info: StorageInfo{
+			Status: StorageOK,
+			Usage:  0,
+			Free:   -1,
+		},
+	}
+
+	return s
+}
+
+func (s *storage) get() (info StorageInfo) {
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.info
+}
+
+func (s *storage) set(info StorageInfo) {
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.info = info
+}
+
+func (s *storage) full() (full bool) {
+
+	return s.get().Status == StorageHardLimit
+}
+
+// Storage returns the storage usage of the log manager, as of its latest
+// check.
+func (lm *LogManager) Storage() (info StorageInfo) {
+
+	return lm.storage.get()
+}
+
+// checkStorage measures storage usage against the quota, dropping segments
+// of expendable logs past the soft threshold.
+func (lm *LogManager) checkStorage() {
+
+	quota := lm.config.Quota
+	logs := lm.ListLogs()
+
+	usage := int64(0)
+	usages := map[string]int64{}
+
+	for _, ml := range logs {
+
+		size := ml.Stat().FileSize
+		usage += size
+
+		for _, parent := range parents(ml.name) {
+			usages[parent] += size
+		}
+	}
+
+	lm.checkNamespaces(logs, usages)
+
+	free, err := freeSpace(lm.config.DataDirectory)
+	if err != nil {
+		logger.Warn("logman:", err)
+		free = -1
+	}
+
+	exceeds := func(maxUsage int64, minFree int64) (exceeded bool) {
+
+		if maxUsage > 0 && usage > maxUsage {
+			return true
+		}
+
+		if minFree > 0 && free != -1 && free < minFree {
+			return true
+		}
+
+		return false
+	}
+
+	status := StorageOK
+
+	if exceeds(quota.SoftMaxUsage, quota.SoftMinFree) {
+		status = StorageSoftLimit
+	}
+
+	if exceeds(quota.HardMaxUsage, quota.HardMinFree) {
+		status = StorageHardLimit
+	}
+
+	previous := lm.storage.get()
+
+	if status != previous.Status {
+		logger.Warnf("logman: storage status changed from %s to %s (usage=%d, free=%d)", previous.Status, status, usage, free)
+	}
+
+	lm.storage.set(StorageInfo{
+		Status: status,
+		Usage:  usage,
+		Free:   free,
+	})
+
+	lm.reporter.ReportStorageStats(usage, free, storageLevels[status])
+
+	if status == StorageOK {
+		return
+	}
+
+	// Drop a segment from each expendable log on each check
+	// until usage gets back under the soft threshold.
+	for _, ml := range logs {
+
+		if !expendable(quota.ExpendableLogs, ml.name) {
+			continue
+		}
+
+		dropped, err := ml.dropOldestSegment()
+		if err != nil {
+			logger.Warn("logman:", err)
+			continue
+		}
+
+		if dropped {
+			logger.Infof("logman: dropped oldest segment of expendable log \"%s\"", ml.name)
+		}
+	}
+}
+
+func expendable(patterns []string, name string) (ok bool) {
+
+	for _, pattern := range patterns {
+		if matchName(pattern, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func freeSpace(pathname string) (free int64, err error) {
+
+	stat := syscall.Statfs_t{}
+
+	err = syscall.Statfs(pathname, &stat)
+	if err != nil {
+		return 0, err
+	}
+
+	free = int64(stat.Bavail) * int64(stat.Bsize)
+
+	return free, nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logman
+
+import (
+	"github.com/dataptive/styx/pkg/log"
+	"github.com/dataptive/styx/pkg/logger"
+)
+
+// Template holds the config of logs created on their first write when they
+// match Pattern, or one of their namespaces does. Non zero fields of
+// LogConfig override the defaults of the namespaces holding the log.
+// Templates with AutoCreate unset opt matching logs out of creation on
+// write.
+type Template struct {
+	Pattern    string
+	AutoCreate bool
+	LogConfig  log.Config
+}
+
+// GetOrCreateLog returns the named log, creating it when missing from the
+// first template it matches, or from the default template when it matches
+// none. It fails with ErrNotExist when no template allows creating the log.
+func (lm *LogManager) GetOrCreateLog(name string) (ml *Log, err error) {
+
+	ml, err = lm.GetLog(name)
+	if err == ErrNotExist {
+		ml, err = lm.createFromTemplate(name)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Concurrent writes may be creating the log.
+	ml.wait(StatusOpening)
+
+	return ml, nil
+}
+
+func (lm *LogManager) createFromTemplate(name string) (ml *Log, err error) {
+
+	template := lm.template(name)
+	if template == nil || !template.AutoCreate {
+		return nil, ErrNotExist
+	}
+
+	logger.Infof("logman: creating log \"%s\" on write from template \"%s\"", name, template.Pattern)
+
+	config := mergeConfig(lm.LogConfig(name), template.LogConfig)
+
+	ml, err = lm.CreateLog(name, config, Metadata{})
+
+	// Concurrent writes may have created the log first.
+	if err == log.ErrExist {
+		return lm.GetLog(name)
+	}
+
+	if err == ErrInvalidName || err == ErrNameConflict {
+		return nil, ErrNotExist
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ml, nil
+}
+
+// SetTemplates replaces the log templates, applying to logs created from
+// then on.
+func (lm *LogManager) SetTemplates(templates []Template, defaultTemplate *Template) {
+
+	lm.logsLock.Lock()
+	defer lm.logsLock.Unlock()
+
+	lm.config.Templates = templates
+	lm.config.DefaultTemplate = defaultTemplate
+}
+
+func (lm *LogManager) template(name string) (template *Template) {
+
+	lm.logsLock.Lock()
+	defer lm.logsLock.Unlock()
+
+	for i := range lm.config.Templates {
+
+		if matchName(lm.config.Templates[i].Pattern, name) {
+			return &lm.config.Templates[i]
+		}
+	}
+
+	return lm.config.DefaultTemplate
+}
@@ -15,6 +15,8 @@
 package metrics
 
 import (
+	"sync"
+
 	"github.com/dataptive/styx/internal/metrics/prometheus"
 	"github.com/dataptive/styx/internal/metrics/statsd"
 	"github.com/dataptive/styx/pkg/log"
@@ -23,19 +25,79 @@ import (
 type Reporter interface {
 	ReportLogStats(string, log.Stat) error
 
+	// ReportLogThroughput reports the records and bytes rates at which a
+	// log is written to or read from, direction being "write" or "read".
+	ReportLogThroughput(string, string, float64, float64) error
+
+	// ReportStorageStats reports the size of all logs, the free space of
+	// the data directory and the storage level, 0 being under quota, 1 past
+	// the soft threshold and 2 past the hard threshold.
+	ReportStorageStats(int64, int64, int) error
+
+	// ReportConsumerProgress reports the position up to which a consumer
+	// session processed a log and its lag behind the end of the log, from
+	// the log name, session ID and client.
+	ReportConsumerProgress(string, string, string, int64, int64) error
+
+	// ReportConsumerClosed reports a consumer session ended.
+	ReportConsumerClosed(string, string, string) error
+
+	// ReportLogLabels reports the labels of a log, replacing those
+	// previously reported. Nil labels report the log gone.
+	ReportLogLabels(string, map[string]string) error
+
 	Close() error
 }
 
 type MetricsReporter struct {
-	reporters []Reporter
+	prometheus    Reporter
+	reporters     []Reporter
+	reportersLock sync.RWMutex
 }
 
 func NewMetricsReporter(config Config) (mp *MetricsReporter, err error) {
 
-	var reporters []Reporter
+	mp = &MetricsReporter{
+		prometheus: prometheus.NewPrometheusReporter(),
+	}
+
+	mp.reporters, err = mp.newReporters(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return mp, nil
+}
+
+// Reload replaces the reporters with those of config, closing the previous
+// ones. The prometheus reporter is kept as its metrics are registered once
+// per process. Running reporters are left untouched when config is invalid.
+func (mp *MetricsReporter) Reload(config Config) (err error) {
+
+	reporters, err := mp.newReporters(config)
+	if err != nil {
+		return err
+	}
+
+	// Reports hold the read lock while using reporters, closing the
+	// previous ones under the write lock guarantees none still uses them.
+	mp.reportersLock.Lock()
+	defer mp.reportersLock.Unlock()
+
+	for _, reporter := range mp.reporters {
+		if reporter != mp.prometheus {
+			reporter.Close()
+		}
+	}
 
-	pp := prometheus.NewPrometheusReporter()
-	reporters = append(reporters, pp)
+	mp.reporters = reporters
+
+	return nil
+}
+
+func (mp *MetricsReporter) newReporters(config Config) (reporters []Reporter, err error) {
+
+	reporters = append(reporters, mp.prometheus)
 
 	if config.Statsd != nil {
 		sp, err := statsd.NewStatsdReporter(*config.Statsd)
@@ -46,15 +108,14 @@ func NewMetricsReporter(config Config) (mp *MetricsReporter, err error) {
 		reporters = append(reporters, sp)
 	}
 
-	mp = &MetricsReporter{
-		reporters: reporters,
-	}
-
-	return mp, nil
+	return reporters, nil
 }
 
 func (mp *MetricsReporter) ReportLogStats(name string, stats log.Stat) (err error) {
 
+	mp.reportersLock.RLock()
+	defer mp.reportersLock.RUnlock()
+
 	for _, reporter := range mp.reporters {
 		reporter.ReportLogStats(name, stats)
 	}
@@ -62,8 +123,71 @@ func (mp *MetricsReporter) ReportLogStats(name string, stats log.Stat) (err erro
 	return nil
 }
 
+func (mp *MetricsReporter) ReportLogThroughput(name string, direction string, recordsRate float64, bytesRate float64) (err error) {
+
+	mp.reportersLock.RLock()
+	defer mp.reportersLock.RUnlock()
+
+	for _, reporter := range mp.reporters {
+		reporter.ReportLogThroughput(name, direction, recordsRate, bytesRate)
+	}
+
+	return nil
+}
+
+func (mp *MetricsReporter) ReportStorageStats(usage int64, free int64, level int) (err error) {
+
+	mp.reportersLock.RLock()
+	defer mp.reportersLock.RUnlock()
+
+	for _, reporter := range mp.reporters {
+		reporter.ReportStorageStats(usage, free, level)
+	}
+
+	return nil
+}
+
+func (mp *MetricsReporter) ReportConsumerProgress(name string, session string, client string, position int64, lag int64) (err error) {
+
+	mp.reportersLock.RLock()
+	defer mp.reportersLock.RUnlock()
+
+	for _, reporter := range mp.reporters {
+		reporter.ReportConsumerProgress(name, session, client, position, lag)
+	}
+
+	return nil
+}
+
+func (mp *MetricsReporter) ReportConsumerClosed(name string, session string, client string) (err error) {
+
+	mp.reportersLock.RLock()
+	defer mp.reportersLock.RUnlock()
+
+	for _, reporter := range mp.reporters {
+		reporter.ReportConsumerClosed(name, session, client)
+	}
+
+	return nil
+}
+
+func (mp *MetricsReporter) ReportLogLabels(name string, labels map[string]string) (err error) {
+
+	mp.reportersLock.RLock()
+	defer mp.reportersLock.RUnlock()
+
+	for _, reporter := range mp.reporters {
+		reporter.ReportLogLabels(name, labels)
+	}
+
+	return nil
+}
+
 func (mp *MetricsReporter) Close() (err error) {
 
+	mp.reportersLock.RLock()
+	defer mp.reportersLock.RUnlock()
+
 	for _, reporter := range mp.reporters {
 		reporter.Close()
 	}
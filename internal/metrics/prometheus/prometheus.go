@@ -15,14 +15,26 @@
 package prometheus
 
 import (
+	"sync"
+
 	"github.com/dataptive/styx/pkg/log"
 
 	prom "github.com/prometheus/client_golang/prometheus"
 )
 
 type PrometheusReporter struct {
-	logRecordCount *prom.GaugeVec
-	logFileSize    *prom.GaugeVec
+	logRecordCount   *prom.GaugeVec
+	logFileSize      *prom.GaugeVec
+	logRecordsRate   *prom.GaugeVec
+	logBytesRate     *prom.GaugeVec
+	storageUsage     prom.Gauge
+	storageFree      prom.Gauge
+	storageLevel     prom.Gauge
+	consumerPosition *prom.GaugeVec
+	consumerLag      *prom.GaugeVec
+	logLabel         *prom.GaugeVec
+	labels           map[string]map[string]string
+	labelsLock       sync.Mutex
 }
 
 func NewPrometheusReporter() (pp *PrometheusReporter) {
@@ -43,12 +55,92 @@ func NewPrometheusReporter() (pp *PrometheusReporter) {
 		[]string{"log"},
 	)
 
+	logRecordsRate := prom.NewGaugeVec(
+		prom.GaugeOpts{
+			Name: "log_records_rate",
+			Help: "Current records per second written to or read from log",
+		},
+		[]string{"log", "direction"},
+	)
+
+	logBytesRate := prom.NewGaugeVec(
+		prom.GaugeOpts{
+			Name: "log_bytes_rate",
+			Help: "Current bytes per second written to or read from log",
+		},
+		[]string{"log", "direction"},
+	)
+
+	storageUsage := prom.NewGauge(
+		prom.GaugeOpts{
+			Name: "storage_usage",
+			Help: "Current size of all logs",
+		},
+	)
+
+	storageFree := prom.NewGauge(
+		prom.GaugeOpts{
+			Name: "storage_free",
+			Help: "Current free space of the data directory",
+		},
+	)
+
+	storageLevel := prom.NewGauge(
+		prom.GaugeOpts{
+			Name: "storage_level",
+			Help: "Current storage level, 0 under quota, 1 past soft threshold, 2 past hard threshold",
+		},
+	)
+
+	consumerPosition := prom.NewGaugeVec(
+		prom.GaugeOpts{
+			Name: "consumer_processed_position",
+			Help: "Position up to which a consumer session reported having processed records",
+		},
+		[]string{"log", "session", "client"},
+	)
+
+	consumerLag := prom.NewGaugeVec(
+		prom.GaugeOpts{
+			Name: "consumer_lag",
+			Help: "Records between the processed position of a consumer session and the end of the log",
+		},
+		[]string{"log", "session", "client"},
+	)
+
+	// Labels are exported as one series per label, to be joined
+	// with log metrics on the log label.
+	logLabel := prom.NewGaugeVec(
+		prom.GaugeOpts{
+			Name: "log_label",
+			Help: "Labels set on log, always 1",
+		},
+		[]string{"log", "label", "value"},
+	)
+
 	prom.MustRegister(logRecordCount)
 	prom.MustRegister(logFileSize)
+	prom.MustRegister(logRecordsRate)
+	prom.MustRegister(logBytesRate)
+	prom.MustRegister(storageUsage)
+	prom.MustRegister(storageFree)
+	prom.MustRegister(storageLevel)
+	prom.MustRegister(consumerPosition)
+	prom.MustRegister(consumerLag)
+	prom.MustRegister(logLabel)
 
 	pp = &PrometheusReporter{
-		logRecordCount: logRecordCount,
-		logFileSize:    logFileSize,
+		logRecordCount:   logRecordCount,
+		logFileSize:      logFileSize,
+		logRecordsRate:   logRecordsRate,
+		logBytesRate:     logBytesRate,
+		storageUsage:     storageUsage,
+		storageFree:      storageFree,
+		storageLevel:     storageLevel,
+		consumerPosition: consumerPosition,
+		consumerLag:      consumerLag,
+		logLabel:         logLabel,
+		labels:           make(map[string]map[string]string),
 	}
 
 	return pp
@@ -73,3 +165,72 @@ func (pp *PrometheusReporter) ReportLogStats(name string, stats log.Stat) (err e
 
 	return nil
 }
+
+func (pp *PrometheusReporter) ReportLogThroughput(name string, direction string, recordsRate float64, bytesRate float64) (err error) {
+
+	pp.logRecordsRate.
+		With(prom.Labels{"log": name, "direction": direction}).
+		Set(recordsRate)
+
+	pp.logBytesRate.
+		With(prom.Labels{"log": name, "direction": direction}).
+		Set(bytesRate)
+
+	return nil
+}
+
+func (pp *PrometheusReporter) ReportStorageStats(usage int64, free int64, level int) (err error) {
+
+	pp.storageUsage.Set(float64(usage))
+	pp.storageFree.Set(float64(free))
+	pp.storageLevel.Set(float64(level))
+
+	return nil
+}
+
+func (pp *PrometheusReporter) ReportConsumerProgress(name string, session string, client string, position int64, lag int64) (err error) {
+
+	labels := prom.Labels{"log": name, "session": session, "client": client}
+
+	pp.consumerPosition.With(labels).Set(float64(position))
+	pp.consumerLag.With(labels).Set(float64(lag))
+
+	return nil
+}
+
+func (pp *PrometheusReporter) ReportConsumerClosed(name string, session string, client string) (err error) {
+
+	labels := prom.Labels{"log": name, "session": session, "client": client}
+
+	pp.consumerPosition.Delete(labels)
+	pp.consumerLag.Delete(labels)
+
+	return nil
+}
+
+func (pp *PrometheusReporter) ReportLogLabels(name string, labels map[string]string) (err error) {
+
+	pp.labelsLock.Lock()
+	defer pp.labelsLock.Unlock()
+
+	for label, value := range pp.labels[name] {
+		pp.logLabel.Delete(prom.Labels{"log": name, "label": label, "value": value})
+	}
+
+	delete(pp.labels, name)
+
+	if labels == nil {
+		return nil
+	}
+
+	reported := make(map[string]string, len(labels))
+
+	for label, value := range labels {
+		pp.logLabel.With(prom.Labels{"log": name, "label": label, "value": value}).Set(1)
+		reported[label] = value
+	}
+
+	pp.labels[name] = reported
+
+	return nil
+}
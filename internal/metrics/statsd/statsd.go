@@ -22,8 +22,15 @@ import (
 )
 
 const (
-	recordCountPattern = "log.%s.record.count"
-	fileSizePattern    = "log.%s.file.size"
+	recordCountPattern      = "log.%s.record.count"
+	fileSizePattern         = "log.%s.file.size"
+	recordsRatePattern      = "log.%s.%s.records.rate"
+	bytesRatePattern        = "log.%s.%s.bytes.rate"
+	storageUsageLabel       = "storage.usage"
+	storageFreeLabel        = "storage.free"
+	storageLevelLabel       = "storage.level"
+	consumerPositionPattern = "log.%s.consumer.%s.processed.position"
+	consumerLagPattern      = "log.%s.consumer.%s.lag"
 )
 
 type StatsdReporter struct {
@@ -76,3 +83,67 @@ func (sp *StatsdReporter) ReportLogStats(name string, stats log.Stat) (err error
 
 	return nil
 }
+
+func (sp *StatsdReporter) ReportLogThroughput(name string, direction string, recordsRate float64, bytesRate float64) (err error) {
+
+	recordsRateLabel := fmt.Sprintf(recordsRatePattern, name, direction)
+	err = sp.client.SetGauge(recordsRateLabel, int64(recordsRate))
+	if err != nil {
+		logger.Warn("statsd:", err)
+	}
+
+	bytesRateLabel := fmt.Sprintf(bytesRatePattern, name, direction)
+	err = sp.client.SetGauge(bytesRateLabel, int64(bytesRate))
+	if err != nil {
+		logger.Warn("statsd:", err)
+	}
+
+	return nil
+}
+
+func (sp *StatsdReporter) ReportStorageStats(usage int64, free int64, level int) (err error) {
+
+	err = sp.client.SetGauge(storageUsageLabel, usage)
+	if err != nil {
+		logger.Warn("statsd:", err)
+	}
+
+	err = sp.client.SetGauge(storageFreeLabel, free)
+	if err != nil {
+		logger.Warn("statsd:", err)
+	}
+
+	err = sp.client.SetGauge(storageLevelLabel, int64(level))
+	if err != nil {
+		logger.Warn("statsd:", err)
+	}
+
+	return nil
+}
+
+func (sp *StatsdReporter) ReportConsumerProgress(name string, session string, client string, position int64, lag int64) (err error) {
+
+	positionLabel := fmt.Sprintf(consumerPositionPattern, name, session)
+	err = sp.client.SetGauge(positionLabel, position)
+	if err != nil {
+		logger.Warn("statsd:", err)
+	}
+
+	lagLabel := fmt.Sprintf(consumerLagPattern, name, session)
+	err = sp.client.SetGauge(lagLabel, lag)
+	if err != nil {
+		logger.Warn("statsd:", err)
+	}
+
+	return nil
+}
+
+func (sp *StatsdReporter) ReportConsumerClosed(name string, session string, client string) (err error) {
+
+	return nil
+}
+
+func (sp *StatsdReporter) ReportLogLabels(name string, labels map[string]string) (err error) {
+
+	return nil
+}
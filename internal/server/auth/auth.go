@@ -0,0 +1,296 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/dataptive/styx/internal/server/config"
+)
+
+// Permission grants access to a class of operations on a log.
+type Permission int
+
+const (
+	PermissionRead  Permission = 1 << iota // Consume records and read log details.
+	PermissionWrite                        // Produce records.
+	PermissionAdmin                        // Create, delete, truncate, backup and restore logs, manage namespaces, reload the server config on "*".
+)
+
+const (
+	TokenQueryParam   = "access_token"
+	AnonymousIdentity = "anonymous"
+)
+
+var (
+	ErrUnauthenticated   = errors.New("auth: unauthenticated")
+	ErrForbidden         = errors.New("auth: forbidden")
+	ErrUnknownRole       = errors.New("auth: unknown role")
+	ErrUnknownPermission = errors.New("auth: unknown permission")
+	ErrInvalidPattern    = errors.New("auth: invalid log name pattern")
+
+	permissions = map[string]Permission{
+		"read":  PermissionRead,
+		"write": PermissionWrite,
+		// Admin implies all other permissions.
+		"admin": PermissionRead | PermissionWrite | PermissionAdmin,
+	}
+)
+
+type contextKey struct{}
+
+type rule struct {
+	pattern     string
+	permissions Permission
+}
+
+// Identity is an authenticated client along with the rules it was granted
+// through its roles.
+type Identity struct {
+	Name  string
+	rules []rule
+}
+
+// Allowed reports whether the identity holds permission p on the named log
+// or namespace, rules matching a namespace applying to all the logs and
+// namespaces it holds. A nil identity, used when authentication is disabled,
+// is allowed anything.
+func (id *Identity) Allowed(name string, p Permission) (allowed bool) {
+
+	if id == nil {
+		return true
+	}
+
+	for _, r := range id.rules {
+
+		if r.permissions&p != p {
+			continue
+		}
+
+		for current := name; current != ""; current = parent(current) {
+
+			match, _ := path.Match(r.pattern, current)
+			if match {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// AllowedAll reports whether the identity holds permission p on all logs
+// and namespaces, as granted by rules with the "*" pattern.
+func (id *Identity) AllowedAll(p Permission) (allowed bool) {
+
+	if id == nil {
+		return true
+	}
+
+	for _, r := range id.rules {
+
+		if r.permissions&p == p && r.pattern == "*" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parent returns the namespace holding name, empty when not namespaced.
+func parent(name string) (namespace string) {
+
+	i := strings.LastIndex(name, "/")
+	if i == -1 {
+		return ""
+	}
+
+	return name[:i]
+}
+
+// Authorizer authenticates clients from their API token or their TLS client
+// certificate and resolves their permissions.
+type Authorizer struct {
+	tokens       map[string]*Identity
+	certificates map[string]*Identity
+	anonymous    *Identity
+}
+
+func NewAuthorizer(c *config.AuthConfig) (a *Authorizer, err error) {
+
+	roles := make(map[string][]rule)
+
+	for _, role := range c.Roles {
+
+		granted := Permission(0)
+
+		for _, name := range role.Permissions {
+
+			p, exists := permissions[name]
+			if !exists {
+				return nil, ErrUnknownPermission
+			}
+
+			granted |= p
+		}
+
+		for _, pattern := range role.Logs {
+
+			_, err = path.Match(pattern, "")
+			if err != nil {
+				return nil, ErrInvalidPattern
+			}
+
+			roles[role.Name] = append(roles[role.Name], rule{
+				pattern:     pattern,
+				permissions: granted,
+			})
+		}
+	}
+
+	newIdentity := func(name string, roleNames []string) (id *Identity, err error) {
+
+		id = &Identity{
+			Name: name,
+		}
+
+		for _, roleName := range roleNames {
+
+			rules, exists := roles[roleName]
+			if !exists {
+				return nil, ErrUnknownRole
+			}
+
+			id.rules = append(id.rules, rules...)
+		}
+
+		return id, nil
+	}
+
+	a = &Authorizer{
+		tokens:       make(map[string]*Identity),
+		certificates: make(map[string]*Identity),
+		anonymous:    nil,
+	}
+
+	for _, token := range c.Tokens {
+
+		a.tokens[token.Token], err = newIdentity(token.Identity, token.Roles)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, certificate := range c.Certificates {
+
+		a.certificates[certificate.CommonName], err = newIdentity(certificate.CommonName, certificate.Roles)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(c.AnonymousRoles) > 0 {
+
+		a.anonymous, err = newIdentity(AnonymousIdentity, c.AnonymousRoles)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
+
+// Authenticate returns the identity of the client issuing r. Tokens are read
+// from the Authorization bearer header, or from the access_token query
+// parameter for clients such as browsers which cannot set headers on
+// websockets and event streams.
+func (a *Authorizer) Authenticate(r *http.Request) (id *Identity, err error) {
+
+	token := ""
+
+	authorization := r.Header.Get("Authorization")
+	if strings.HasPrefix(authorization, "Bearer ") {
+		token = strings.TrimPrefix(authorization, "Bearer ")
+	}
+
+	if token == "" {
+		token = r.URL.Query().Get(TokenQueryParam)
+	}
+
+	return a.authenticate(token, r.TLS)
+}
+
+// AuthenticateConn returns the identity of a raw styx protocol client from
+// the token sent in its handshake or from its TLS client certificate.
+func (a *Authorizer) AuthenticateConn(token string, state *tls.ConnectionState) (id *Identity, err error) {
+
+	return a.authenticate(token, state)
+}
+
+func (a *Authorizer) authenticate(token string, state *tls.ConnectionState) (id *Identity, err error) {
+
+	if token != "" {
+
+		// Compare all tokens in constant time.
+		for t, identity := range a.tokens {
+			if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+				id = identity
+			}
+		}
+
+		if id == nil {
+			return nil, ErrUnauthenticated
+		}
+
+		return id, nil
+	}
+
+	if state != nil && len(state.VerifiedChains) > 0 {
+
+		commonName := state.VerifiedChains[0][0].Subject.CommonName
+
+		id, exists := a.certificates[commonName]
+		if exists {
+			return id, nil
+		}
+	}
+
+	if a.anonymous != nil {
+		return a.anonymous, nil
+	}
+
+	return nil, ErrUnauthenticated
+}
+
+// WithIdentity returns a copy of ctx carrying id.
+func WithIdentity(ctx context.Context, id *Identity) (c context.Context) {
+
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the identity carried by ctx, nil when authentication
+// is disabled.
+func FromContext(ctx context.Context) (id *Identity) {
+
+	id, _ = ctx.Value(contextKey{}).(*Identity)
+
+	return id
+}
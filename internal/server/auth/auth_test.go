@@ -0,0 +1,367 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dataptive/styx/internal/logman"
+	"github.com/dataptive/styx/internal/server"
+	"github.com/dataptive/styx/internal/server/auth"
+	"github.com/dataptive/styx/internal/server/config"
+	"github.com/dataptive/styx/pkg/log"
+)
+
+var testAuthConfig = &config.AuthConfig{
+	AnonymousRoles: []string{"public"},
+	Roles: []config.RoleConfig{
+		{Name: "public", Logs: []string{"public"}, Permissions: []string{"read"}},
+		{Name: "team", Logs: []string{"team"}, Permissions: []string{"read", "write"}},
+		{Name: "events", Logs: []string{"events-*"}, Permissions: []string{"read"}},
+		{Name: "reader", Logs: []string{"*"}, Permissions: []string{"read"}},
+		{Name: "admin", Logs: []string{"*"}, Permissions: []string{"admin"}},
+		{Name: "team-admin", Logs: []string{"team"}, Permissions: []string{"admin"}},
+	},
+	Tokens: []config.TokenConfig{
+		{Identity: "team", Token: "team-token", Roles: []string{"team", "events"}},
+		{Identity: "reader", Token: "reader-token", Roles: []string{"reader"}},
+		{Identity: "admin", Token: "admin-token", Roles: []string{"admin"}},
+		{Identity: "team-admin", Token: "team-admin-token", Roles: []string{"team-admin"}},
+	},
+	Certificates: []config.CertificateConfig{
+		{CommonName: "service", Roles: []string{"team"}},
+	},
+}
+
+func TestIdentity_Allowed(t *testing.T) {
+
+	a := testAuth_New(t, testAuthConfig)
+
+	team := testAuth_Identity(t, a, "team-token")
+	reader := testAuth_Identity(t, a, "reader-token")
+	admin := testAuth_Identity(t, a, "admin-token")
+
+	cases := []struct {
+		id      *auth.Identity
+		name    string
+		p       auth.Permission
+		allowed bool
+	}{
+		// Rules on a namespace apply to all the logs it holds.
+		{team, "team", auth.PermissionWrite, true},
+		{team, "team/log", auth.PermissionWrite, true},
+		{team, "team/nested/log", auth.PermissionRead, true},
+		{team, "teams/log", auth.PermissionRead, false},
+		{team, "other/team", auth.PermissionRead, false},
+		{team, "team/log", auth.PermissionAdmin, false},
+
+		// Patterns match whole names, "*" not crossing namespaces but
+		// matching their outermost one.
+		{team, "events-a", auth.PermissionRead, true},
+		{team, "events-a/log", auth.PermissionRead, true},
+		{team, "events-a", auth.PermissionWrite, false},
+		{reader, "log", auth.PermissionRead, true},
+		{reader, "ns/nested/log", auth.PermissionRead, true},
+		{reader, "log", auth.PermissionWrite, false},
+
+		// Admin implies all other permissions.
+		{admin, "ns/log", auth.PermissionRead, true},
+		{admin, "ns/log", auth.PermissionWrite, true},
+		{admin, "ns/log", auth.PermissionAdmin, true},
+
+		// A nil identity, when auth is disabled, is allowed anything.
+		{nil, "ns/log", auth.PermissionAdmin, true},
+	}
+
+	for _, c := range cases {
+
+		allowed := c.id.Allowed(c.name, c.p)
+		if allowed != c.allowed {
+			t.Fatalf("%v on %s with permission %d should be allowed=%t but got %t", c.id, c.name, c.p, c.allowed, allowed)
+		}
+	}
+}
+
+func TestIdentity_AllowedAll(t *testing.T) {
+
+	a := testAuth_New(t, testAuthConfig)
+
+	cases := []struct {
+		token   string
+		allowed bool
+	}{
+		{"admin-token", true},
+		{"team-admin-token", false},
+		{"reader-token", false},
+		{"team-token", false},
+	}
+
+	for _, c := range cases {
+
+		allowed := testAuth_Identity(t, a, c.token).AllowedAll(auth.PermissionAdmin)
+		if allowed != c.allowed {
+			t.Fatalf("%s should be allowed=%t on all logs but got %t", c.token, c.allowed, allowed)
+		}
+	}
+}
+
+func TestAuthorizer_AuthenticateConn(t *testing.T) {
+
+	a := testAuth_New(t, testAuthConfig)
+
+	service := testAuth_ConnectionState("service")
+	unknown := testAuth_ConnectionState("unknown")
+
+	cases := []struct {
+		token    string
+		state    *tls.ConnectionState
+		identity string
+		err      error
+	}{
+		{"team-token", nil, "team", nil},
+		// Tokens take precedence over client certificates.
+		{"reader-token", service, "reader", nil},
+		{"", service, "service", nil},
+		// Unknown tokens never fall back to other identities.
+		{"invalid-token", nil, "", auth.ErrUnauthenticated},
+		{"invalid-token", service, "", auth.ErrUnauthenticated},
+		{"", nil, auth.AnonymousIdentity, nil},
+		{"", unknown, auth.AnonymousIdentity, nil},
+		// Unverified certificates are ignored.
+		{"", &tls.ConnectionState{}, auth.AnonymousIdentity, nil},
+	}
+
+	for _, c := range cases {
+
+		id, err := a.AuthenticateConn(c.token, c.state)
+		if err != c.err {
+			t.Fatalf("token %q should fail with err = %v but got %v", c.token, c.err, err)
+		}
+
+		if err == nil && id.Name != c.identity {
+			t.Fatalf("token %q should identify %s but got %s", c.token, c.identity, id.Name)
+		}
+	}
+
+	// Without anonymous roles, clients must identify.
+	withoutAnonymous := *testAuthConfig
+	withoutAnonymous.AnonymousRoles = nil
+
+	a = testAuth_New(t, &withoutAnonymous)
+
+	_, err := a.AuthenticateConn("", unknown)
+	if err != auth.ErrUnauthenticated {
+		t.Fatalf("unknown client should fail with err = %v but got %v", auth.ErrUnauthenticated, err)
+	}
+}
+
+func TestNewAuthorizer_Invalid(t *testing.T) {
+
+	cases := []struct {
+		config *config.AuthConfig
+		err    error
+	}{
+		{
+			&config.AuthConfig{
+				Roles: []config.RoleConfig{{Name: "r", Logs: []string{"*"}, Permissions: []string{"delete"}}},
+			},
+			auth.ErrUnknownPermission,
+		},
+		{
+			&config.AuthConfig{
+				Roles: []config.RoleConfig{{Name: "r", Logs: []string{"["}, Permissions: []string{"read"}}},
+			},
+			auth.ErrInvalidPattern,
+		},
+		{
+			&config.AuthConfig{
+				Tokens: []config.TokenConfig{{Identity: "i", Token: "t", Roles: []string{"missing"}}},
+			},
+			auth.ErrUnknownRole,
+		},
+		{
+			&config.AuthConfig{
+				AnonymousRoles: []string{"missing"},
+			},
+			auth.ErrUnknownRole,
+		},
+	}
+
+	for _, c := range cases {
+
+		_, err := auth.NewAuthorizer(c.config)
+		if err != c.err {
+			t.Fatalf("config %+v should fail with err = %v but got %v", c.config, c.err, err)
+		}
+	}
+}
+
+func TestRouter_Auth(t *testing.T) {
+
+	managerConfig := logman.DefaultConfig
+	managerConfig.DataDirectory = t.TempDir()
+
+	lm, err := logman.NewLogManager(managerConfig, nopReporter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lm.Close()
+
+	for _, name := range []string{"public", "team/log", "other"} {
+
+		_, err = lm.CreateLog(name, log.DefaultConfig, logman.Metadata{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	withoutAnonymous := *testAuthConfig
+	withoutAnonymous.AnonymousRoles = nil
+
+	reloader := &testReloader{}
+	router := server.NewRouter(lm, config.Config{}, testAuth_New(t, &withoutAnonymous), reloader)
+
+	cases := []struct {
+		method string
+		path   string
+		token  string
+		status int
+	}{
+		// Unidentified clients are unauthorized, identified clients
+		// lacking permissions are forbidden.
+		{http.MethodGet, "/logs/public", "", http.StatusUnauthorized},
+		{http.MethodGet, "/logs/public", "invalid-token", http.StatusUnauthorized},
+		{http.MethodGet, "/logs/other", "team-token", http.StatusForbidden},
+		{http.MethodGet, "/logs/team/log", "team-token", http.StatusOK},
+		{http.MethodGet, "/logs/other", "reader-token", http.StatusOK},
+
+		// Reloading requires admin on all logs.
+		{http.MethodPost, "/reload", "", http.StatusUnauthorized},
+		{http.MethodPost, "/reload", "reader-token", http.StatusForbidden},
+		{http.MethodPost, "/reload", "team-admin-token", http.StatusForbidden},
+		{http.MethodPost, "/reload", "admin-token", http.StatusOK},
+	}
+
+	for _, c := range cases {
+
+		r := httptest.NewRequest(c.method, c.path, nil)
+		if c.token != "" {
+			r.Header.Set("Authorization", "Bearer "+c.token)
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		if w.Code != c.status {
+			t.Fatalf("%s %s with token %q should respond %d but got %d", c.method, c.path, c.token, c.status, w.Code)
+		}
+
+		if c.status == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") != "Bearer" {
+			t.Fatalf("%s %s with token %q should challenge for a bearer token", c.method, c.path, c.token)
+		}
+	}
+
+	if reloader.count != 1 {
+		t.Fatalf("config should have been reloaded once but was %d times", reloader.count)
+	}
+}
+
+func testAuth_New(t *testing.T, c *config.AuthConfig) (a *auth.Authorizer) {
+
+	a, err := auth.NewAuthorizer(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return a
+}
+
+func testAuth_Identity(t *testing.T, a *auth.Authorizer, token string) (id *auth.Identity) {
+
+	id, err := a.AuthenticateConn(token, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
+
+// testAuth_ConnectionState returns the state of a TLS connection whose client
+// certificate was verified.
+func testAuth_ConnectionState(commonName string) (state *tls.ConnectionState) {
+
+	certificate := &x509.Certificate{
+		Subject: pkix.Name{CommonName: commonName},
+	}
+
+	state = &tls.ConnectionState{
+		VerifiedChains: [][]*x509.Certificate{{certificate}},
+	}
+
+	return state
+}
+
+type testReloader struct {
+	count int
+}
+
+func (tr *testReloader) Reload() (restartRequired []string, err error) {
+
+	tr.count++
+
+	return []string{}, nil
+}
+
+type nopReporter struct{}
+
+func (nopReporter) ReportLogStats(string, log.Stat) (err error) {
+
+	return nil
+}
+
+func (nopReporter) ReportLogThroughput(string, string, float64, float64) (err error) {
+
+	return nil
+}
+
+func (nopReporter) ReportStorageStats(int64, int64, int) (err error) {
+
+	return nil
+}
+
+func (nopReporter) ReportConsumerProgress(string, string, string, int64, int64) (err error) {
+
+	return nil
+}
+
+func (nopReporter) ReportConsumerClosed(string, string, string) (err error) {
+
+	return nil
+}
+
+func (nopReporter) ReportLogLabels(string, map[string]string) (err error) {
+
+	return nil
+}
+
+func (nopReporter) Close() (err error) {
+
+	return nil
+}
@@ -15,16 +15,44 @@
 package config
 
 import (
+	"crypto/tls"
+	"errors"
+	"os"
+	"path"
+	"reflect"
+	"strconv"
+
 	"github.com/dataptive/styx/internal/logman"
 	"github.com/dataptive/styx/internal/metrics"
 	"github.com/dataptive/styx/internal/metrics/statsd"
+	"github.com/dataptive/styx/pkg/compress"
+	"github.com/dataptive/styx/pkg/log"
 
 	"github.com/BurntSushi/toml"
 )
 
+var (
+	ErrInvalidSocketMode  = errors.New("config: invalid unix socket mode")
+	ErrInvalidClientAuth  = errors.New("config: invalid tls client auth")
+	ErrMissingTLSKeyPair  = errors.New("config: tls requires both cert_file and key_file")
+	ErrInvalidCompression = errors.New("config: invalid compression codec")
+	ErrInvalidTemplate    = errors.New("config: invalid log template pattern")
+	ErrInvalidReadiness   = errors.New("config: invalid readiness log pattern")
+
+	clientAuthTypes = map[string]tls.ClientAuthType{
+		"":         tls.NoClientCert,
+		"none":     tls.NoClientCert,
+		"optional": tls.VerifyClientCertIfGiven,
+		"required": tls.RequireAndVerifyClientCert,
+	}
+)
+
 type TOMLConfig struct {
 	PIDFile             string               `toml:"pid_file"`
 	BindAddress         string               `toml:"bind_address"`
+	StyxBindAddress     string               `toml:"styx_bind_address"`
+	UnixSocketPath      string               `toml:"unix_socket_path"`
+	UnixSocketMode      string               `toml:"unix_socket_mode"`
 	ShutdownTimeout     int                  `toml:"shutdown_timeout"`
 	CORSAllowedOrigins  []string             `toml:"cors_allowed_origins"`
 	HTTPReadBufferSize  int                  `toml:"http_read_buffer_size"`
@@ -34,14 +62,91 @@ type TOMLConfig struct {
 	WSReadBufferSize    int                  `toml:"websocket_read_buffer_size"`
 	WSWriteBufferSize   int                  `toml:"websocket_write_buffer_size"`
 	TCPTimeout          int                  `toml:"tcp_timeout"`
+	CompressionCodecs   []string             `toml:"compression_codecs"`
+	WSCompression       bool                 `toml:"websocket_compression"`
+	TLS                 *TOMLTLSConfig       `toml:"tls"`
+	Auth                *TOMLAuthConfig      `toml:"auth"`
 	LogManager          TOMLLogManagerConfig `toml:"log_manager"`
+	Limits              TOMLLimitsConfig     `toml:"limits"`
+	Readiness           TOMLReadinessConfig  `toml:"readiness"`
 	Metrics             TOMLMetricsConfig    `toml:"metrics"`
 }
 
+type TOMLTLSConfig struct {
+	CertFile     string `toml:"cert_file"`
+	KeyFile      string `toml:"key_file"`
+	ClientCAFile string `toml:"client_ca_file"`
+	ClientAuth   string `toml:"client_auth"`
+}
+
+type TOMLAuthConfig struct {
+	AnonymousRoles []string                `toml:"anonymous_roles"`
+	Roles          []TOMLRoleConfig        `toml:"roles"`
+	Tokens         []TOMLTokenConfig       `toml:"tokens"`
+	Certificates   []TOMLCertificateConfig `toml:"certificates"`
+}
+
+type TOMLRoleConfig struct {
+	Name        string   `toml:"name"`
+	Logs        []string `toml:"logs"`
+	Permissions []string `toml:"permissions"`
+}
+
+type TOMLTokenConfig struct {
+	Identity string   `toml:"identity"`
+	Token    string   `toml:"token"`
+	Roles    []string `toml:"roles"`
+}
+
+type TOMLCertificateConfig struct {
+	CommonName string   `toml:"common_name"`
+	Roles      []string `toml:"roles"`
+}
+
 type TOMLLogManagerConfig struct {
-	DataDirectory   string `toml:"data_directory"`
-	ReadBufferSize  int    `toml:"read_buffer_size"`
-	WriteBufferSize int    `toml:"write_buffer_size"`
+	DataDirectory   string               `toml:"data_directory"`
+	ReadBufferSize  int                  `toml:"read_buffer_size"`
+	WriteBufferSize int                  `toml:"write_buffer_size"`
+	Quota           TOMLQuotaConfig      `toml:"quota"`
+	Templates       []TOMLTemplateConfig `toml:"templates"`
+	DefaultTemplate *TOMLTemplateConfig  `toml:"default_template"`
+}
+
+type TOMLTemplateConfig struct {
+	Pattern         string `toml:"pattern"`
+	AutoCreate      *bool  `toml:"auto_create"`
+	MaxRecordSize   int    `toml:"max_record_size"`
+	IndexAfterSize  int64  `toml:"index_after_size"`
+	SegmentMaxCount int64  `toml:"segment_max_count"`
+	SegmentMaxSize  int64  `toml:"segment_max_size"`
+	SegmentMaxAge   int64  `toml:"segment_max_age"`
+	LogMaxCount     int64  `toml:"log_max_count"`
+	LogMaxSize      int64  `toml:"log_max_size"`
+	LogMaxAge       int64  `toml:"log_max_age"`
+}
+
+type TOMLQuotaConfig struct {
+	SoftMaxUsage   int64    `toml:"soft_max_usage"`
+	HardMaxUsage   int64    `toml:"hard_max_usage"`
+	SoftMinFree    int64    `toml:"soft_min_free"`
+	HardMinFree    int64    `toml:"hard_min_free"`
+	ExpendableLogs []string `toml:"expendable_logs"`
+}
+
+type TOMLLimitsConfig struct {
+	Log    TOMLRateLimitsConfig `toml:"log"`
+	Client TOMLRateLimitsConfig `toml:"client"`
+}
+
+type TOMLRateLimitsConfig struct {
+	WriteRecordsRate int64 `toml:"write_records_rate"`
+	WriteBytesRate   int64 `toml:"write_bytes_rate"`
+	ReadRecordsRate  int64 `toml:"read_records_rate"`
+	ReadBytesRate    int64 `toml:"read_bytes_rate"`
+}
+
+type TOMLReadinessConfig struct {
+	RequiredLogs []string `toml:"required_logs"`
 }
 
 type TOMLMetricsConfig struct {
@@ -57,6 +162,9 @@ type TOMLStatsdConfig struct {
 type Config struct {
 	PIDFile             string
 	BindAddress         string
+	StyxBindAddress     string
+	UnixSocketPath      string
+	UnixSocketMode      os.FileMode
 	ShutdownTimeout     int
 	CORSAllowedOrigins  []string
 	HTTPReadBufferSize  int
@@ -66,10 +174,57 @@ type Config struct {
 	WSReadBufferSize    int
 	WSWriteBufferSize   int
 	TCPTimeout          int
+	CompressionCodecs   []string
+	WSCompression       bool
+	TLS                 *TLSConfig
+	Auth                *AuthConfig
 	LogManager          logman.Config
+	Readiness           ReadinessConfig
 	Metrics             metrics.Config
 }
 
+// ReadinessConfig holds the patterns of the logs which must be available for
+// the server to report ready, all logs being required when empty.
+type ReadinessConfig struct {
+	RequiredLogs []string
+}
+
+// AuthConfig enables authentication and authorization on logs routes when
+// set. Tokens and client certificates identities are granted roles, which
+// give permissions on the logs whose names match their patterns.
+type AuthConfig struct {
+	AnonymousRoles []string
+	Roles          []RoleConfig
+	Tokens         []TokenConfig
+	Certificates   []CertificateConfig
+}
+
+type RoleConfig struct {
+	Name        string
+	Logs        []string
+	Permissions []string
+}
+
+type TokenConfig struct {
+	Identity string
+	Token    string
+	Roles    []string
+}
+
+type CertificateConfig struct {
+	CommonName string
+	Roles      []string
+}
+
+// TLSConfig enables TLS on the HTTP and raw styx protocol listeners when
+// set. Client certificates are verified against ClientCAFile.
+type TLSConfig struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string
+	ClientAuth   tls.ClientAuthType
+}
+
 func Load(path string) (c Config, err error) {
 
 	tc := &TOMLConfig{}
@@ -81,6 +236,19 @@ func Load(path string) (c Config, err error) {
 
 	c.PIDFile = tc.PIDFile
 	c.BindAddress = tc.BindAddress
+	c.StyxBindAddress = tc.StyxBindAddress
+	c.UnixSocketPath = tc.UnixSocketPath
+
+	if tc.UnixSocketMode != "" {
+
+		mode, err := strconv.ParseUint(tc.UnixSocketMode, 8, 32)
+		if err != nil {
+			return c, ErrInvalidSocketMode
+		}
+
+		c.UnixSocketMode = os.FileMode(mode)
+	}
+
 	c.ShutdownTimeout = tc.ShutdownTimeout
 	c.CORSAllowedOrigins = tc.CORSAllowedOrigins
 	c.HTTPReadBufferSize = tc.HTTPReadBufferSize
@@ -90,10 +258,172 @@ func Load(path string) (c Config, err error) {
 	c.WSReadBufferSize = tc.WSReadBufferSize
 	c.WSWriteBufferSize = tc.WSWriteBufferSize
 	c.TCPTimeout = tc.TCPTimeout
-	c.LogManager = logman.Config(tc.LogManager)
+
+	for _, codec := range tc.CompressionCodecs {
+		if codec == compress.Identity || !compress.Supported(codec) {
+			return c, ErrInvalidCompression
+		}
+	}
+
+	c.CompressionCodecs = tc.CompressionCodecs
+	c.WSCompression = tc.WSCompression
+
+	if tc.TLS != nil {
+
+		if tc.TLS.CertFile == "" || tc.TLS.KeyFile == "" {
+			return c, ErrMissingTLSKeyPair
+		}
+
+		clientAuth, ok := clientAuthTypes[tc.TLS.ClientAuth]
+		if !ok {
+			return c, ErrInvalidClientAuth
+		}
+
+		c.TLS = &TLSConfig{
+			CertFile:     tc.TLS.CertFile,
+			KeyFile:      tc.TLS.KeyFile,
+			ClientCAFile: tc.TLS.ClientCAFile,
+			ClientAuth:   clientAuth,
+		}
+	}
+
+	if tc.Auth != nil {
+
+		c.Auth = &AuthConfig{
+			AnonymousRoles: tc.Auth.AnonymousRoles,
+		}
+
+		for _, role := range tc.Auth.Roles {
+			c.Auth.Roles = append(c.Auth.Roles, RoleConfig(role))
+		}
+
+		for _, token := range tc.Auth.Tokens {
+			c.Auth.Tokens = append(c.Auth.Tokens, TokenConfig(token))
+		}
+
+		for _, certificate := range tc.Auth.Certificates {
+			c.Auth.Certificates = append(c.Auth.Certificates, CertificateConfig(certificate))
+		}
+	}
+
+	c.LogManager = logman.Config{
+		DataDirectory:   tc.LogManager.DataDirectory,
+		ReadBufferSize:  tc.LogManager.ReadBufferSize,
+		WriteBufferSize: tc.LogManager.WriteBufferSize,
+		LogLimits:       logman.Limits(tc.Limits.Log),
+		ClientLimits:    logman.Limits(tc.Limits.Client),
+		Quota:           logman.Quota(tc.LogManager.Quota),
+	}
+	for _, template := range tc.LogManager.Templates {
+
+		t, err := newTemplate(template)
+		if err != nil {
+			return c, err
+		}
+
+		c.LogManager.Templates = append(c.LogManager.Templates, t)
+	}
+
+	if tc.LogManager.DefaultTemplate != nil {
+
+		// The default template applies to logs matching
+		// no other template.
+		tc.LogManager.DefaultTemplate.Pattern = "*"
+
+		t, err := newTemplate(*tc.LogManager.DefaultTemplate)
+		if err != nil {
+			return c, err
+		}
+
+		c.LogManager.DefaultTemplate = &t
+	}
+
+	for _, pattern := range tc.Readiness.RequiredLogs {
+		if !validPattern(pattern) {
+			return c, ErrInvalidReadiness
+		}
+	}
+
+	c.Readiness = ReadinessConfig(tc.Readiness)
+
 	c.Metrics = metrics.Config{
 		Statsd: (*statsd.Config)(tc.Metrics.Statsd),
 	}
 
 	return c, nil
 }
+
+// RestartRequired returns the settings of the config file which differ
+// between the running config and c and only apply on restart. Other settings
+// are applied on reload.
+func RestartRequired(running Config, c Config) (settings []string) {
+
+	restartSettings := []struct {
+		name    string
+		running interface{}
+		value   interface{}
+	}{
+		{"pid_file", running.PIDFile, c.PIDFile},
+		{"bind_address", running.BindAddress, c.BindAddress},
+		{"styx_bind_address", running.StyxBindAddress, c.StyxBindAddress},
+		{"unix_socket_path", running.UnixSocketPath, c.UnixSocketPath},
+		{"unix_socket_mode", running.UnixSocketMode, c.UnixSocketMode},
+		{"shutdown_timeout", running.ShutdownTimeout, c.ShutdownTimeout},
+		{"tls", running.TLS, c.TLS},
+		{"log_manager.data_directory", running.LogManager.DataDirectory, c.LogManager.DataDirectory},
+		{"log_manager.read_buffer_size", running.LogManager.ReadBufferSize, c.LogManager.ReadBufferSize},
+		{"log_manager.write_buffer_size", running.LogManager.WriteBufferSize, c.LogManager.WriteBufferSize},
+		{"log_manager.quota", running.LogManager.Quota, c.LogManager.Quota},
+		{"limits.log", running.LogManager.LogLimits, c.LogManager.LogLimits},
+		{"limits.client", running.LogManager.ClientLimits, c.LogManager.ClientLimits},
+	}
+
+	settings = []string{}
+
+	for _, setting := range restartSettings {
+		if !reflect.DeepEqual(setting.running, setting.value) {
+			settings = append(settings, setting.name)
+		}
+	}
+
+	return settings
+}
+
+// newTemplate converts a template from the config file, templates creating
+// logs on write unless auto_create is false.
+func newTemplate(tt TOMLTemplateConfig) (t logman.Template, err error) {
+
+	if !validPattern(tt.Pattern) {
+		return t, ErrInvalidTemplate
+	}
+
+	autoCreate := true
+	if tt.AutoCreate != nil {
+		autoCreate = *tt.AutoCreate
+	}
+
+	t = logman.Template{
+		Pattern:    tt.Pattern,
+		AutoCreate: autoCreate,
+		LogConfig: log.Config{
+			MaxRecordSize:   tt.MaxRecordSize,
+			IndexAfterSize:  tt.IndexAfterSize,
+			SegmentMaxCount: tt.SegmentMaxCount,
+			SegmentMaxSize:  tt.SegmentMaxSize,
+			SegmentMaxAge:   tt.SegmentMaxAge,
+			LogMaxCount:     tt.LogMaxCount,
+			LogMaxSize:      tt.LogMaxSize,
+			LogMaxAge:       tt.LogMaxAge,
+		},
+	}
+
+	return t, nil
+}
+
+// validPattern reports whether pattern is a valid log name pattern.
+func validPattern(pattern string) (valid bool) {
+
+	_, err := path.Match(pattern, "")
+
+	return pattern != "" && err == nil
+}
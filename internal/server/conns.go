@@ -0,0 +1,99 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// connCounter counts the client connections open on the listeners it
+// tracks, including those hijacked by websocket and styx protocol upgrades.
+type connCounter struct {
+	count int64
+}
+
+func (cc *connCounter) track(listener net.Listener) (l net.Listener) {
+
+	l = &countedListener{
+		Listener: listener,
+		counter:  cc,
+	}
+
+	return l
+}
+
+func (cc *connCounter) get() (count int64) {
+
+	return atomic.LoadInt64(&cc.count)
+}
+
+type countedListener struct {
+	net.Listener
+	counter *connCounter
+}
+
+func (l *countedListener) Accept() (conn net.Conn, err error) {
+
+	conn, err = l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	atomic.AddInt64(&l.counter.count, 1)
+
+	conn = &countedConn{
+		Conn:    conn,
+		counter: l.counter,
+	}
+
+	return conn, nil
+}
+
+type countedConn struct {
+	net.Conn
+	counter   *connCounter
+	closeOnce sync.Once
+}
+
+func (c *countedConn) Close() (err error) {
+
+	err = c.Conn.Close()
+
+	c.closeOnce.Do(func() {
+		atomic.AddInt64(&c.counter.count, -1)
+	})
+
+	return err
+}
+
+// CloseWrite half-closes the wrapped connection, as TCP and Unix domain
+// socket connections support, closing it otherwise.
+func (c *countedConn) CloseWrite() (err error) {
+
+	cw, ok := c.Conn.(interface{ CloseWrite() error })
+	if !ok {
+		return c.Close()
+	}
+
+	return cw.CloseWrite()
+}
+
+// NetConn returns the wrapped connection.
+func (c *countedConn) NetConn() (conn net.Conn) {
+
+	return c.Conn
+}
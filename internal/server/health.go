@@ -0,0 +1,72 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/dataptive/styx/pkg/api"
+)
+
+// healthHandler reports the process alive, regardless of the state of logs.
+func (r *Router) healthHandler(w http.ResponseWriter, req *http.Request) {
+
+	api.WriteResponse(w, http.StatusOK, api.HealthInfo{Status: "ok"})
+}
+
+// readyHandler reports whether the logs required by the readiness rules are
+// open and available, failing with 503 otherwise.
+func (r *Router) readyHandler(w http.ResponseWriter, req *http.Request) {
+
+	readiness := r.readiness()
+
+	status := http.StatusOK
+	if !readiness.Ready {
+		status = http.StatusServiceUnavailable
+	}
+
+	api.WriteResponse(w, status, readiness)
+}
+
+func (r *Router) statusHandler(w http.ResponseWriter, req *http.Request) {
+
+	serverStatus := api.ServerStatus{
+		Version:     Version,
+		Uptime:      int64(time.Since(r.startTime) / time.Second),
+		Ready:       r.readiness().Ready,
+		Storage:     api.StorageInfo(r.logManager.Storage()),
+		Logs:        r.logManager.CountLogs(),
+		Connections: r.conns.get(),
+	}
+
+	api.WriteResponse(w, http.StatusOK, serverStatus)
+}
+
+func (r *Router) readiness() (readiness api.ReadinessInfo) {
+
+	r.configLock.RLock()
+	requiredLogs := r.config.Readiness.RequiredLogs
+	r.configLock.RUnlock()
+
+	unavailable := r.logManager.UnavailableLogs(requiredLogs)
+
+	readiness = api.ReadinessInfo{
+		Ready:           len(unavailable) == 0,
+		UnavailableLogs: len(unavailable),
+	}
+
+	return readiness
+}
@@ -0,0 +1,119 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs_routes
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/dataptive/styx/internal/server/auth"
+	"github.com/dataptive/styx/pkg/api"
+	"github.com/dataptive/styx/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+// authenticate identifies the client before calling next, carrying its
+// identity in the request context. It is a no-op when auth is disabled.
+func (lr *LogsRouter) authenticate(next http.HandlerFunc) (h http.HandlerFunc) {
+
+	h = func(w http.ResponseWriter, r *http.Request) {
+
+		// Auth may be enabled or disabled on reload.
+		authorizer := lr.currentAuthorizer()
+		if authorizer == nil {
+			next(w, r)
+			return
+		}
+
+		id, err := authorizer.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			api.WriteError(w, http.StatusUnauthorized, api.ErrUnauthorized)
+			logger.Debug(err)
+			return
+		}
+
+		next(w, r.WithContext(auth.WithIdentity(r.Context(), id)))
+	}
+
+	return h
+}
+
+// authorize checks the client holds permission p on the log named in the
+// route before calling next. Permissions of renamed logs reached through their
+// previous name are checked against their current name.
+func (lr *LogsRouter) authorize(p auth.Permission, next http.HandlerFunc) (h http.HandlerFunc) {
+
+	h = func(w http.ResponseWriter, r *http.Request) {
+
+		name := lr.manager.ResolveName(mux.Vars(r)["name"])
+
+		if !allowed(r, name, p) {
+			api.WriteError(w, http.StatusForbidden, api.ErrForbidden)
+			logger.Debug(auth.ErrForbidden)
+			return
+		}
+
+		next(w, r)
+	}
+
+	return lr.authenticate(h)
+}
+
+// AuthorizeServer checks the client holds permission p on all logs before
+// calling next, guarding routes acting on the whole server.
+func (lr *LogsRouter) AuthorizeServer(p auth.Permission, next http.HandlerFunc) (h http.HandlerFunc) {
+
+	h = func(w http.ResponseWriter, r *http.Request) {
+
+		if !auth.FromContext(r.Context()).AllowedAll(p) {
+			api.WriteError(w, http.StatusForbidden, api.ErrForbidden)
+			logger.Debug(auth.ErrForbidden)
+			return
+		}
+
+		next(w, r)
+	}
+
+	return lr.authenticate(h)
+}
+
+func allowed(r *http.Request, name string, p auth.Permission) (ok bool) {
+
+	return auth.FromContext(r.Context()).Allowed(name, p)
+}
+
+// clientID identifies the client issuing r for per client rate limits.
+func clientID(r *http.Request) (id string) {
+
+	return connClientID(auth.FromContext(r.Context()), r.RemoteAddr)
+}
+
+// connClientID identifies a client by its identity when authenticated, and
+// by its remote host otherwise.
+func connClientID(identity *auth.Identity, remoteAddr string) (id string) {
+
+	if identity != nil && identity.Name != auth.AnonymousIdentity {
+		return identity.Name
+	}
+
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,145 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs_routes
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/dataptive/styx/pkg/compress"
+)
+
+var (
+	ErrUnsupportedEncoding = errors.New("server: unsupported content encoding")
+)
+
+// requestBody returns the request body, decompressed according to its
+// Content-Encoding header. Only the configured codecs are accepted.
+func (lr *LogsRouter) requestBody(r *http.Request) (body io.ReadCloser, err error) {
+
+	codec := r.Header.Get("Content-Encoding")
+
+	if codec == "" || codec == compress.Identity {
+		return r.Body, nil
+	}
+
+	if !lr.codecEnabled(codec) {
+		return nil, ErrUnsupportedEncoding
+	}
+
+	body, err = compress.NewReader(codec, r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// responseCodec negotiates the codec used to compress the response body from
+// the request Accept-Encoding header.
+func (lr *LogsRouter) responseCodec(r *http.Request) (codec string) {
+
+	return compress.Negotiate(r.Header.Get("Accept-Encoding"), lr.currentConfig().CompressionCodecs)
+}
+
+// connCodec negotiates the codec used to compress a styx protocol connection
+// from the codecs offered by the client.
+func (lr *LogsRouter) connCodec(offered string) (codec string) {
+
+	return compress.Negotiate(offered, lr.currentConfig().CompressionCodecs)
+}
+
+// compressConn wraps a styx protocol connection to compress its stream with
+// codec. The connection is returned unchanged for the identity codec.
+func compressConn(conn net.Conn, codec string) (c net.Conn, err error) {
+
+	if codec == compress.Identity {
+		return conn, nil
+	}
+
+	c, err = compress.NewConn(conn, codec)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (lr *LogsRouter) codecEnabled(codec string) (enabled bool) {
+
+	for _, enabledCodec := range lr.currentConfig().CompressionCodecs {
+		if codec == enabledCodec {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compressResponse sets the response headers for codec and returns a writer
+// compressing the response body. It must be called before writing the
+// response header, and the returned writer closed once the body is written.
+func compressResponse(w http.ResponseWriter, codec string) (cw compress.Writer, err error) {
+
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	if codec != compress.Identity {
+		w.Header().Set("Content-Encoding", codec)
+	}
+
+	writer, err := compress.NewWriter(codec, w)
+	if err != nil {
+		return nil, err
+	}
+
+	cw = &flushWriter{
+		writer: writer,
+	}
+
+	return cw, nil
+}
+
+// flushWriter flushes the compressor after each write, so that records
+// buffered by handlers are not held back when following a log.
+type flushWriter struct {
+	writer compress.Writer
+}
+
+func (fw *flushWriter) Write(p []byte) (n int, err error) {
+
+	n, err = fw.writer.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	err = fw.writer.Flush()
+	if err != nil {
+		return n, err
+	}
+
+	return n, nil
+}
+
+func (fw *flushWriter) Flush() (err error) {
+
+	return fw.writer.Flush()
+}
+
+func (fw *flushWriter) Close() (err error) {
+
+	return fw.writer.Close()
+}
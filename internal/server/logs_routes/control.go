@@ -0,0 +1,275 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs_routes
+
+import (
+	"sync"
+
+	"github.com/dataptive/styx/internal/logman"
+	"github.com/dataptive/styx/pkg/api"
+	"github.com/dataptive/styx/pkg/log"
+	"github.com/dataptive/styx/pkg/logger"
+	"github.com/dataptive/styx/pkg/recio"
+)
+
+type seekRequest struct {
+	position int64
+	whence   log.Whence
+}
+
+// readControl holds the state of a single log record stream that consumers
+// act on while it is running. Control methods are called from the goroutine
+// reading the connection, and interrupt the reader to unlock follow. Only the
+// stream goroutine, calling Wait between records and Close once done, reopens
+// or closes the reader.
+type readControl struct {
+	log    *logman.Log
+	client string
+	follow bool
+	reader *log.LogReader
+	limit  int64
+	count  int64
+	paused bool
+	seek   *seekRequest
+	closed bool
+	wake   chan struct{}
+	lock   sync.Mutex
+}
+
+func newReadControl(managedLog *logman.Log, client string, follow bool, reader *log.LogReader, limit int64) (rc *readControl) {
+
+	rc = &readControl{
+		log:    managedLog,
+		client: client,
+		follow: follow,
+		reader: reader,
+		limit:  limit,
+		count:  0,
+		paused: false,
+		seek:   nil,
+		closed: false,
+		wake:   make(chan struct{}, 1),
+	}
+
+	return rc
+}
+
+// Seek moves the stream to position relative to whence. Records read from
+// the previous position may still be sent until Wait applies the seek.
+func (rc *readControl) Seek(position int64, whence log.Whence) {
+
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	rc.seek = &seekRequest{
+		position: position,
+		whence:   whence,
+	}
+
+	rc.interrupt()
+}
+
+// Pause stops the stream until Resume is called.
+func (rc *readControl) Pause() {
+
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	rc.paused = true
+
+	rc.signal()
+}
+
+func (rc *readControl) Resume() {
+
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	rc.paused = false
+
+	rc.signal()
+}
+
+// SetCount ends the stream after count more records, or never when count is
+// negative.
+func (rc *readControl) SetCount(count int64) {
+
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	rc.limit = count
+	rc.count = 0
+
+	rc.interrupt()
+}
+
+// Stop ends the stream, the stream goroutine closing the reader once it
+// returns from Wait.
+func (rc *readControl) Stop() {
+
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	rc.closed = true
+
+	rc.interrupt()
+}
+
+// Close ends the stream and closes the current reader. It must be called
+// from the stream goroutine.
+func (rc *readControl) Close() (err error) {
+
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	rc.closed = true
+
+	return rc.reader.Close()
+}
+
+// Reader returns the log reader the stream reads from.
+func (rc *readControl) Reader() (lr *log.LogReader) {
+
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	return rc.reader
+}
+
+// Sent accounts for a record sent to the consumer.
+func (rc *readControl) Sent() {
+
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	rc.count++
+}
+
+// Wait applies pending controls before the stream reads the next record. It
+// blocks while the stream is paused, calling flush beforehand when not nil.
+// It returns seeked when a seek was handled, so that the stream can tell the
+// consumer, seekErr telling why it failed when the stream goes on from its
+// previous position, and done when the stream must end.
+func (rc *readControl) Wait(flush func() error) (seeked bool, seekErr error, done bool, err error) {
+
+	for {
+		rc.lock.Lock()
+
+		if rc.closed {
+			rc.lock.Unlock()
+			return false, nil, true, nil
+		}
+
+		if rc.seek != nil {
+			request := *rc.seek
+			rc.seek = nil
+
+			seekErr = rc.reopen(request)
+			rc.lock.Unlock()
+
+			return true, seekErr, false, nil
+		}
+
+		if rc.count == rc.limit {
+			rc.lock.Unlock()
+			return false, nil, true, nil
+		}
+
+		paused := rc.paused
+
+		rc.lock.Unlock()
+
+		if !paused {
+			return false, nil, false, nil
+		}
+
+		if flush != nil {
+			err = flush()
+			if err != nil {
+				return false, nil, false, err
+			}
+		}
+
+		<-rc.wake
+	}
+}
+
+// reopen replaces the current reader with a new one positioned as
+// requested, the current reader being kept on error. It must be called with
+// the lock held.
+func (rc *readControl) reopen(request seekRequest) (err error) {
+
+	if request.whence == "" {
+		request.whence = log.SeekOrigin
+	}
+
+	params := api.ConsumeParams{
+		Whence: request.whence,
+	}
+
+	err = params.Validate()
+	if err != nil {
+		return err
+	}
+
+	// The new reader starts from the origin, seek relatively to the
+	// position following the last record read by the stream instead.
+	if request.whence == log.SeekCurrent {
+		current, _ := rc.reader.Tell()
+
+		request.position += current
+		request.whence = log.SeekOrigin
+	}
+
+	// Keep the current reader until the new one is positioned, so that
+	// a failed seek leaves the stream running.
+	reader, err := rc.log.NewReader(rc.client, rc.follow, recio.ModeManual)
+	if err != nil {
+		return err
+	}
+
+	err = reader.Seek(request.position, request.whence)
+	if err != nil {
+		reader.Close()
+		return err
+	}
+
+	previous := rc.reader
+	rc.reader = reader
+
+	err = previous.Close()
+	if err != nil {
+		logger.Debug(err)
+	}
+
+	return nil
+}
+
+// interrupt wakes up the stream, waiting either for records or in Wait. It
+// must be called with the lock held.
+func (rc *readControl) interrupt() {
+
+	rc.reader.Interrupt()
+
+	rc.signal()
+}
+
+func (rc *readControl) signal() {
+
+	select {
+	case rc.wake <- struct{}{}:
+	default:
+	}
+}
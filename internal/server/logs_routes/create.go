@@ -18,6 +18,7 @@ import (
 	"net/http"
 
 	"github.com/dataptive/styx/internal/logman"
+	"github.com/dataptive/styx/internal/server/auth"
 	"github.com/dataptive/styx/pkg/api"
 	"github.com/dataptive/styx/pkg/log"
 	"github.com/dataptive/styx/pkg/logger"
@@ -25,13 +26,6 @@ import (
 
 func (lr *LogsRouter) CreateHandler(w http.ResponseWriter, r *http.Request) {
 
-	config := log.DefaultConfig
-
-	form := api.CreateLogForm{
-		Name:      "",
-		LogConfig: (*api.LogConfig)(&config),
-	}
-
 	err := r.ParseForm()
 	if err != nil {
 		api.WriteError(w, http.StatusBadRequest, api.ErrUnknownError)
@@ -39,6 +33,16 @@ func (lr *LogsRouter) CreateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Fields missing from the form default to the config of the
+	// namespaces holding the log.
+	config := lr.manager.LogConfig(r.PostForm.Get("name"))
+
+	form := api.CreateLogForm{
+		Name:        "",
+		LogConfig:   (*api.LogConfig)(&config),
+		LogMetadata: api.LogMetadata{},
+	}
+
 	err = lr.schemaDecoder.Decode(&form, r.PostForm)
 	if err != nil {
 		er := api.NewParamsError(err)
@@ -47,7 +51,26 @@ func (lr *LogsRouter) CreateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ml, err := lr.manager.CreateLog(form.Name, config)
+	if !allowed(r, form.Name, auth.PermissionAdmin) {
+		api.WriteError(w, http.StatusForbidden, api.ErrForbidden)
+		logger.Debug(auth.ErrForbidden)
+		return
+	}
+
+	if !validName(form.Name) {
+		api.WriteError(w, http.StatusBadRequest, api.ErrLogInvalidName)
+		logger.Debug(logman.ErrInvalidName)
+		return
+	}
+
+	metadata, err := parseMetadata(form.LogMetadata)
+	if err != nil {
+		api.WriteError(w, http.StatusBadRequest, api.ErrInvalidLabel)
+		logger.Debug(err)
+		return
+	}
+
+	ml, err := lr.manager.CreateLog(form.Name, config, metadata)
 	if err == log.ErrExist {
 		api.WriteError(w, http.StatusBadRequest, api.ErrLogExist)
 		logger.Debug(err)
@@ -60,6 +83,30 @@ func (lr *LogsRouter) CreateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err == logman.ErrInvalidLabel {
+		api.WriteError(w, http.StatusBadRequest, api.ErrInvalidLabel)
+		logger.Debug(err)
+		return
+	}
+
+	if err == logman.ErrNameConflict {
+		api.WriteError(w, http.StatusBadRequest, api.ErrNameConflict)
+		logger.Debug(err)
+		return
+	}
+
+	if err == logman.ErrNamespaceQuota {
+		api.WriteError(w, http.StatusBadRequest, api.ErrNamespaceQuota)
+		logger.Debug(err)
+		return
+	}
+
+	if err == logman.ErrUnavailable {
+		api.WriteError(w, http.StatusBadRequest, api.ErrLogNotAvailable)
+		logger.Debug(err)
+		return
+	}
+
 	if err != nil {
 		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
 		logger.Debug(err)
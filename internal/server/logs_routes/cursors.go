@@ -0,0 +1,66 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs_routes
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/dataptive/styx/internal/logman"
+	"github.com/dataptive/styx/pkg/api"
+	"github.com/dataptive/styx/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+func (lr *LogsRouter) CursorsHandler(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	name := vars["name"]
+
+	managedLog, err := lr.manager.GetLog(name)
+	if err == logman.ErrNotExist {
+		api.WriteError(w, http.StatusNotFound, api.ErrLogNotFound)
+		logger.Debug(err)
+		return
+	}
+
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
+		logger.Debug(err)
+		return
+	}
+
+	positions, err := managedLog.Cursors()
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
+		logger.Debug(err)
+		return
+	}
+
+	response := api.ListCursorsResponse{}
+	for cursor, position := range positions {
+		response = append(response, api.CursorInfo{
+			Name:     cursor,
+			Position: position,
+		})
+	}
+
+	sort.Slice(response, func(i, j int) bool {
+		return response[i].Name < response[j].Name
+	})
+
+	api.WriteResponse(w, http.StatusOK, response)
+}
@@ -42,6 +42,12 @@ func (lr *LogsRouter) DeleteHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err == logman.ErrUnavailable {
+		api.WriteError(w, http.StatusBadRequest, api.ErrLogNotAvailable)
+		logger.Debug(err)
+		return
+	}
+
 	if err != nil {
 		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
 		logger.Debug(err)
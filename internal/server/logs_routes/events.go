@@ -0,0 +1,223 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs_routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dataptive/styx/internal/logman"
+	"github.com/dataptive/styx/internal/server/auth"
+	"github.com/dataptive/styx/pkg/api"
+	"github.com/dataptive/styx/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// Number of seconds long polls wait for events by default.
+	defaultEventsTimeout = 30
+)
+
+// RegisterEventRoutes registers the routes watching the lifecycle events of
+// logs on router.
+func (lr *LogsRouter) RegisterEventRoutes(router *mux.Router) {
+
+	router.HandleFunc("", lr.authenticate(lr.EventsSSEHandler)).
+		Methods(http.MethodGet).
+		MatcherFunc(lr.ReadSSEMatcher)
+
+	router.HandleFunc("", lr.authenticate(lr.EventsHandler)).
+		Methods(http.MethodGet)
+}
+
+// EventsHandler long polls the events following the one given as since, or
+// those to come when omitted. The ID of the last event considered is sent in
+// a header, to be given as since on the next poll.
+func (lr *LogsRouter) EventsHandler(w http.ResponseWriter, r *http.Request) {
+
+	params := api.ListEventsParams{
+		Since:   -1,
+		Timeout: defaultEventsTimeout,
+	}
+	query := r.URL.Query()
+
+	err := lr.schemaDecoder.Decode(&params, query)
+	if err != nil {
+		er := api.NewParamsError(err)
+		api.WriteError(w, http.StatusBadRequest, er)
+		logger.Debug(err)
+		return
+	}
+
+	since := params.Since
+	if since < 0 {
+		since = lr.manager.LastEventID()
+	}
+
+	deadline := time.Now().Add(time.Duration(params.Timeout) * time.Second)
+
+	entries := api.ListEventsResponse{}
+
+	for {
+		events, err := lr.manager.Events(since, time.Until(deadline), r.Context().Done())
+		if err == logman.ErrClosed {
+			break
+		}
+
+		if err != nil {
+			api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
+			logger.Debug(err)
+			return
+		}
+
+		for _, event := range events {
+
+			since = event.ID
+
+			// Only send events of logs the client can read,
+			// resets being about no log in particular.
+			if event.Type != logman.EventReset && !allowed(r, event.Name, auth.PermissionRead) {
+				continue
+			}
+
+			entries = append(entries, api.Event(event))
+		}
+
+		// Keep waiting while events were all filtered out.
+		if len(entries) > 0 || len(events) == 0 {
+			break
+		}
+	}
+
+	w.Header().Set(api.EventIDHeaderName, strconv.FormatInt(since, 10))
+
+	api.WriteResponse(w, http.StatusOK, entries)
+}
+
+// EventsSSEHandler streams events as server-sent events, from those following
+// the one given as since, or those to come when omitted.
+func (lr *LogsRouter) EventsSSEHandler(w http.ResponseWriter, r *http.Request) {
+
+	config := lr.currentConfig()
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
+		logger.Debug(errStreamingUnsupported)
+		return
+	}
+
+	params := api.ListEventsParams{
+		Since: -1,
+	}
+	query := r.URL.Query()
+
+	err := lr.schemaDecoder.Decode(&params, query)
+	if err != nil {
+		er := api.NewParamsError(err)
+		api.WriteError(w, http.StatusBadRequest, er)
+		logger.Debug(err)
+		return
+	}
+
+	// On reconnection, resume right after the last event received
+	// by the client, regardless of the initial query params.
+	lastEventID := r.Header.Get(api.LastEventIDHeaderName)
+	if lastEventID != "" {
+
+		params.Since, err = strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil {
+			er := api.NewParamsError(err)
+			api.WriteError(w, http.StatusBadRequest, er)
+			logger.Debug(err)
+			return
+		}
+	}
+
+	since := params.Since
+	if since < 0 {
+		since = lr.manager.LastEventID()
+	}
+
+	w.Header().Set("Content-Type", api.EventStreamMediaType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	heartbeatInterval := time.Duration(config.TCPTimeout) * time.Second / 2
+	sw := newSSEWriter(w, flusher, config.HTTPWriteBufferSize, heartbeatInterval)
+
+	sw.HandleError(func(err error) {
+		logger.Debug(err)
+	})
+
+	err = lr.streamEvents(sw, r, since, heartbeatInterval)
+	if err != nil {
+		logger.Debug(err)
+	}
+
+	err = sw.Close()
+	if err != nil {
+		logger.Debug(err)
+	}
+}
+
+// streamEvents writes the events following since until the client goes away
+// or the log manager is closed.
+func (lr *LogsRouter) streamEvents(sw *sseWriter, r *http.Request, since int64, timeout time.Duration) (err error) {
+
+	for {
+		events, err := lr.manager.Events(since, timeout, r.Context().Done())
+		if err == logman.ErrClosed {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if r.Context().Err() != nil {
+			return nil
+		}
+
+		for _, event := range events {
+
+			since = event.ID
+
+			// Only send events of logs the client can read,
+			// resets being about no log in particular.
+			if event.Type != logman.EventReset && !allowed(r, event.Name, auth.PermissionRead) {
+				continue
+			}
+
+			data, err := json.Marshal(api.Event(event))
+			if err != nil {
+				return err
+			}
+
+			err = sw.WriteEvent(event.ID, data)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = sw.Flush()
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -16,12 +16,26 @@ package logs_routes
 
 import (
 	"net/http"
+	"strings"
 
+	"github.com/dataptive/styx/internal/server/auth"
 	"github.com/dataptive/styx/pkg/api"
+	"github.com/dataptive/styx/pkg/logger"
 )
 
 func (lr *LogsRouter) ListHandler(w http.ResponseWriter, r *http.Request) {
 
+	params := api.ListLogsParams{}
+	query := r.URL.Query()
+
+	err := lr.schemaDecoder.Decode(&params, query)
+	if err != nil {
+		er := api.NewParamsError(err)
+		api.WriteError(w, http.StatusBadRequest, er)
+		logger.Debug(err)
+		return
+	}
+
 	entries := api.ListLogsResponse{}
 
 	managedLogs := lr.manager.ListLogs()
@@ -29,6 +43,22 @@ func (lr *LogsRouter) ListHandler(w http.ResponseWriter, r *http.Request) {
 	for _, ml := range managedLogs {
 
 		logInfo := ml.Stat()
+
+		// Only list logs nested in the namespace when given.
+		if params.Namespace != "" && !strings.HasPrefix(logInfo.Name, params.Namespace+"/") {
+			continue
+		}
+
+		// Only list logs holding all the given labels.
+		if !matchLabels(logInfo.Labels, params.Labels) {
+			continue
+		}
+
+		// Only list logs the client can read.
+		if !allowed(r, logInfo.Name, auth.PermissionRead) {
+			continue
+		}
+
 		entries = append(entries, api.LogInfo(logInfo))
 	}
 
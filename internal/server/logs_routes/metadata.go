@@ -0,0 +1,131 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs_routes
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/dataptive/styx/internal/logman"
+	"github.com/dataptive/styx/pkg/api"
+	"github.com/dataptive/styx/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+func (lr *LogsRouter) SetMetadataHandler(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	name := vars["name"]
+
+	form := api.SetLogMetadataForm{}
+
+	err := r.ParseForm()
+	if err != nil {
+		api.WriteError(w, http.StatusBadRequest, api.ErrUnknownError)
+		logger.Debug(err)
+		return
+	}
+
+	err = lr.schemaDecoder.Decode(&form, r.PostForm)
+	if err != nil {
+		er := api.NewParamsError(err)
+		api.WriteError(w, http.StatusBadRequest, er)
+		logger.Debug(err)
+		return
+	}
+
+	metadata, err := parseMetadata(api.LogMetadata(form))
+	if err != nil {
+		api.WriteError(w, http.StatusBadRequest, api.ErrInvalidLabel)
+		logger.Debug(err)
+		return
+	}
+
+	ml, err := lr.manager.GetLog(name)
+	if err == logman.ErrNotExist {
+		api.WriteError(w, http.StatusNotFound, api.ErrLogNotFound)
+		logger.Debug(err)
+		return
+	}
+
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
+		logger.Debug(err)
+		return
+	}
+
+	err = ml.SetMetadata(metadata)
+	if err == logman.ErrInvalidLabel {
+		api.WriteError(w, http.StatusBadRequest, api.ErrInvalidLabel)
+		logger.Debug(err)
+		return
+	}
+
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
+		logger.Debug(err)
+		return
+	}
+
+	logInfo := ml.Stat()
+
+	api.WriteResponse(w, http.StatusOK, api.SetLogMetadataResponse(logInfo))
+}
+
+// parseMetadata converts form metadata, where labels are given as
+// "name=value" pairs.
+func parseMetadata(form api.LogMetadata) (metadata logman.Metadata, err error) {
+
+	metadata = logman.Metadata{
+		Owner:       form.Owner,
+		Description: form.Description,
+		SchemaURL:   form.SchemaURL,
+		Labels:      make(map[string]string, len(form.Labels)),
+	}
+
+	for _, label := range form.Labels {
+
+		parts := strings.SplitN(label, "=", 2)
+		if len(parts) != 2 {
+			return metadata, logman.ErrInvalidLabel
+		}
+
+		metadata.Labels[parts[0]] = parts[1]
+	}
+
+	return metadata, nil
+}
+
+// matchLabels reports whether labels match all selectors, either
+// "name=value" pairs or label names that must be set whatever their value.
+func matchLabels(labels map[string]string, selectors []string) (match bool) {
+
+	for _, selector := range selectors {
+
+		parts := strings.SplitN(selector, "=", 2)
+
+		value, exists := labels[parts[0]]
+		if !exists {
+			return false
+		}
+
+		if len(parts) == 2 && parts[1] != value {
+			return false
+		}
+	}
+
+	return true
+}
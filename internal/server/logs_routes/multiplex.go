@@ -0,0 +1,258 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs_routes
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/dataptive/styx/internal/server/auth"
+	"github.com/dataptive/styx/pkg/api"
+	"github.com/dataptive/styx/pkg/api/tcp"
+	"github.com/dataptive/styx/pkg/filter"
+	"github.com/dataptive/styx/pkg/log"
+	"github.com/dataptive/styx/pkg/recio"
+)
+
+var (
+	errDuplicateSubscription = errors.New("server: duplicate subscription id")
+)
+
+// subscriptionSink abstracts the transport on which records of multiplexed
+// subscriptions are delivered. Implementations must be safe for concurrent
+// use since every subscription writes from its own goroutine.
+type subscriptionSink interface {
+	WriteRecord(id uint32, position int64, offset int64, r log.Record) (err error)
+	WriteEnd(id uint32, reason int, er error) (err error)
+	Flush() (err error)
+}
+
+type subscription struct {
+	id           uint32
+	reader       *log.LogReader
+	filter       filter.Filter
+	count        int64
+	unsubscribed bool
+}
+
+// multiplexSession tracks the subscriptions of a single multiplexed
+// connection, each following its own log from its own goroutine.
+type multiplexSession struct {
+	router            *LogsRouter
+	identity          *auth.Identity
+	client            string
+	sink              subscriptionSink
+	subscriptions     map[uint32]*subscription
+	subscriptionsLock sync.Mutex
+	closed            bool
+	wg                sync.WaitGroup
+}
+
+func newMultiplexSession(lr *LogsRouter, identity *auth.Identity, client string, sink subscriptionSink) (ms *multiplexSession) {
+
+	ms = &multiplexSession{
+		router:        lr,
+		identity:      identity,
+		client:        client,
+		sink:          sink,
+		subscriptions: make(map[uint32]*subscription),
+		closed:        false,
+	}
+
+	return ms
+}
+
+func (ms *multiplexSession) Subscribe(id uint32, name string, params api.ConsumeParams) (err error) {
+
+	ms.subscriptionsLock.Lock()
+	defer ms.subscriptionsLock.Unlock()
+
+	if ms.closed {
+		return tcp.ErrClosed
+	}
+
+	_, exists := ms.subscriptions[id]
+	if exists {
+		return errDuplicateSubscription
+	}
+
+	logReader, recordFilter, err := ms.open(name, params)
+	if err != nil {
+		// Failing to open a subscription does not affect
+		// other subscriptions of the session.
+		return ms.sink.WriteEnd(id, tcp.EndReasonError, err)
+	}
+
+	sub := &subscription{
+		id:     id,
+		reader: logReader,
+		filter: recordFilter,
+		count:  params.Count,
+	}
+
+	ms.subscriptions[id] = sub
+
+	ms.wg.Add(1)
+	go ms.follow(sub)
+
+	return nil
+}
+
+func (ms *multiplexSession) Unsubscribe(id uint32) {
+
+	ms.subscriptionsLock.Lock()
+	defer ms.subscriptionsLock.Unlock()
+
+	sub, exists := ms.subscriptions[id]
+	if !exists {
+		return
+	}
+
+	sub.unsubscribed = true
+
+	// Close reader to unlock follow.
+	sub.reader.Close()
+}
+
+func (ms *multiplexSession) Close() {
+
+	ms.subscriptionsLock.Lock()
+
+	ms.closed = true
+
+	for _, sub := range ms.subscriptions {
+		sub.unsubscribed = true
+		sub.reader.Close()
+	}
+
+	ms.subscriptionsLock.Unlock()
+
+	ms.wg.Wait()
+}
+
+func (ms *multiplexSession) open(name string, params api.ConsumeParams) (lr *log.LogReader, f filter.Filter, err error) {
+
+	err = params.Validate()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	f, err = filter.Parse(params.Filter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !ms.identity.Allowed(ms.router.manager.ResolveName(name), auth.PermissionRead) {
+		return nil, nil, auth.ErrForbidden
+	}
+
+	managedLog, err := ms.router.manager.GetLog(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lr, err = managedLog.NewReader(ms.client, params.Follow, recio.ModeManual)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = lr.Seek(params.Position, params.Whence)
+	if err != nil {
+		lr.Close()
+		return nil, nil, err
+	}
+
+	return lr, f, nil
+}
+
+func (ms *multiplexSession) follow(sub *subscription) {
+
+	defer ms.wg.Done()
+
+	err := readSubscription(ms.sink, sub)
+
+	ms.subscriptionsLock.Lock()
+	delete(ms.subscriptions, sub.id)
+	unsubscribed := sub.unsubscribed
+	ms.subscriptionsLock.Unlock()
+
+	sub.reader.Close()
+
+	reason := tcp.EndReasonComplete
+
+	if unsubscribed {
+		reason = tcp.EndReasonUnsubscribed
+		err = nil
+	}
+
+	if err != nil {
+		reason = tcp.EndReasonError
+	}
+
+	ms.sink.WriteEnd(sub.id, reason, err)
+	ms.sink.Flush()
+}
+
+func readSubscription(sink subscriptionSink, sub *subscription) (err error) {
+
+	count := int64(0)
+	record := log.Record{}
+
+	for {
+		if count == sub.count {
+			break
+		}
+
+		n, err := sub.reader.Read(&record)
+		if err == io.EOF {
+			break
+		}
+
+		if err == recio.ErrMustFill {
+
+			err = sink.Flush()
+			if err != nil {
+				return err
+			}
+
+			err = sub.reader.Fill()
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if !filter.Match(sub.filter, record) {
+			continue
+		}
+
+		position, offset := sub.reader.Tell()
+
+		err = sink.WriteRecord(sub.id, position-1, offset-int64(n), record)
+		if err != nil {
+			return err
+		}
+
+		count++
+	}
+
+	return nil
+}
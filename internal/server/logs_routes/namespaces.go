@@ -0,0 +1,185 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs_routes
+
+import (
+	"net/http"
+
+	"github.com/dataptive/styx/internal/logman"
+	"github.com/dataptive/styx/internal/server/auth"
+	"github.com/dataptive/styx/pkg/api"
+	"github.com/dataptive/styx/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterNamespaceRoutes registers the routes managing namespaces on
+// router.
+func (lr *LogsRouter) RegisterNamespaceRoutes(router *mux.Router) {
+
+	router.HandleFunc("", lr.authenticate(lr.ListNamespacesHandler)).
+		Methods(http.MethodGet)
+
+	router.HandleFunc(logRoute, lr.authorize(auth.PermissionRead, lr.GetNamespaceHandler)).
+		Methods(http.MethodGet)
+
+	router.HandleFunc(logRoute, lr.authorize(auth.PermissionAdmin, lr.SetNamespaceHandler)).
+		Methods(http.MethodPut)
+
+	router.HandleFunc(logRoute, lr.authorize(auth.PermissionAdmin, lr.DeleteNamespaceHandler)).
+		Methods(http.MethodDelete)
+}
+
+func (lr *LogsRouter) ListNamespacesHandler(w http.ResponseWriter, r *http.Request) {
+
+	entries := api.ListNamespacesResponse{}
+
+	for _, info := range lr.manager.ListNamespaces() {
+
+		// Only list namespaces the client can read.
+		if !allowed(r, info.Name, auth.PermissionRead) {
+			continue
+		}
+
+		entries = append(entries, namespaceInfo(info))
+	}
+
+	api.WriteResponse(w, http.StatusOK, entries)
+}
+
+func (lr *LogsRouter) GetNamespaceHandler(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	name := vars["name"]
+
+	info, err := lr.manager.GetNamespace(name)
+	if err == logman.ErrNamespaceNotExist {
+		api.WriteError(w, http.StatusNotFound, api.ErrNamespaceNotFound)
+		logger.Debug(err)
+		return
+	}
+
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
+		logger.Debug(err)
+		return
+	}
+
+	api.WriteResponse(w, http.StatusOK, api.GetNamespaceResponse(namespaceInfo(info)))
+}
+
+func (lr *LogsRouter) SetNamespaceHandler(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	name := vars["name"]
+
+	config := logman.NamespaceConfig{}
+
+	form := api.SetNamespaceForm{
+		LogConfig: (*api.LogConfig)(&config.LogConfig),
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		api.WriteError(w, http.StatusBadRequest, api.ErrUnknownError)
+		logger.Debug(err)
+		return
+	}
+
+	err = lr.schemaDecoder.Decode(&form, r.PostForm)
+	if err != nil {
+		er := api.NewParamsError(err)
+		api.WriteError(w, http.StatusBadRequest, er)
+		logger.Debug(err)
+		return
+	}
+
+	config.MaxLogs = form.MaxLogs
+	config.MaxUsage = form.MaxUsage
+
+	info, err := lr.manager.SetNamespace(name, config)
+	if err == logman.ErrInvalidName {
+		api.WriteError(w, http.StatusBadRequest, api.ErrLogInvalidName)
+		logger.Debug(err)
+		return
+	}
+
+	if err == logman.ErrNameConflict {
+		api.WriteError(w, http.StatusBadRequest, api.ErrNameConflict)
+		logger.Debug(err)
+		return
+	}
+
+	if err == logman.ErrUnavailable {
+		api.WriteError(w, http.StatusBadRequest, api.ErrLogNotAvailable)
+		logger.Debug(err)
+		return
+	}
+
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
+		logger.Debug(err)
+		return
+	}
+
+	api.WriteResponse(w, http.StatusOK, api.SetNamespaceResponse(namespaceInfo(info)))
+}
+
+func (lr *LogsRouter) DeleteNamespaceHandler(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	name := vars["name"]
+
+	err := lr.manager.DeleteNamespace(name)
+	if err == logman.ErrNamespaceNotExist {
+		api.WriteError(w, http.StatusNotFound, api.ErrNamespaceNotFound)
+		logger.Debug(err)
+		return
+	}
+
+	if err == logman.ErrInvalidName {
+		api.WriteError(w, http.StatusBadRequest, api.ErrLogInvalidName)
+		logger.Debug(err)
+		return
+	}
+
+	if err == logman.ErrUnavailable {
+		api.WriteError(w, http.StatusBadRequest, api.ErrLogNotAvailable)
+		logger.Debug(err)
+		return
+	}
+
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
+		logger.Debug(err)
+		return
+	}
+
+	api.WriteResponse(w, http.StatusOK, nil)
+}
+
+func namespaceInfo(info logman.NamespaceInfo) (ni api.NamespaceInfo) {
+
+	ni = api.NamespaceInfo{
+		Name:      info.Name,
+		LogCount:  info.LogCount,
+		Usage:     info.Usage,
+		LogConfig: api.LogConfig(info.Config.LogConfig),
+		MaxLogs:   info.Config.MaxLogs,
+		MaxUsage:  info.Config.MaxUsage,
+	}
+
+	return ni
+}
@@ -0,0 +1,150 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs_routes
+
+import (
+	"net/http"
+
+	"github.com/dataptive/styx/internal/logman"
+	"github.com/dataptive/styx/internal/server/auth"
+	"github.com/dataptive/styx/pkg/api"
+	"github.com/dataptive/styx/pkg/api/tcp"
+	"github.com/dataptive/styx/pkg/compress"
+	"github.com/dataptive/styx/pkg/filter"
+
+	"github.com/gorilla/mux"
+)
+
+var (
+	// Features offered on each kind of styx protocol connection, when
+	// supported by the server.
+	consumeFeatures = []string{
+		tcp.FeatureCompression,
+		tcp.FeatureBatch,
+		tcp.FeatureMetadata,
+		tcp.FeatureFlowControl,
+		tcp.FeatureProgress,
+		tcp.FeatureControl,
+	}
+
+	produceFeatures = []string{
+		tcp.FeatureCompression,
+		tcp.FeatureBatch,
+	}
+
+	multiplexFeatures = []string{
+		tcp.FeatureCompression,
+	}
+)
+
+// StyxUpgradeMatcher matches styx protocol upgrade requests offering at
+// least one supported protocol version.
+func (lr *LogsRouter) StyxUpgradeMatcher(r *http.Request, rm *mux.RouteMatch) (match bool) {
+
+	_, match = tcp.NegotiateVersion(r.Header.Get("Upgrade"))
+
+	return match
+}
+
+// upgradeCapabilities negotiates the capabilities of a styx protocol upgrade
+// request among the offered features, and sets the response headers
+// announcing them.
+func (lr *LogsRouter) upgradeCapabilities(w http.ResponseWriter, r *http.Request, offered []string) (caps tcp.Capabilities) {
+
+	version, _ := tcp.NegotiateVersion(r.Header.Get("Upgrade"))
+	features := tcp.ParseFeatures(r.Header.Get(api.FeaturesHeaderName))
+
+	caps = lr.negotiateCapabilities(version, features, r.Header.Get(api.CompressionHeaderName), offered)
+
+	w.Header().Add(api.CompressionHeaderName, caps.Compression)
+
+	if caps.Version >= tcp.ProtocolVersion1 {
+		w.Header().Add(api.FeaturesHeaderName, tcp.FormatFeatures(caps.Features))
+	}
+
+	return caps
+}
+
+// negotiateCapabilities returns the capabilities of a connection from the
+// protocol version, features and compression codecs requested by the client,
+// among the offered features. Version 0 connections have no features but may
+// still be compressed.
+func (lr *LogsRouter) negotiateCapabilities(version int, features []string, compression string, offered []string) (caps tcp.Capabilities) {
+
+	caps = tcp.Capabilities{
+		Version:     version,
+		Features:    []string{},
+		Compression: compress.Identity,
+	}
+
+	if version >= tcp.ProtocolVersion1 {
+		caps.Features = tcp.NegotiateFeatures(features, lr.supportedFeatures(offered))
+	}
+
+	if version == tcp.ProtocolVersion0 || caps.Has(tcp.FeatureCompression) {
+		caps.Compression = lr.connCodec(compression)
+	}
+
+	return caps
+}
+
+// supportedFeatures returns the offered features the server supports,
+// compression being left out when no codec is enabled.
+func (lr *LogsRouter) supportedFeatures(offered []string) (features []string) {
+
+	features = []string{}
+
+	for _, feature := range offered {
+
+		if feature == tcp.FeatureCompression && len(lr.currentConfig().CompressionCodecs) == 0 {
+			continue
+		}
+
+		features = append(features, feature)
+	}
+
+	return features
+}
+
+// protocolError translates errors raised by the server outside of the log
+// package into their styx protocol counterpart, so that clients receive a
+// meaningful error code instead of an unknown error.
+func protocolError(err error) (er error) {
+
+	switch err {
+	case logman.ErrNotExist:
+		return tcp.ErrLogNotFound
+
+	case logman.ErrUnavailable:
+		return tcp.ErrLogUnavailable
+
+	case logman.ErrClosed:
+		return tcp.ErrLogClosed
+
+	case logman.ErrInsufficientStorage, logman.ErrNamespaceQuota:
+		return tcp.ErrQuotaExceeded
+
+	case auth.ErrUnauthenticated:
+		return tcp.ErrUnauthorized
+
+	case auth.ErrForbidden:
+		return tcp.ErrForbidden
+
+	case api.ErrInvalidWhence, filter.ErrInvalidFilter, filter.ErrUnknownKind, filter.ErrInvalidPredicate:
+		return tcp.ErrInvalidParams
+	}
+
+	return err
+}
@@ -21,6 +21,7 @@ import (
 
 	"github.com/dataptive/styx/internal/logman"
 	"github.com/dataptive/styx/pkg/api"
+	"github.com/dataptive/styx/pkg/filter"
 	"github.com/dataptive/styx/pkg/log"
 	"github.com/dataptive/styx/pkg/logger"
 	"github.com/dataptive/styx/pkg/recio"
@@ -57,6 +58,14 @@ func (lr *LogsRouter) ReadHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordFilter, err := filter.Parse(params.Filter)
+	if err != nil {
+		er := api.NewParamsError(err)
+		api.WriteError(w, http.StatusBadRequest, er)
+		logger.Debug(err)
+		return
+	}
+
 	managedLog, err := lr.manager.GetLog(name)
 	if err == logman.ErrNotExist {
 		api.WriteError(w, http.StatusNotFound, api.ErrLogNotFound)
@@ -70,7 +79,7 @@ func (lr *LogsRouter) ReadHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	logReader, err := managedLog.NewReader(false, recio.ModeAuto)
+	logReader, err := managedLog.NewReader(clientID(r), false, recio.ModeAuto)
 	if err == logman.ErrUnavailable {
 		api.WriteError(w, http.StatusBadRequest, api.ErrLogNotAvailable)
 		logger.Debug(err)
@@ -84,6 +93,13 @@ func (lr *LogsRouter) ReadHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	err = logReader.Seek(params.Position, params.Whence)
+	if err == log.ErrOutOfRange {
+		api.WriteError(w, http.StatusBadRequest, api.ErrOutOfRange)
+		logger.Debug(err)
+		logReader.Close()
+		return
+	}
+
 	if err != nil {
 		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
 		logger.Debug(err)
@@ -93,9 +109,23 @@ func (lr *LogsRouter) ReadHandler(w http.ResponseWriter, r *http.Request) {
 
 	record := log.Record{}
 
-	_, err = logReader.Read(&record)
+	// Skip records until one matches the filter, if any.
+	for {
+		_, err = logReader.Read(&record)
+		if err != nil {
+			break
+		}
+
+		if filter.Match(recordFilter, record) {
+			break
+		}
+	}
+
 	if err == io.EOF {
+		nextPosition, _ := logReader.Tell()
+		w.Header().Set(api.NextPositionHeaderName, strconv.FormatInt(nextPosition, 10))
 		w.WriteHeader(http.StatusOK)
+		logReader.Close()
 		return
 	}
 
@@ -106,6 +136,8 @@ func (lr *LogsRouter) ReadHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	nextPosition, _ := logReader.Tell()
+
 	err = logReader.Close()
 	if err != nil {
 		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
@@ -114,6 +146,8 @@ func (lr *LogsRouter) ReadHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Length", strconv.Itoa(len(record)))
+	w.Header().Set(api.PositionHeaderName, strconv.FormatInt(nextPosition-1, 10))
+	w.Header().Set(api.NextPositionHeaderName, strconv.FormatInt(nextPosition, 10))
 	w.WriteHeader(http.StatusOK)
 
 	_, err = w.Write(record)
@@ -22,6 +22,7 @@ import (
 
 	"github.com/dataptive/styx/internal/logman"
 	"github.com/dataptive/styx/pkg/api"
+	"github.com/dataptive/styx/pkg/filter"
 	"github.com/dataptive/styx/pkg/log"
 	"github.com/dataptive/styx/pkg/logger"
 	"github.com/dataptive/styx/pkg/recio"
@@ -58,6 +59,14 @@ func (lr *LogsRouter) ReadBatchHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordFilter, err := filter.Parse(params.Filter)
+	if err != nil {
+		er := api.NewParamsError(err)
+		api.WriteError(w, http.StatusBadRequest, er)
+		logger.Debug(err)
+		return
+	}
+
 	timeout := -1
 
 	if params.Follow {
@@ -87,9 +96,7 @@ func (lr *LogsRouter) ReadBatchHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	bufferedWriter := recio.NewBufferedWriter(w, lr.config.HTTPWriteBufferSize, recio.ModeAuto)
-
-	logReader, err := managedLog.NewReader(params.Follow, recio.ModeManual)
+	logReader, err := managedLog.NewReader(clientID(r), params.Follow, recio.ModeManual)
 	if err == logman.ErrUnavailable {
 		api.WriteError(w, http.StatusBadRequest, api.ErrLogNotAvailable)
 		logger.Debug(err)
@@ -103,6 +110,13 @@ func (lr *LogsRouter) ReadBatchHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	err = logReader.Seek(params.Position, params.Whence)
+	if err == log.ErrOutOfRange {
+		api.WriteError(w, http.StatusBadRequest, api.ErrOutOfRange)
+		logger.Debug(err)
+		logReader.Close()
+		return
+	}
+
 	if err != nil {
 		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
 		logger.Debug(err)
@@ -110,23 +124,46 @@ func (lr *LogsRouter) ReadBatchHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	bodyWriter, err := compressResponse(w, lr.responseCodec(r))
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
+		logger.Debug(err)
+		logReader.Close()
+		return
+	}
+
+	bufferedWriter := recio.NewBufferedWriter(bodyWriter, lr.currentConfig().HTTPWriteBufferSize, recio.ModeAuto)
+
 	w.Header().Set("Content-Type", api.RecordBinaryMediaType)
+	w.Header().Set("Trailer", api.NextPositionHeaderName)
 	w.WriteHeader(http.StatusOK)
 
-	err = readBatch(bufferedWriter, logReader, params.Count, params.Follow, timeout)
+	err = readBatch(bufferedWriter, logReader, recordFilter, params.Count, params.Follow, timeout)
 	if err != nil {
 		logger.Debug(err)
 		logReader.Close()
 		return
 	}
 
+	err = bodyWriter.Close()
+	if err != nil {
+		logger.Debug(err)
+		logReader.Close()
+		return
+	}
+
+	// Report the position following the last scanned record, which
+	// may differ from the last delivered one when filtering.
+	nextPosition, _ := logReader.Tell()
+	w.Header().Set(api.NextPositionHeaderName, strconv.FormatInt(nextPosition, 10))
+
 	err = logReader.Close()
 	if err != nil {
 		logger.Debug(err)
 	}
 }
 
-func readBatch(bw *recio.BufferedWriter, lr *log.LogReader, limit int64, follow bool, timeout int) (err error) {
+func readBatch(bw *recio.BufferedWriter, lr *log.LogReader, f filter.Filter, limit int64, follow bool, timeout int) (err error) {
 
 	count := int64(0)
 	record := log.Record{}
@@ -176,6 +213,10 @@ func readBatch(bw *recio.BufferedWriter, lr *log.LogReader, limit int64, follow
 			return err
 		}
 
+		if !filter.Match(f, record) {
+			continue
+		}
+
 		_, err = bw.Write(&record)
 		if err != nil {
 			return err
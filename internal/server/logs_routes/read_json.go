@@ -0,0 +1,377 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs_routes
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dataptive/styx/internal/logman"
+	"github.com/dataptive/styx/pkg/api"
+	"github.com/dataptive/styx/pkg/filter"
+	"github.com/dataptive/styx/pkg/log"
+	"github.com/dataptive/styx/pkg/logger"
+	"github.com/dataptive/styx/pkg/recio"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	payloadJSON   = "json"
+	payloadBase64 = "base64"
+)
+
+var (
+	errInvalidPayloadEncoding = errors.New("server: invalid payload encoding")
+)
+
+func (lr *LogsRouter) ReadJSONMatcher(r *http.Request, rm *mux.RouteMatch) (match bool) {
+
+	accept := r.Header.Get("Accept")
+	mediaType, _, _ := mime.ParseMediaType(accept)
+
+	match = mediaType == api.RecordNDJSONMediaType || mediaType == api.RecordJSONMediaType
+
+	return match
+}
+
+func (lr *LogsRouter) ReadJSONHandler(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	name := vars["name"]
+
+	accept := r.Header.Get("Accept")
+	mediaType, typeParams, err := mime.ParseMediaType(accept)
+	if err != nil {
+		er := api.NewParamsError(err)
+		api.WriteError(w, http.StatusBadRequest, er)
+		logger.Debug(err)
+		return
+	}
+
+	if typeParams["payload"] == "" {
+		typeParams["payload"] = payloadJSON
+	}
+
+	payloadEncoding := typeParams["payload"]
+	if payloadEncoding != payloadJSON && payloadEncoding != payloadBase64 {
+		er := api.NewParamsError(errInvalidPayloadEncoding)
+		api.WriteError(w, http.StatusBadRequest, er)
+		logger.Debug(errInvalidPayloadEncoding)
+		return
+	}
+
+	params := api.ConsumeParams{
+		Whence:   log.SeekOrigin,
+		Position: 0,
+		Count:    -1,
+		Follow:   false,
+	}
+	query := r.URL.Query()
+
+	err = lr.schemaDecoder.Decode(&params, query)
+	if err != nil {
+		er := api.NewParamsError(err)
+		api.WriteError(w, http.StatusBadRequest, er)
+		logger.Debug(err)
+		return
+	}
+
+	err = params.Validate()
+	if err != nil {
+		er := api.NewParamsError(err)
+		api.WriteError(w, http.StatusBadRequest, er)
+		logger.Debug(err)
+		return
+	}
+
+	recordFilter, err := filter.Parse(params.Filter)
+	if err != nil {
+		er := api.NewParamsError(err)
+		api.WriteError(w, http.StatusBadRequest, er)
+		logger.Debug(err)
+		return
+	}
+
+	timeout := -1
+
+	if params.Follow {
+
+		rawTimeout := r.Header.Get(api.TimeoutHeaderName)
+		if rawTimeout != "" {
+
+			timeout, err = strconv.Atoi(rawTimeout)
+			if err != nil {
+				api.WriteError(w, http.StatusBadRequest, api.ErrUnknownError)
+				logger.Debug(err)
+				return
+			}
+		}
+	}
+
+	managedLog, err := lr.manager.GetLog(name)
+	if err == logman.ErrNotExist {
+		api.WriteError(w, http.StatusNotFound, api.ErrLogNotFound)
+		logger.Debug(err)
+		return
+	}
+
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
+		logger.Debug(err)
+		return
+	}
+
+	logReader, err := managedLog.NewReader(clientID(r), params.Follow, recio.ModeManual)
+	if err == logman.ErrUnavailable {
+		api.WriteError(w, http.StatusBadRequest, api.ErrLogNotAvailable)
+		logger.Debug(err)
+		return
+	}
+
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
+		logger.Debug(err)
+		return
+	}
+
+	err = logReader.Seek(params.Position, params.Whence)
+	if err == log.ErrOutOfRange {
+		api.WriteError(w, http.StatusBadRequest, api.ErrOutOfRange)
+		logger.Debug(err)
+		logReader.Close()
+		return
+	}
+
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
+		logger.Debug(err)
+		logReader.Close()
+		return
+	}
+
+	bodyWriter, err := compressResponse(w, lr.responseCodec(r))
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
+		logger.Debug(err)
+		logReader.Close()
+		return
+	}
+
+	jw := newJSONRecordWriter(bodyWriter, lr.currentConfig().HTTPWriteBufferSize, mediaType == api.RecordJSONMediaType, payloadEncoding == payloadBase64)
+
+	w.Header().Set("Content-Type", mime.FormatMediaType(mediaType, typeParams))
+	w.Header().Set("Trailer", api.NextPositionHeaderName)
+	w.WriteHeader(http.StatusOK)
+
+	err = readJSON(jw, logReader, recordFilter, params.Count, params.Follow, timeout)
+	if err != nil {
+		logger.Debug(err)
+		logReader.Close()
+		return
+	}
+
+	err = bodyWriter.Close()
+	if err != nil {
+		logger.Debug(err)
+		logReader.Close()
+		return
+	}
+
+	nextPosition, _ := logReader.Tell()
+	w.Header().Set(api.NextPositionHeaderName, strconv.FormatInt(nextPosition, 10))
+
+	err = logReader.Close()
+	if err != nil {
+		logger.Debug(err)
+	}
+}
+
+func readJSON(jw *jsonRecordWriter, lr *log.LogReader, f filter.Filter, limit int64, follow bool, timeout int) (err error) {
+
+	count := int64(0)
+	record := log.Record{}
+	waitTimeout := time.Duration(timeout) * time.Second
+
+	for {
+		if count == limit {
+			break
+		}
+
+		n, err := lr.Read(&record)
+		if err == io.EOF {
+			break
+		}
+
+		if err == recio.ErrMustFill {
+
+			err = jw.Flush()
+			if err != nil {
+				return err
+			}
+
+			if follow {
+
+				if count > 0 {
+					break
+				}
+
+				if timeout != -1 {
+					start := time.Now()
+					deadline := start.Add(waitTimeout)
+
+					lr.SetWaitDeadline(deadline)
+				}
+			}
+
+			err = lr.Fill()
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if !filter.Match(f, record) {
+			continue
+		}
+
+		position, offset := lr.Tell()
+
+		err = jw.Write(position-1, offset-int64(n), record)
+		if err != nil {
+			return err
+		}
+
+		count++
+	}
+
+	err = jw.Close()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// jsonRecordWriter encodes records either as newline delimited JSON objects
+// or as a single JSON array of objects.
+type jsonRecordWriter struct {
+	writer      *bufio.Writer
+	encoder     *json.Encoder
+	array       bool
+	forceBase64 bool
+	written     int64
+	jsonRecord  api.JSONRecord
+}
+
+func newJSONRecordWriter(w io.Writer, bufferSize int, array bool, forceBase64 bool) (jw *jsonRecordWriter) {
+
+	writer := bufio.NewWriterSize(w, bufferSize)
+
+	// JSON payloads are compacted when encoded, but their strings are
+	// left as is rather than having HTML characters escaped.
+	encoder := json.NewEncoder(writer)
+	encoder.SetEscapeHTML(false)
+
+	jw = &jsonRecordWriter{
+		writer:      writer,
+		encoder:     encoder,
+		array:       array,
+		forceBase64: forceBase64,
+		written:     0,
+	}
+
+	return jw
+}
+
+func (jw *jsonRecordWriter) Write(position int64, offset int64, r log.Record) (err error) {
+
+	jw.jsonRecord.Position = position
+	jw.jsonRecord.Offset = offset
+	jw.jsonRecord.Payload = nil
+	jw.jsonRecord.PayloadBase64 = nil
+
+	// Records that are not valid JSON documents are always base64
+	// encoded so that binary payloads survive the round trip.
+	if !jw.forceBase64 && len(r) > 0 && json.Valid(r) {
+		jw.jsonRecord.Payload = json.RawMessage(r)
+	} else {
+		jw.jsonRecord.PayloadBase64 = []byte(r)
+	}
+
+	if jw.array {
+		delimiter := byte(',')
+		if jw.written == 0 {
+			delimiter = '['
+		}
+
+		err = jw.writer.WriteByte(delimiter)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = jw.encoder.Encode(&jw.jsonRecord)
+	if err != nil {
+		return err
+	}
+
+	jw.written++
+
+	return nil
+}
+
+func (jw *jsonRecordWriter) Flush() (err error) {
+
+	err = jw.writer.Flush()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (jw *jsonRecordWriter) Close() (err error) {
+
+	if jw.array {
+		closing := "]\n"
+		if jw.written == 0 {
+			closing = "[]\n"
+		}
+
+		_, err = jw.writer.WriteString(closing)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = jw.writer.Flush()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,249 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs_routes
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/dataptive/styx/internal/server/auth"
+	"github.com/dataptive/styx/pkg/api"
+	"github.com/dataptive/styx/pkg/api/tcp"
+	"github.com/dataptive/styx/pkg/log"
+	"github.com/dataptive/styx/pkg/logger"
+	"github.com/dataptive/styx/pkg/recio"
+)
+
+func (lr *LogsRouter) ReadMultiplexTCPHandler(w http.ResponseWriter, r *http.Request) {
+
+	config := lr.currentConfig()
+
+	var err error
+
+	remoteTimeout := config.TCPTimeout
+
+	rawTimeout := r.Header.Get(api.TimeoutHeaderName)
+	if rawTimeout != "" {
+
+		remoteTimeout, err = strconv.Atoi(rawTimeout)
+		if err != nil {
+			api.WriteError(w, http.StatusBadRequest, api.ErrUnknownError)
+			logger.Debug(err)
+			return
+		}
+	}
+
+	caps := lr.upgradeCapabilities(w, r, multiplexFeatures)
+
+	w.Header().Add(api.TimeoutHeaderName, strconv.Itoa(config.TCPTimeout))
+	conn, err := UpgradeTCP(w, caps.Version)
+	if err != nil {
+		logger.Debug(err)
+		return
+	}
+
+	lr.setConnBuffers(conn)
+
+	compressedConn, err := compressConn(conn, caps.Compression)
+	if err != nil {
+		logger.Debug(err)
+		conn.Close()
+		return
+	}
+
+	conn = compressedConn
+
+	tcpPeer := tcp.NewTCPPeer(conn, config.TCPWriteBufferSize, config.TCPReadBufferSize, config.TCPTimeout, remoteTimeout, recio.ModeAuto)
+
+	tcpPeer.HandleError(func(err error) {
+		logger.Debug(err)
+
+		// Close conn to unlock the control messages reader
+		// in case of an heartbeat error.
+		conn.Close()
+	})
+
+	sink := newTCPSubscriptionSink(tcpPeer)
+	session := newMultiplexSession(lr, auth.FromContext(r.Context()), clientID(r), sink)
+
+	err = readMultiplexTCP(session, tcpPeer)
+	if err != nil && err != io.EOF {
+		logger.Debug(err)
+
+		session.Close()
+
+		// Try to write error back to
+		// client in case conn is still open.
+		sink.WriteError(err)
+		sink.Flush()
+
+		tcpPeer.Close()
+		conn.Close()
+		return
+	}
+
+	session.Close()
+
+	err = tcpPeer.Close()
+	if err != nil {
+		logger.Debug(err)
+	}
+
+	err = conn.Close()
+	if err != nil {
+		logger.Debug(err)
+	}
+}
+
+func readMultiplexTCP(ms *multiplexSession, tp *tcp.TCPPeer) (err error) {
+
+	message := &tcp.Message{}
+
+	for {
+		_, err = tp.ReadMessage(message)
+		if err != nil {
+			return err
+		}
+
+		switch v := message.Payload.(type) {
+
+		case *tcp.SubscribeMessage:
+
+			params := api.ConsumeParams{
+				Whence:   log.Whence(v.Whence),
+				Position: v.Position,
+				Count:    v.Count,
+				Follow:   v.Follow,
+				Filter:   v.Filter,
+			}
+
+			err = ms.Subscribe(v.ID, v.Name, params)
+			if err != nil {
+				return err
+			}
+
+		case *tcp.UnsubscribeMessage:
+			ms.Unsubscribe(v.ID)
+
+		case *tcp.HeartbeatMessage:
+			// Ignore.
+			continue
+
+		default:
+			return tcp.ErrUnexpectedMessageType
+		}
+	}
+}
+
+// tcpSubscriptionSink serializes writes of concurrent subscriptions on a
+// single styx protocol connection.
+type tcpSubscriptionSink struct {
+	tcpPeer                *tcp.TCPPeer
+	message                *tcp.Message
+	logRecordMessage       *tcp.LogRecordMessage
+	subscriptionEndMessage *tcp.SubscriptionEndMessage
+	errorMessage           *tcp.ErrorMessage
+	lock                   sync.Mutex
+}
+
+func newTCPSubscriptionSink(tp *tcp.TCPPeer) (ts *tcpSubscriptionSink) {
+
+	ts = &tcpSubscriptionSink{
+		tcpPeer:                tp,
+		message:                &tcp.Message{},
+		logRecordMessage:       &tcp.LogRecordMessage{},
+		subscriptionEndMessage: &tcp.SubscriptionEndMessage{},
+		errorMessage:           &tcp.ErrorMessage{},
+	}
+
+	return ts
+}
+
+func (ts *tcpSubscriptionSink) WriteRecord(id uint32, position int64, offset int64, r log.Record) (err error) {
+
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+
+	ts.logRecordMessage.ID = id
+	ts.logRecordMessage.Position = position
+	ts.logRecordMessage.Record = r
+
+	ts.message.Type = tcp.TypeLogRecordMessage
+	ts.message.Payload = ts.logRecordMessage
+
+	_, err = ts.tcpPeer.WriteMessage(ts.message)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (ts *tcpSubscriptionSink) WriteEnd(id uint32, reason int, er error) (err error) {
+
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+
+	ts.subscriptionEndMessage.ID = id
+	ts.subscriptionEndMessage.Reason = reason
+	ts.subscriptionEndMessage.Code = 0
+
+	if er != nil {
+		ts.subscriptionEndMessage.Code = tcp.GetErrorCode(protocolError(er))
+	}
+
+	ts.message.Type = tcp.TypeSubscriptionEndMessage
+	ts.message.Payload = ts.subscriptionEndMessage
+
+	_, err = ts.tcpPeer.WriteMessage(ts.message)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (ts *tcpSubscriptionSink) WriteError(er error) (err error) {
+
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+
+	ts.errorMessage.Code = tcp.GetErrorCode(protocolError(er))
+
+	ts.message.Type = tcp.TypeErrorMessage
+	ts.message.Payload = ts.errorMessage
+
+	_, err = ts.tcpPeer.WriteMessage(ts.message)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (ts *tcpSubscriptionSink) Flush() (err error) {
+
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+
+	err = ts.tcpPeer.Flush()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,220 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs_routes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/dataptive/styx/internal/server/auth"
+	"github.com/dataptive/styx/pkg/api"
+	"github.com/dataptive/styx/pkg/api/tcp"
+	"github.com/dataptive/styx/pkg/log"
+	"github.com/dataptive/styx/pkg/logger"
+
+	"github.com/gorilla/websocket"
+)
+
+var (
+	errUnknownControlAction = errors.New("server: unknown control action")
+
+	endReasons = map[int]string{
+		tcp.EndReasonComplete:     "complete",
+		tcp.EndReasonUnsubscribed: "unsubscribed",
+		tcp.EndReasonError:        "error",
+	}
+)
+
+func (lr *LogsRouter) ReadMultiplexWSHandler(w http.ResponseWriter, r *http.Request) {
+
+	config := lr.currentConfig()
+
+	conn, err := UpgradeWebsocket(w, r, config.CORSAllowedOrigins, config.WSReadBufferSize, config.WSWriteBufferSize, config.WSCompression)
+	if err != nil {
+		logger.Debug(err)
+		return
+	}
+
+	sink := newWSSubscriptionSink(conn)
+	session := newMultiplexSession(lr, auth.FromContext(r.Context()), clientID(r), sink)
+
+	err = readMultiplexWS(session, conn)
+	if err != nil {
+		logger.Debug(err)
+
+		session.Close()
+
+		conn.Close()
+		return
+	}
+
+	session.Close()
+
+	err = sink.WriteClose()
+	if err != nil {
+		logger.Debug(err)
+
+		conn.Close()
+		return
+	}
+
+	err = conn.Close()
+	if err != nil {
+		logger.Debug(err)
+	}
+}
+
+func readMultiplexWS(ms *multiplexSession, ws *websocket.Conn) (err error) {
+
+	for {
+		control := api.MultiplexControl{
+			Whence:   log.SeekOrigin,
+			Position: 0,
+			Count:    -1,
+			Follow:   false,
+		}
+
+		messageType, p, err := ws.ReadMessage()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				break
+			}
+
+			return err
+		}
+
+		if messageType != websocket.TextMessage {
+			return tcp.ErrUnexpectedMessageType
+		}
+
+		err = json.Unmarshal(p, &control)
+		if err != nil {
+			return err
+		}
+
+		switch control.Action {
+
+		case api.ActionSubscribe:
+
+			params := api.ConsumeParams{
+				Whence:   control.Whence,
+				Position: control.Position,
+				Count:    control.Count,
+				Follow:   control.Follow,
+				Filter:   control.Filter,
+			}
+
+			err = ms.Subscribe(control.ID, control.Name, params)
+			if err != nil {
+				return err
+			}
+
+		case api.ActionUnsubscribe:
+			ms.Unsubscribe(control.ID)
+
+		default:
+			return errUnknownControlAction
+		}
+	}
+
+	return nil
+}
+
+// wsSubscriptionSink serializes writes of concurrent subscriptions on a
+// single websocket connection, which supports only one concurrent writer.
+type wsSubscriptionSink struct {
+	conn  *websocket.Conn
+	event api.MultiplexEvent
+	lock  sync.Mutex
+}
+
+func newWSSubscriptionSink(conn *websocket.Conn) (ws *wsSubscriptionSink) {
+
+	ws = &wsSubscriptionSink{
+		conn: conn,
+	}
+
+	return ws
+}
+
+func (ws *wsSubscriptionSink) WriteRecord(id uint32, position int64, offset int64, r log.Record) (err error) {
+
+	ws.lock.Lock()
+	defer ws.lock.Unlock()
+
+	ws.event = api.MultiplexEvent{
+		ID: id,
+		JSONRecord: &api.JSONRecord{
+			Position: position,
+			Offset:   offset,
+		},
+	}
+
+	if json.Valid(r) {
+		ws.event.Payload = json.RawMessage(r)
+	} else {
+		ws.event.PayloadBase64 = []byte(r)
+	}
+
+	err = ws.conn.WriteJSON(&ws.event)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (ws *wsSubscriptionSink) WriteEnd(id uint32, reason int, er error) (err error) {
+
+	ws.lock.Lock()
+	defer ws.lock.Unlock()
+
+	ws.event = api.MultiplexEvent{
+		ID:  id,
+		End: endReasons[reason],
+	}
+
+	if er != nil {
+		ws.event.Error = er.Error()
+	}
+
+	err = ws.conn.WriteJSON(&ws.event)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (ws *wsSubscriptionSink) WriteClose() (err error) {
+
+	ws.lock.Lock()
+	defer ws.lock.Unlock()
+
+	err = ws.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (ws *wsSubscriptionSink) Flush() (err error) {
+
+	// Websocket messages are written as a whole.
+	return nil
+}
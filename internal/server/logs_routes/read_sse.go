@@ -0,0 +1,418 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs_routes
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dataptive/styx/internal/logman"
+	"github.com/dataptive/styx/pkg/api"
+	"github.com/dataptive/styx/pkg/filter"
+	"github.com/dataptive/styx/pkg/log"
+	"github.com/dataptive/styx/pkg/logger"
+	"github.com/dataptive/styx/pkg/recio"
+
+	"github.com/gorilla/mux"
+)
+
+var (
+	sseNewline   = []byte("\n")
+	sseHeartbeat = []byte(": heartbeat\n\n")
+
+	errStreamingUnsupported = errors.New("server: response writer does not support streaming")
+)
+
+func (lr *LogsRouter) ReadSSEMatcher(r *http.Request, rm *mux.RouteMatch) (match bool) {
+
+	accept := r.Header.Get("Accept")
+	mediaType, _, _ := mime.ParseMediaType(accept)
+
+	match = mediaType == api.EventStreamMediaType
+
+	return match
+}
+
+func (lr *LogsRouter) ReadSSEHandler(w http.ResponseWriter, r *http.Request) {
+
+	config := lr.currentConfig()
+
+	vars := mux.Vars(r)
+	name := vars["name"]
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
+		logger.Debug(errStreamingUnsupported)
+		return
+	}
+
+	params := api.ConsumeParams{
+		Whence:   log.SeekOrigin,
+		Position: 0,
+		Count:    -1,
+		Follow:   true,
+	}
+	query := r.URL.Query()
+
+	err := lr.schemaDecoder.Decode(&params, query)
+	if err != nil {
+		er := api.NewParamsError(err)
+		api.WriteError(w, http.StatusBadRequest, er)
+		logger.Debug(err)
+		return
+	}
+
+	// On reconnection, resume right after the last event received
+	// by the client, regardless of the initial query params.
+	lastEventID := r.Header.Get(api.LastEventIDHeaderName)
+	if lastEventID != "" {
+
+		lastPosition, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil {
+			er := api.NewParamsError(err)
+			api.WriteError(w, http.StatusBadRequest, er)
+			logger.Debug(err)
+			return
+		}
+
+		params.Whence = log.SeekOrigin
+		params.Position = lastPosition + 1
+	}
+
+	err = params.Validate()
+	if err != nil {
+		er := api.NewParamsError(err)
+		api.WriteError(w, http.StatusBadRequest, er)
+		logger.Debug(err)
+		return
+	}
+
+	recordFilter, err := filter.Parse(params.Filter)
+	if err != nil {
+		er := api.NewParamsError(err)
+		api.WriteError(w, http.StatusBadRequest, er)
+		logger.Debug(err)
+		return
+	}
+
+	managedLog, err := lr.manager.GetLog(name)
+	if err == logman.ErrNotExist {
+		api.WriteError(w, http.StatusNotFound, api.ErrLogNotFound)
+		logger.Debug(err)
+		return
+	}
+
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
+		logger.Debug(err)
+		return
+	}
+
+	logReader, err := managedLog.NewReader(clientID(r), params.Follow, recio.ModeManual)
+	if err == logman.ErrUnavailable {
+		api.WriteError(w, http.StatusBadRequest, api.ErrLogNotAvailable)
+		logger.Debug(err)
+		return
+	}
+
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
+		logger.Debug(err)
+		return
+	}
+
+	err = logReader.Seek(params.Position, params.Whence)
+	if err == log.ErrOutOfRange {
+		api.WriteError(w, http.StatusBadRequest, api.ErrOutOfRange)
+		logger.Debug(err)
+		logReader.Close()
+		return
+	}
+
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
+		logger.Debug(err)
+		logReader.Close()
+		return
+	}
+
+	w.Header().Set("Content-Type", api.EventStreamMediaType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	heartbeatInterval := time.Duration(config.TCPTimeout) * time.Second / 2
+	sw := newSSEWriter(w, flusher, config.HTTPWriteBufferSize, heartbeatInterval)
+
+	sw.HandleError(func(err error) {
+		logger.Debug(err)
+
+		// Close reader to unlock follow.
+		logReader.Close()
+	})
+
+	// Close reader to unlock follow when the client goes away.
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-r.Context().Done():
+			logReader.Close()
+		case <-done:
+		}
+	}()
+
+	err = readSSE(sw, logReader, recordFilter, params.Count)
+	if err != nil {
+		logger.Debug(err)
+
+		// Close reader to unlock follow
+		// if not already done.
+		logReader.Close()
+		sw.Close()
+		return
+	}
+
+	err = logReader.Close()
+	if err != nil {
+		logger.Debug(err)
+	}
+
+	err = sw.Close()
+	if err != nil {
+		logger.Debug(err)
+	}
+}
+
+func readSSE(sw *sseWriter, lr *log.LogReader, f filter.Filter, limit int64) (err error) {
+
+	count := int64(0)
+	record := log.Record{}
+
+	for {
+		if count == limit {
+			break
+		}
+
+		_, err := lr.Read(&record)
+		if err == io.EOF {
+			break
+		}
+
+		if err == recio.ErrMustFill {
+
+			err = sw.Flush()
+			if err != nil {
+				return err
+			}
+
+			err = lr.Fill()
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if !filter.Match(f, record) {
+			continue
+		}
+
+		position, _ := lr.Tell()
+
+		err = sw.WriteEvent(position-1, record)
+		if err != nil {
+			return err
+		}
+
+		count++
+	}
+
+	err = sw.Flush()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// sseWriter writes server-sent events and keeps the stream alive with
+// comment heartbeats while no event is sent.
+type sseWriter struct {
+	writer            *bufio.Writer
+	flusher           http.Flusher
+	heartbeatInterval time.Duration
+	heartbeatTicker   *time.Ticker
+	heartbeaterClose  chan struct{}
+	heartbeaterDone   chan struct{}
+	closed            bool
+	lock              sync.Mutex
+	errorHandler      func(err error)
+}
+
+func newSSEWriter(w io.Writer, flusher http.Flusher, bufferSize int, heartbeatInterval time.Duration) (sw *sseWriter) {
+
+	sw = &sseWriter{
+		writer:            bufio.NewWriterSize(w, bufferSize),
+		flusher:           flusher,
+		heartbeatInterval: heartbeatInterval,
+		heartbeatTicker:   time.NewTicker(heartbeatInterval),
+		heartbeaterClose:  make(chan struct{}),
+		heartbeaterDone:   make(chan struct{}),
+		closed:            false,
+		errorHandler:      nil,
+	}
+
+	go sw.heartbeater()
+
+	return sw
+}
+
+func (sw *sseWriter) WriteEvent(id int64, p []byte) (err error) {
+
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+
+	_, err = sw.writer.WriteString("id: " + strconv.FormatInt(id, 10) + "\n")
+	if err != nil {
+		return err
+	}
+
+	// Multi-line payloads are sent as multiple data fields, which
+	// clients join back with newlines.
+	lines := bytes.Split(p, sseNewline)
+
+	for _, line := range lines {
+
+		_, err = sw.writer.WriteString("data: ")
+		if err != nil {
+			return err
+		}
+
+		_, err = sw.writer.Write(bytes.TrimSuffix(line, []byte("\r")))
+		if err != nil {
+			return err
+		}
+
+		_, err = sw.writer.Write(sseNewline)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = sw.writer.Write(sseNewline)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (sw *sseWriter) Flush() (err error) {
+
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+
+	err = sw.flush()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (sw *sseWriter) Close() (err error) {
+
+	sw.lock.Lock()
+
+	if sw.closed {
+		sw.lock.Unlock()
+		return nil
+	}
+
+	sw.closed = true
+	sw.heartbeatTicker.Stop()
+
+	sw.lock.Unlock()
+
+	sw.heartbeaterClose <- struct{}{}
+	<-sw.heartbeaterDone
+
+	return nil
+}
+
+func (sw *sseWriter) HandleError(h func(err error)) {
+
+	sw.errorHandler = h
+}
+
+func (sw *sseWriter) flush() (err error) {
+
+	sw.heartbeatTicker.Stop()
+
+	err = sw.writer.Flush()
+	if err != nil {
+		return err
+	}
+
+	sw.flusher.Flush()
+
+	sw.heartbeatTicker.Reset(sw.heartbeatInterval)
+
+	return nil
+}
+
+func (sw *sseWriter) heartbeater() {
+
+	for {
+		select {
+		case <-sw.heartbeatTicker.C:
+
+			sw.lock.Lock()
+
+			if sw.closed {
+				sw.lock.Unlock()
+				break
+			}
+
+			_, err := sw.writer.Write(sseHeartbeat)
+			if err == nil {
+				err = sw.flush()
+			}
+
+			sw.lock.Unlock()
+
+			if err != nil && sw.errorHandler != nil {
+				go sw.errorHandler(err)
+			}
+
+		case <-sw.heartbeaterClose:
+			sw.heartbeaterDone <- struct{}{}
+			return
+		}
+	}
+}
@@ -15,13 +15,16 @@
 package logs_routes
 
 import (
+	"errors"
 	"io"
+	"net"
 	"net/http"
 	"strconv"
 
 	"github.com/dataptive/styx/internal/logman"
 	"github.com/dataptive/styx/pkg/api"
 	"github.com/dataptive/styx/pkg/api/tcp"
+	"github.com/dataptive/styx/pkg/filter"
 	"github.com/dataptive/styx/pkg/log"
 	"github.com/dataptive/styx/pkg/logger"
 	"github.com/dataptive/styx/pkg/recio"
@@ -29,14 +32,22 @@ import (
 	"github.com/gorilla/mux"
 )
 
+var (
+	// Filtered streams skip records, consumers can only tell the
+	// position of those they receive when sent along with them.
+	errFilterWithoutMetadata = errors.New("server: filter requires metadata")
+)
+
 func (lr *LogsRouter) ReadTCPHandler(w http.ResponseWriter, r *http.Request) {
 
+	config := lr.currentConfig()
+
 	var err error
 
 	vars := mux.Vars(r)
 	name := vars["name"]
 
-	remoteTimeout := lr.config.TCPTimeout
+	remoteTimeout := config.TCPTimeout
 
 	// TODO: Change the header name to a more adequate one.
 	rawTimeout := r.Header.Get(api.TimeoutHeaderName)
@@ -74,6 +85,14 @@ func (lr *LogsRouter) ReadTCPHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordFilter, err := filter.Parse(params.Filter)
+	if err != nil {
+		er := api.NewParamsError(err)
+		api.WriteError(w, http.StatusBadRequest, er)
+		logger.Debug(err)
+		return
+	}
+
 	managedLog, err := lr.manager.GetLog(name)
 	if err == logman.ErrNotExist {
 		api.WriteError(w, http.StatusNotFound, api.ErrLogNotFound)
@@ -87,7 +106,7 @@ func (lr *LogsRouter) ReadTCPHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	logReader, err := managedLog.NewReader(params.Follow, recio.ModeManual)
+	logReader, err := managedLog.NewReader(clientID(r), params.Follow, recio.ModeManual)
 	if err == logman.ErrUnavailable {
 		api.WriteError(w, http.StatusBadRequest, api.ErrLogNotAvailable)
 		logger.Debug(err)
@@ -101,6 +120,13 @@ func (lr *LogsRouter) ReadTCPHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	err = logReader.Seek(params.Position, params.Whence)
+	if err == log.ErrOutOfRange {
+		api.WriteError(w, http.StatusBadRequest, api.ErrOutOfRange)
+		logger.Debug(err)
+		logReader.Close()
+		return
+	}
+
 	if err != nil {
 		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
 		logger.Debug(err)
@@ -108,46 +134,104 @@ func (lr *LogsRouter) ReadTCPHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Add(api.TimeoutHeaderName, strconv.Itoa(lr.config.TCPTimeout))
-	conn, err := UpgradeTCP(w)
+	caps := lr.upgradeCapabilities(w, r, consumeFeatures)
+
+	if recordFilter != nil && !caps.Has(tcp.FeatureMetadata) {
+		er := api.NewParamsError(errFilterWithoutMetadata)
+		api.WriteError(w, http.StatusBadRequest, er)
+		logger.Debug(errFilterWithoutMetadata)
+		logReader.Close()
+		return
+	}
+
+	w.Header().Add(api.TimeoutHeaderName, strconv.Itoa(config.TCPTimeout))
+	conn, err := UpgradeTCP(w, caps.Version)
 	if err != nil {
 		logger.Debug(err)
 		logReader.Close()
 		return
 	}
 
-	err = conn.SetReadBuffer(lr.config.TCPReadBufferSize)
+	position, _ := logReader.Tell()
+	session := managedLog.NewSession(clientID(r), conn.RemoteAddr().String(), position)
+
+	control := newReadControl(managedLog, clientID(r), params.Follow, logReader, params.Count)
+
+	lr.serveReadTCP(conn, caps, control, session, recordFilter, remoteTimeout)
+}
+
+// serveReadTCP streams records to a styx protocol connection once the
+// handshake completed, and closes the log reader, the consumer session and
+// the connection.
+func (lr *LogsRouter) serveReadTCP(conn net.Conn, caps tcp.Capabilities, control *readControl, session *logman.Session, recordFilter filter.Filter, remoteTimeout int) {
+
+	var err error
+
+	defer session.Close()
+
+	lr.setConnBuffers(conn)
+
+	compressedConn, err := compressConn(conn, caps.Compression)
 	if err != nil {
-		logger.Warn(err)
+		logger.Debug(err)
+		control.Close()
+		conn.Close()
+		return
 	}
 
-	err = conn.SetWriteBuffer(lr.config.TCPWriteBufferSize)
-	if err != nil {
-		logger.Warn(err)
+	conn = compressedConn
+
+	config := lr.currentConfig()
+
+	tcpWriter := tcp.NewTCPWriter(conn, config.TCPWriteBufferSize, config.TCPReadBufferSize, config.TCPTimeout, remoteTimeout, recio.ModeAuto)
+
+	if caps.Has(tcp.FeatureBatch) {
+		tcpWriter.EnableBatch()
+	}
+
+	if caps.Has(tcp.FeatureMetadata) {
+		tcpWriter.EnableMetadata()
+	}
+
+	if caps.Has(tcp.FeatureFlowControl) {
+		tcpWriter.EnableFlowControl()
+	}
+
+	if caps.Has(tcp.FeatureProgress) {
+		tcpWriter.HandleProgress(func(count int64, cursor string) {
+			err := session.Progress(count, cursor)
+			if err != nil {
+				logger.Debug(err)
+			}
+		})
 	}
 
-	tcpWriter := tcp.NewTCPWriter(conn, lr.config.TCPWriteBufferSize, lr.config.TCPReadBufferSize, lr.config.TCPTimeout, remoteTimeout, recio.ModeAuto)
+	if caps.Has(tcp.FeatureControl) {
+		tcpWriter.HandleControl(func(message tcp.ControlMessage) {
+			applyControl(control, message)
+		})
+	}
 
 	tcpWriter.HandleError(func(err error) {
 		if err != io.EOF {
 			logger.Debug(err)
 		}
 
-		// Close reader to unlock follow.
-		logReader.Close()
+		// Stop stream to unlock follow.
+		control.Stop()
 	})
 
-	err = readTCP(tcpWriter, logReader, params.Count)
+	err = readTCP(tcpWriter, control, session, recordFilter)
 	if err != nil {
 		logger.Debug(err)
 
 		// Close reader to unlock follow
 		// if not already done.
-		logReader.Close()
+		control.Close()
 
 		// Try to write error back to
 		// client in case conn is still open.
-		tcpWriter.WriteError(err)
+		tcpWriter.WriteError(protocolError(err))
 		tcpWriter.Flush()
 
 		// Close conn in case its still open.
@@ -156,7 +240,7 @@ func (lr *LogsRouter) ReadTCPHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = logReader.Close()
+	err = control.Close()
 	if err != nil {
 		logger.Debug(err)
 
@@ -169,17 +253,64 @@ func (lr *LogsRouter) ReadTCPHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func readTCP(w *tcp.TCPWriter, lr *log.LogReader, limit int64) (err error) {
+// applyControl acts on the record stream as requested by a styx protocol
+// control message.
+func applyControl(control *readControl, message tcp.ControlMessage) {
+
+	switch message.Action {
+	case tcp.ControlSeek:
+		control.Seek(message.Position, log.Whence(message.Whence))
+	case tcp.ControlPause:
+		control.Pause()
+	case tcp.ControlResume:
+		control.Resume()
+	case tcp.ControlCount:
+		control.SetCount(message.Count)
+	default:
+		logger.Debug(tcp.ErrInvalidParams)
+	}
+}
+
+func readTCP(w *tcp.TCPWriter, control *readControl, s *logman.Session, f filter.Filter) (err error) {
 
-	count := int64(0)
 	record := log.Record{}
 
 	for {
-		if count == limit {
+		seeked, seekErr, done, err := control.Wait(w.Flush)
+		if err != nil {
+			return err
+		}
+
+		if done {
 			break
 		}
 
-		_, err := lr.Read(&record)
+		lr := control.Reader()
+
+		if seeked {
+
+			// Report a failed seek before echoing the position
+			// the stream goes on from.
+			if seekErr != nil {
+				logger.Debug(seekErr)
+
+				_, err = w.WriteError(protocolError(seekErr))
+				if err != nil {
+					return err
+				}
+			}
+
+			position, _ := lr.Tell()
+
+			_, err = w.WriteSeek(position)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		_, err = lr.Read(&record)
 		if err == io.EOF {
 			break
 		}
@@ -192,6 +323,11 @@ func readTCP(w *tcp.TCPWriter, lr *log.LogReader, limit int64) (err error) {
 			}
 
 			err = lr.Fill()
+			// Interrupted by a control, apply it.
+			if err == log.ErrInterrupted {
+				continue
+			}
+
 			if err != nil {
 				return err
 			}
@@ -203,12 +339,21 @@ func readTCP(w *tcp.TCPWriter, lr *log.LogReader, limit int64) (err error) {
 			return err
 		}
 
-		_, err = w.Write(&record)
+		if !filter.Match(f, record) {
+			continue
+		}
+
+		position, _ := lr.Tell()
+		position--
+
+		_, err = w.WriteAt(&record, position)
 		if err != nil {
 			return err
 		}
 
-		count++
+		s.Sent(position)
+
+		control.Sent()
 	}
 
 	err = w.Flush()
@@ -15,11 +15,15 @@
 package logs_routes
 
 import (
+	"encoding/binary"
+	"encoding/json"
 	"io"
 	"net/http"
 
 	"github.com/dataptive/styx/internal/logman"
 	"github.com/dataptive/styx/pkg/api"
+	"github.com/dataptive/styx/pkg/api/tcp"
+	"github.com/dataptive/styx/pkg/filter"
 	"github.com/dataptive/styx/pkg/log"
 	"github.com/dataptive/styx/pkg/logger"
 	"github.com/dataptive/styx/pkg/recio"
@@ -51,6 +55,30 @@ func (lr *LogsRouter) ReadWSHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	flowControlParams := api.FlowControlParams{
+		FlowControl: false,
+	}
+
+	err = lr.schemaDecoder.Decode(&flowControlParams, query)
+	if err != nil {
+		er := api.NewParamsError(err)
+		api.WriteError(w, http.StatusBadRequest, er)
+		logger.Debug(err)
+		return
+	}
+
+	metadataParams := api.MetadataParams{
+		Metadata: false,
+	}
+
+	err = lr.schemaDecoder.Decode(&metadataParams, query)
+	if err != nil {
+		er := api.NewParamsError(err)
+		api.WriteError(w, http.StatusBadRequest, er)
+		logger.Debug(err)
+		return
+	}
+
 	err = params.Validate()
 	if err != nil {
 		er := api.NewParamsError(err)
@@ -59,6 +87,21 @@ func (lr *LogsRouter) ReadWSHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordFilter, err := filter.Parse(params.Filter)
+	if err != nil {
+		er := api.NewParamsError(err)
+		api.WriteError(w, http.StatusBadRequest, er)
+		logger.Debug(err)
+		return
+	}
+
+	if recordFilter != nil && !metadataParams.Metadata {
+		er := api.NewParamsError(errFilterWithoutMetadata)
+		api.WriteError(w, http.StatusBadRequest, er)
+		logger.Debug(errFilterWithoutMetadata)
+		return
+	}
+
 	managedLog, err := lr.manager.GetLog(name)
 	if err == logman.ErrNotExist {
 		api.WriteError(w, http.StatusNotFound, api.ErrLogNotFound)
@@ -72,7 +115,7 @@ func (lr *LogsRouter) ReadWSHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	logReader, err := managedLog.NewReader(params.Follow, recio.ModeManual)
+	logReader, err := managedLog.NewReader(clientID(r), params.Follow, recio.ModeManual)
 	if err == logman.ErrUnavailable {
 		api.WriteError(w, http.StatusBadRequest, api.ErrLogNotAvailable)
 		logger.Debug(err)
@@ -86,6 +129,13 @@ func (lr *LogsRouter) ReadWSHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	err = logReader.Seek(params.Position, params.Whence)
+	if err == log.ErrOutOfRange {
+		api.WriteError(w, http.StatusBadRequest, api.ErrOutOfRange)
+		logger.Debug(err)
+		logReader.Close()
+		return
+	}
+
 	if err != nil {
 		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
 		logger.Debug(err)
@@ -93,7 +143,9 @@ func (lr *LogsRouter) ReadWSHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	conn, err := UpgradeWebsocket(w, r, lr.config.CORSAllowedOrigins, lr.config.WSReadBufferSize, lr.config.WSWriteBufferSize)
+	config := lr.currentConfig()
+
+	conn, err := UpgradeWebsocket(w, r, config.CORSAllowedOrigins, config.WSReadBufferSize, config.WSWriteBufferSize, config.WSCompression)
 	if err != nil {
 		logger.Debug(err)
 
@@ -101,20 +153,38 @@ func (lr *LogsRouter) ReadWSHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = readWS(conn, logReader, params.Count)
+	control := newReadControl(managedLog, clientID(r), params.Follow, logReader, params.Count)
+
+	var credits *tcp.Credits
+
+	if flowControlParams.FlowControl {
+		credits = tcp.NewCredits()
+	}
+
+	go func() {
+		err := readControls(conn, control, credits)
+		if err != nil {
+			logger.Debug(err)
+		}
+
+		// Stop stream to unlock follow.
+		control.Stop()
+	}()
+
+	err = readWS(conn, control, recordFilter, credits, metadataParams.Metadata)
 	if err != nil {
 		logger.Debug(err)
 
 		// Close reader to unlock follow
 		// if not already done.
-		logReader.Close()
+		control.Close()
 
 		// Close conn in case its still open.
 		conn.Close()
 		return
 	}
 
-	err = logReader.Close()
+	err = control.Close()
 	if err != nil {
 		logger.Debug(err)
 
@@ -136,17 +206,52 @@ func (lr *LogsRouter) ReadWSHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func readWS(w *websocket.Conn, lr *log.LogReader, limit int64) (err error) {
+// readWS sends records as binary frames. With metadata, each frame starts
+// with the position of the record as a big endian int64.
+func readWS(w *websocket.Conn, control *readControl, f filter.Filter, credits *tcp.Credits, metadata bool) (err error) {
 
-	count := int64(0)
 	record := log.Record{}
+	frame := []byte{}
+	header := [8]byte{}
 
 	for {
-		if count == limit {
+		seeked, seekErr, done, err := control.Wait(nil)
+		if err != nil {
+			return err
+		}
+
+		if done {
 			break
 		}
 
-		_, err := lr.Read(&record)
+		lr := control.Reader()
+
+		if seeked {
+
+			position, _ := lr.Tell()
+
+			// A failed seek is echoed with the error and the
+			// position the stream goes on from.
+			code := ""
+			if seekErr != nil {
+				logger.Debug(seekErr)
+
+				code = seekErrorCode(seekErr)
+			}
+
+			err = w.WriteJSON(api.ConsumerControl{
+				Action:   api.ActionSeek,
+				Position: position,
+				Error:    code,
+			})
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		_, err = lr.Read(&record)
 		if err == io.EOF {
 			break
 		}
@@ -154,6 +259,11 @@ func readWS(w *websocket.Conn, lr *log.LogReader, limit int64) (err error) {
 		if err == recio.ErrMustFill {
 
 			err = lr.Fill()
+			// Interrupted by a control, apply it.
+			if err == log.ErrInterrupted {
+				continue
+			}
+
 			if err != nil {
 				return err
 			}
@@ -165,11 +275,112 @@ func readWS(w *websocket.Conn, lr *log.LogReader, limit int64) (err error) {
 			return err
 		}
 
-		err = w.WriteMessage(websocket.BinaryMessage, []byte(record))
+		if !filter.Match(f, record) {
+			continue
+		}
+
+		if credits != nil {
+			err = credits.Acquire(len(record))
+			if err != nil {
+				return err
+			}
+		}
+
+		frame = frame[:0]
+
+		if metadata {
+			position, _ := lr.Tell()
+
+			binary.BigEndian.PutUint64(header[:], uint64(position-1))
+			frame = append(frame, header[:]...)
+		}
+
+		frame = append(frame, record...)
+
+		err = w.WriteMessage(websocket.BinaryMessage, frame)
 		if err != nil {
 			return err
 		}
+
+		control.Sent()
 	}
 
 	return nil
 }
+
+// seekErrorCode returns the API error code of a failed seek.
+func seekErrorCode(err error) (code string) {
+
+	switch err {
+	case log.ErrOutOfRange:
+		return api.ErrOutOfRange.Code
+
+	case logman.ErrUnavailable:
+		return api.ErrLogNotAvailable.Code
+
+	case api.ErrInvalidWhence:
+		return api.NewParamsError(err).Code
+	}
+
+	return api.ErrUnknownError.Code
+}
+
+// readControls applies the controls sent by a websocket client until the
+// connection is closed, and closes credits when returning. Text frames
+// without an action grant credits to flow controlled streams.
+func readControls(conn *websocket.Conn, control *readControl, credits *tcp.Credits) (err error) {
+
+	if credits != nil {
+		defer credits.Close()
+	}
+
+	for {
+		messageType, p, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+
+		if messageType != websocket.TextMessage {
+			return tcp.ErrUnexpectedMessageType
+		}
+
+		message := api.ConsumerControl{}
+
+		err = json.Unmarshal(p, &message)
+		if err != nil {
+			return err
+		}
+
+		switch message.Action {
+
+		case "":
+			if credits == nil {
+				return tcp.ErrUnexpectedMessageType
+			}
+
+			credit := api.Credit{}
+
+			err = json.Unmarshal(p, &credit)
+			if err != nil {
+				return err
+			}
+
+			credits.Grant(credit.Records, credit.Bytes)
+
+		case api.ActionSeek:
+			control.Seek(message.Position, message.Whence)
+
+		case api.ActionPause:
+			control.Pause()
+
+		case api.ActionResume:
+			control.Resume()
+
+		case api.ActionCount:
+			control.SetCount(message.Count)
+
+		default:
+			return errUnknownControlAction
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs_routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/dataptive/styx/internal/logman"
+	"github.com/dataptive/styx/internal/server/auth"
+	"github.com/dataptive/styx/pkg/api"
+	"github.com/dataptive/styx/pkg/log"
+	"github.com/dataptive/styx/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+func (lr *LogsRouter) RenameHandler(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	name := vars["name"]
+
+	form := api.RenameLogForm{}
+
+	err := r.ParseForm()
+	if err != nil {
+		api.WriteError(w, http.StatusBadRequest, api.ErrUnknownError)
+		logger.Debug(err)
+		return
+	}
+
+	err = lr.schemaDecoder.Decode(&form, r.PostForm)
+	if err != nil {
+		er := api.NewParamsError(err)
+		api.WriteError(w, http.StatusBadRequest, er)
+		logger.Debug(err)
+		return
+	}
+
+	if !allowed(r, form.Name, auth.PermissionAdmin) {
+		api.WriteError(w, http.StatusForbidden, api.ErrForbidden)
+		logger.Debug(auth.ErrForbidden)
+		return
+	}
+
+	if !validName(form.Name) {
+		api.WriteError(w, http.StatusBadRequest, api.ErrLogInvalidName)
+		logger.Debug(logman.ErrInvalidName)
+		return
+	}
+
+	aliasTTL := time.Duration(form.AliasTTL) * time.Second
+
+	ml, err := lr.manager.RenameLog(name, form.Name, aliasTTL)
+	if err == logman.ErrNotExist {
+		api.WriteError(w, http.StatusNotFound, api.ErrLogNotFound)
+		logger.Debug(err)
+		return
+	}
+
+	if err == log.ErrExist {
+		api.WriteError(w, http.StatusBadRequest, api.ErrLogExist)
+		logger.Debug(err)
+		return
+	}
+
+	if err == logman.ErrInvalidName {
+		api.WriteError(w, http.StatusBadRequest, api.ErrLogInvalidName)
+		logger.Debug(err)
+		return
+	}
+
+	if err == logman.ErrNameConflict {
+		api.WriteError(w, http.StatusBadRequest, api.ErrNameConflict)
+		logger.Debug(err)
+		return
+	}
+
+	if err == logman.ErrNamespaceQuota {
+		api.WriteError(w, http.StatusBadRequest, api.ErrNamespaceQuota)
+		logger.Debug(err)
+		return
+	}
+
+	if err == logman.ErrUnavailable {
+		api.WriteError(w, http.StatusBadRequest, api.ErrLogNotAvailable)
+		logger.Debug(err)
+		return
+	}
+
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
+		logger.Debug(err)
+		return
+	}
+
+	logInfo := ml.Stat()
+
+	api.WriteResponse(w, http.StatusOK, api.RenameLogResponse(logInfo))
+}
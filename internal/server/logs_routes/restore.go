@@ -18,6 +18,7 @@ import (
 	"net/http"
 
 	"github.com/dataptive/styx/internal/logman"
+	"github.com/dataptive/styx/internal/server/auth"
 	"github.com/dataptive/styx/pkg/api"
 	"github.com/dataptive/styx/pkg/log"
 	"github.com/dataptive/styx/pkg/logger"
@@ -36,6 +37,18 @@ func (lr *LogsRouter) RestoreHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !allowed(r, params.Name, auth.PermissionAdmin) {
+		api.WriteError(w, http.StatusForbidden, api.ErrForbidden)
+		logger.Debug(auth.ErrForbidden)
+		return
+	}
+
+	if !validName(params.Name) {
+		api.WriteError(w, http.StatusBadRequest, api.ErrLogInvalidName)
+		logger.Debug(logman.ErrInvalidName)
+		return
+	}
+
 	err = lr.manager.RestoreLog(params.Name, r.Body)
 	if err == log.ErrExist {
 		api.WriteError(w, http.StatusBadRequest, api.ErrLogExist)
@@ -49,6 +62,24 @@ func (lr *LogsRouter) RestoreHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err == logman.ErrNameConflict {
+		api.WriteError(w, http.StatusBadRequest, api.ErrNameConflict)
+		logger.Debug(err)
+		return
+	}
+
+	if err == logman.ErrNamespaceQuota {
+		api.WriteError(w, http.StatusBadRequest, api.ErrNamespaceQuota)
+		logger.Debug(err)
+		return
+	}
+
+	if err == logman.ErrUnavailable {
+		api.WriteError(w, http.StatusBadRequest, api.ErrLogNotAvailable)
+		logger.Debug(err)
+		return
+	}
+
 	if err != nil {
 		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
 		logger.Debug(err)
@@ -16,8 +16,11 @@ package logs_routes
 
 import (
 	"net/http"
+	"strings"
+	"sync"
 
 	"github.com/dataptive/styx/internal/logman"
+	"github.com/dataptive/styx/internal/server/auth"
 	"github.com/dataptive/styx/internal/server/config"
 	"github.com/dataptive/styx/pkg/api"
 
@@ -25,14 +28,28 @@ import (
 	"github.com/gorilla/schema"
 )
 
+const (
+	// logRoute matches log names, holding slashes when namespaced.
+	logRoute = `/{name:[a-zA-Z0-9_\-]+(?:/[a-zA-Z0-9_\-]+)*}`
+)
+
+var (
+	routeSuffixes = []string{"records", "sessions", "cursors", "truncate", "rename", "metadata", "backup", "scan"}
+)
+
+// LogsRouter serves the logs routes. Its config and authorizer may be
+// replaced on reload, applying to requests and connections accepted from
+// then on.
 type LogsRouter struct {
 	router        *mux.Router
 	manager       *logman.LogManager
 	config        config.Config
+	authorizer    *auth.Authorizer
+	configLock    sync.RWMutex
 	schemaDecoder *schema.Decoder
 }
 
-func RegisterRoutes(router *mux.Router, logManager *logman.LogManager, config config.Config) (lr *LogsRouter) {
+func RegisterRoutes(router *mux.Router, logManager *logman.LogManager, config config.Config, authorizer *auth.Authorizer) (lr *LogsRouter) {
 
 	var decoder = schema.NewDecoder()
 	decoder.IgnoreUnknownKeys(true)
@@ -41,82 +58,177 @@ func RegisterRoutes(router *mux.Router, logManager *logman.LogManager, config co
 		router:        router,
 		manager:       logManager,
 		config:        config,
+		authorizer:    authorizer,
 		schemaDecoder: decoder,
 	}
 
-	router.HandleFunc("", lr.ListHandler).
+	router.HandleFunc("", lr.authenticate(lr.ListHandler)).
 		Methods(http.MethodGet)
 
-	router.HandleFunc("", lr.CreateHandler).
+	router.HandleFunc("", lr.authenticate(lr.CreateHandler)).
 		Methods(http.MethodPost)
 
-	router.HandleFunc("/{name}", lr.GetHandler).
+	router.HandleFunc("/records", lr.authenticate(lr.ReadMultiplexWSHandler)).
+		Methods(http.MethodGet).
+		Headers("Upgrade", "websocket")
+
+	router.HandleFunc("/records", lr.authenticate(lr.ReadMultiplexTCPHandler)).
+		Methods(http.MethodGet).
+		Headers("Connection", "upgrade").
+		MatcherFunc(lr.StyxUpgradeMatcher)
+
+	router.HandleFunc(logRoute+"/sessions", lr.authorize(auth.PermissionRead, lr.SessionsHandler)).
 		Methods(http.MethodGet)
 
-	router.HandleFunc("/{name}", lr.DeleteHandler).
-		Methods(http.MethodDelete)
+	router.HandleFunc(logRoute+"/cursors", lr.authorize(auth.PermissionRead, lr.CursorsHandler)).
+		Methods(http.MethodGet)
+
+	router.HandleFunc(logRoute+"/truncate", lr.authorize(auth.PermissionAdmin, lr.TruncateHandler)).
+		Methods(http.MethodPost)
+
+	router.HandleFunc(logRoute+"/scan", lr.authorize(auth.PermissionAdmin, lr.ScanHandler)).
+		Methods(http.MethodPost)
 
-	router.HandleFunc("/{name}/truncate", lr.TruncateHandler).
+	router.HandleFunc(logRoute+"/rename", lr.authorize(auth.PermissionAdmin, lr.RenameHandler)).
 		Methods(http.MethodPost)
 
-	router.HandleFunc("/{name}/backup", lr.BackupHandler).
+	router.HandleFunc(logRoute+"/metadata", lr.authorize(auth.PermissionAdmin, lr.SetMetadataHandler)).
+		Methods(http.MethodPut)
+
+	router.HandleFunc(logRoute+"/backup", lr.authorize(auth.PermissionAdmin, lr.BackupHandler)).
 		Methods(http.MethodGet)
 
-	router.HandleFunc("/restore", lr.RestoreHandler).
+	router.HandleFunc("/restore", lr.authenticate(lr.RestoreHandler)).
 		Methods(http.MethodPost)
 
-	router.HandleFunc("/{name}/records", lr.WriteWSHandler).
+	router.HandleFunc(logRoute+"/records", lr.authorize(auth.PermissionWrite, lr.WriteWSHandler)).
 		Methods(http.MethodGet).
 		Headers("Upgrade", "websocket").
 		Headers("X-HTTP-Method-Override", "POST")
 
-	router.HandleFunc("/{name}/records", lr.WriteWSHandler).
+	router.HandleFunc(logRoute+"/records", lr.authorize(auth.PermissionWrite, lr.WriteWSHandler)).
 		Methods(http.MethodPost).
 		Headers("Upgrade", "websocket")
 
-	router.HandleFunc("/{name}/records", lr.ReadWSHandler).
+	router.HandleFunc(logRoute+"/records", lr.authorize(auth.PermissionRead, lr.ReadWSHandler)).
 		Methods(http.MethodGet).
 		Headers("Upgrade", "websocket")
 
-	router.HandleFunc("/{name}/records", lr.WriteTCPHandler).
+	router.HandleFunc(logRoute+"/records", lr.authorize(auth.PermissionWrite, lr.WriteTCPHandler)).
 		Methods(http.MethodPost).
 		Headers("Connection", "upgrade").
-		Headers("Upgrade", api.StyxProtocolString)
+		MatcherFunc(lr.StyxUpgradeMatcher)
 
-	router.HandleFunc("/{name}/records", lr.ReadTCPHandler).
+	router.HandleFunc(logRoute+"/records", lr.authorize(auth.PermissionRead, lr.ReadTCPHandler)).
 		Methods(http.MethodGet).
 		Headers("Connection", "upgrade").
-		Headers("Upgrade", api.StyxProtocolString)
+		MatcherFunc(lr.StyxUpgradeMatcher)
 
-	router.HandleFunc("/{name}/records", lr.WriteLinesHandler).
+	router.HandleFunc(logRoute+"/records", lr.authorize(auth.PermissionRead, lr.ReadSSEHandler)).
+		Methods(http.MethodGet).
+		MatcherFunc(lr.ReadSSEMatcher)
+
+	router.HandleFunc(logRoute+"/records", lr.authorize(auth.PermissionWrite, lr.WriteJSONHandler)).
+		Methods(http.MethodPost).
+		MatcherFunc(lr.WriteJSONMatcher)
+
+	router.HandleFunc(logRoute+"/records", lr.authorize(auth.PermissionRead, lr.ReadJSONHandler)).
+		Methods(http.MethodGet).
+		MatcherFunc(lr.ReadJSONMatcher)
+
+	router.HandleFunc(logRoute+"/records", lr.authorize(auth.PermissionWrite, lr.WriteLinesHandler)).
 		Methods(http.MethodPost).
 		MatcherFunc(lr.WriteLinesMatcher)
 
-	router.HandleFunc("/{name}/records", lr.ReadLinesHandler).
+	router.HandleFunc(logRoute+"/records", lr.authorize(auth.PermissionRead, lr.ReadLinesHandler)).
 		Methods(http.MethodGet).
 		MatcherFunc(lr.ReadLinesMatcher)
 
-	router.HandleFunc("/{name}/records", lr.WriteBatchHandler).
+	router.HandleFunc(logRoute+"/records", lr.authorize(auth.PermissionWrite, lr.WriteBatchHandler)).
 		Methods(http.MethodPost).
 		Headers("Content-Type", api.RecordBinaryMediaType)
 
-	router.HandleFunc("/{name}/records", lr.ReadBatchHandler).
+	router.HandleFunc(logRoute+"/records", lr.authorize(auth.PermissionRead, lr.ReadBatchHandler)).
 		Methods(http.MethodGet).
 		Headers("Accept", api.RecordBinaryMediaType)
 
-	router.HandleFunc("/{name}/records", lr.WriteHandler).
+	router.HandleFunc(logRoute+"/records", lr.authorize(auth.PermissionWrite, lr.WriteHandler)).
 		Methods(http.MethodPost).
 		Headers("Content-Type", "application/octet-stream")
 
-	router.HandleFunc("/{name}/records", lr.WriteHandler).
+	router.HandleFunc(logRoute+"/records", lr.authorize(auth.PermissionWrite, lr.WriteHandler)).
 		Methods(http.MethodPost)
 
-	router.HandleFunc("/{name}/records", lr.ReadHandler).
+	router.HandleFunc(logRoute+"/records", lr.authorize(auth.PermissionRead, lr.ReadHandler)).
 		Methods(http.MethodGet).
 		Headers("Accept", "application/octet-stream")
 
-	router.HandleFunc("/{name}/records", lr.ReadHandler).
+	router.HandleFunc(logRoute+"/records", lr.authorize(auth.PermissionRead, lr.ReadHandler)).
 		Methods(http.MethodGet)
 
+	// Registered last as namespaced log names would also match the
+	// routes nested under logs.
+	router.HandleFunc(logRoute, lr.authorize(auth.PermissionRead, lr.GetHandler)).
+		Methods(http.MethodGet)
+
+	router.HandleFunc(logRoute, lr.authorize(auth.PermissionAdmin, lr.DeleteHandler)).
+		Methods(http.MethodDelete)
+
 	return lr
 }
+
+// Reload replaces the config and authorizer of the router, a nil authorizer
+// disabling auth.
+func (lr *LogsRouter) Reload(c config.Config, authorizer *auth.Authorizer) {
+
+	lr.configLock.Lock()
+	defer lr.configLock.Unlock()
+
+	lr.config = c
+	lr.authorizer = authorizer
+}
+
+func (lr *LogsRouter) currentConfig() (c config.Config) {
+
+	lr.configLock.RLock()
+	defer lr.configLock.RUnlock()
+
+	return lr.config
+}
+
+func (lr *LogsRouter) currentAuthorizer() (a *auth.Authorizer) {
+
+	lr.configLock.RLock()
+	defer lr.configLock.RUnlock()
+
+	return lr.authorizer
+}
+
+// validName reports whether the log name can be routed, the last segment
+// of namespaced names not colliding with the routes nested under logs.
+func validName(name string) (valid bool) {
+
+	i := strings.LastIndex(name, "/")
+	if i == -1 {
+		return true
+	}
+
+	for _, suffix := range routeSuffixes {
+		if name[i+1:] == suffix {
+			return false
+		}
+	}
+
+	return true
+}
+
+// writeLog returns the named log for writing, creating it from the log
+// templates when missing.
+func (lr *LogsRouter) writeLog(name string) (ml *logman.Log, err error) {
+
+	if !validName(name) {
+		return lr.manager.GetLog(name)
+	}
+
+	return lr.manager.GetOrCreateLog(name)
+}
@@ -0,0 +1,262 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs_routes
+
+import (
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/dataptive/styx/internal/server/auth"
+	"github.com/dataptive/styx/pkg/api"
+	"github.com/dataptive/styx/pkg/api/tcp"
+	"github.com/dataptive/styx/pkg/filter"
+	"github.com/dataptive/styx/pkg/log"
+	"github.com/dataptive/styx/pkg/logger"
+	"github.com/dataptive/styx/pkg/recio"
+)
+
+// ServeStyx serves a connection accepted by the raw styx protocol listener.
+// The connection starts with a binary handshake selecting the log and the
+// direction of the stream instead of an HTTP upgrade.
+func (lr *LogsRouter) ServeStyx(conn net.Conn) {
+
+	config := lr.currentConfig()
+
+	handshakeTimeout := time.Duration(config.TCPTimeout) * time.Second
+
+	err := conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	if err != nil {
+		logger.Debug(err)
+		conn.Close()
+		return
+	}
+
+	request := tcp.HandshakeRequest{}
+
+	_, err = request.ReadFrom(conn)
+	if err == tcp.ErrUnsupportedVersion {
+		logger.Debug(err)
+		rejectStyx(conn, tcp.LatestVersion, err)
+		return
+	}
+
+	if err != nil {
+		logger.Debug(err)
+		rejectStyx(conn, tcp.ProtocolVersion0, err)
+		return
+	}
+
+	err = conn.SetDeadline(time.Time{})
+	if err != nil {
+		logger.Debug(err)
+		conn.Close()
+		return
+	}
+
+	if request.Timeout == 0 {
+		request.Timeout = config.TCPTimeout
+	}
+
+	permission := auth.PermissionRead
+	if request.Direction == tcp.DirectionWrite {
+		permission = auth.PermissionWrite
+	}
+
+	var id *auth.Identity
+
+	authorizer := lr.currentAuthorizer()
+
+	if authorizer != nil {
+
+		var state *tls.ConnectionState
+
+		tlsConn, ok := conn.(*tls.Conn)
+		if ok {
+			connectionState := tlsConn.ConnectionState()
+			state = &connectionState
+		}
+
+		id, err = authorizer.AuthenticateConn(request.Token, state)
+		if err != nil {
+			logger.Debug(err)
+			rejectStyx(conn, request.Version, err)
+			return
+		}
+
+		name := lr.manager.ResolveName(request.Name)
+
+		if !id.Allowed(name, permission) {
+			logger.Debug(auth.ErrForbidden)
+			rejectStyx(conn, request.Version, auth.ErrForbidden)
+			return
+		}
+	}
+
+	client := connClientID(id, conn.RemoteAddr().String())
+
+	switch request.Direction {
+
+	case tcp.DirectionRead:
+		lr.serveStyxRead(conn, &request, client)
+
+	case tcp.DirectionWrite:
+		lr.serveStyxWrite(conn, &request, client)
+
+	default:
+		logger.Debug(tcp.ErrInvalidDirection)
+		rejectStyx(conn, request.Version, tcp.ErrInvalidDirection)
+	}
+}
+
+func (lr *LogsRouter) serveStyxRead(conn net.Conn, request *tcp.HandshakeRequest, client string) {
+
+	params := api.ConsumeParams{
+		Whence:   log.Whence(request.Whence),
+		Position: request.Position,
+		Count:    request.Count,
+		Follow:   request.Follow,
+		Filter:   request.Filter,
+	}
+
+	if params.Whence == "" {
+		params.Whence = log.SeekOrigin
+	}
+
+	err := params.Validate()
+	if err != nil {
+		logger.Debug(err)
+		rejectStyx(conn, request.Version, err)
+		return
+	}
+
+	recordFilter, err := filter.Parse(params.Filter)
+	if err != nil {
+		logger.Debug(err)
+		rejectStyx(conn, request.Version, tcp.ErrInvalidParams)
+		return
+	}
+
+	managedLog, err := lr.manager.GetLog(request.Name)
+	if err != nil {
+		logger.Debug(err)
+		rejectStyx(conn, request.Version, err)
+		return
+	}
+
+	logReader, err := managedLog.NewReader(client, params.Follow, recio.ModeManual)
+	if err != nil {
+		logger.Debug(err)
+		rejectStyx(conn, request.Version, err)
+		return
+	}
+
+	err = logReader.Seek(params.Position, params.Whence)
+	if err != nil {
+		logger.Debug(err)
+		logReader.Close()
+		rejectStyx(conn, request.Version, err)
+		return
+	}
+
+	caps := lr.negotiateCapabilities(request.Version, request.Features, request.Compression, consumeFeatures)
+
+	if recordFilter != nil && !caps.Has(tcp.FeatureMetadata) {
+		logger.Debug(errFilterWithoutMetadata)
+		logReader.Close()
+		rejectStyx(conn, request.Version, tcp.ErrInvalidParams)
+		return
+	}
+
+	err = lr.acceptStyx(conn, caps)
+	if err != nil {
+		logger.Debug(err)
+		logReader.Close()
+		conn.Close()
+		return
+	}
+
+	position, _ := logReader.Tell()
+	session := managedLog.NewSession(client, conn.RemoteAddr().String(), position)
+
+	control := newReadControl(managedLog, client, params.Follow, logReader, params.Count)
+
+	lr.serveReadTCP(conn, caps, control, session, recordFilter, request.Timeout)
+}
+
+func (lr *LogsRouter) serveStyxWrite(conn net.Conn, request *tcp.HandshakeRequest, client string) {
+
+	managedLog, err := lr.writeLog(request.Name)
+	if err != nil {
+		logger.Debug(err)
+		rejectStyx(conn, request.Version, err)
+		return
+	}
+
+	logWriter, err := managedLog.NewWriter(client, recio.ModeAuto)
+	if err != nil {
+		logger.Debug(err)
+		rejectStyx(conn, request.Version, err)
+		return
+	}
+
+	caps := lr.negotiateCapabilities(request.Version, request.Features, request.Compression, produceFeatures)
+
+	err = lr.acceptStyx(conn, caps)
+	if err != nil {
+		logger.Debug(err)
+		logWriter.Close()
+		conn.Close()
+		return
+	}
+
+	lr.serveWriteTCP(conn, caps, logWriter, request.Timeout)
+}
+
+func (lr *LogsRouter) acceptStyx(conn net.Conn, caps tcp.Capabilities) (err error) {
+
+	response := tcp.HandshakeResponse{
+		Version:     caps.Version,
+		Status:      tcp.HandshakeAccepted,
+		Timeout:     lr.currentConfig().TCPTimeout,
+		Compression: caps.Compression,
+		Features:    caps.Features,
+	}
+
+	_, err = response.WriteTo(conn)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// rejectStyx answers a handshake with an error and closes the connection.
+// The response uses the protocol version of the request, which the client
+// should retry with when it differs from the one it requested.
+func rejectStyx(conn net.Conn, version int, er error) {
+
+	response := tcp.HandshakeResponse{
+		Version: version,
+		Status:  tcp.HandshakeRejected,
+		Code:    tcp.GetErrorCode(protocolError(er)),
+	}
+
+	// Try to write the rejection back to
+	// client in case conn is still open.
+	response.WriteTo(conn)
+
+	conn.Close()
+}
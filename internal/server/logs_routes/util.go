@@ -21,6 +21,8 @@ import (
 	"strings"
 
 	"github.com/dataptive/styx/pkg/api"
+	"github.com/dataptive/styx/pkg/api/tcp"
+	"github.com/dataptive/styx/pkg/logger"
 
 	"github.com/gorilla/websocket"
 )
@@ -30,7 +32,7 @@ var (
 	ErrDataSentBeforeUpgrade = errors.New("server: client sent data before upgrade completion")
 )
 
-func UpgradeTCP(w http.ResponseWriter) (c *net.TCPConn, err error) {
+func UpgradeTCP(w http.ResponseWriter, version int) (c net.Conn, err error) {
 
 	hj, ok := w.(http.Hijacker)
 	if !ok {
@@ -53,7 +55,7 @@ func UpgradeTCP(w http.ResponseWriter) (c *net.TCPConn, err error) {
 
 	header := w.Header()
 	header.Add("Connection", "Upgrade")
-	header.Add("Upgrade", api.StyxProtocolString)
+	header.Add("Upgrade", tcp.ProtocolString(version))
 
 	resp := http.Response{
 		Status:     "101 Switching Protocols",
@@ -71,12 +73,42 @@ func UpgradeTCP(w http.ResponseWriter) (c *net.TCPConn, err error) {
 		return nil, err
 	}
 
-	tcpConn := conn.(*net.TCPConn)
+	return conn, nil
+}
+
+// setConnBuffers sizes the socket buffers of TCP connections, other
+// connections such as Unix domain sockets keep their system defaults.
+func (lr *LogsRouter) setConnBuffers(conn net.Conn) {
+
+	config := lr.currentConfig()
+
+	// Unwrap connections tracked by the server.
+	for {
+		wrapper, ok := conn.(interface{ NetConn() net.Conn })
+		if !ok {
+			break
+		}
+
+		conn = wrapper.NetConn()
+	}
 
-	return tcpConn, nil
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return
+	}
+
+	err := tcpConn.SetReadBuffer(config.TCPReadBufferSize)
+	if err != nil {
+		logger.Warn(err)
+	}
+
+	err = tcpConn.SetWriteBuffer(config.TCPWriteBufferSize)
+	if err != nil {
+		logger.Warn(err)
+	}
 }
 
-func UpgradeWebsocket(w http.ResponseWriter, r *http.Request, allowedOrigins []string, readBufferSize int, writeBufferSize int) (conn *websocket.Conn, err error) {
+func UpgradeWebsocket(w http.ResponseWriter, r *http.Request, allowedOrigins []string, readBufferSize int, writeBufferSize int, enableCompression bool) (conn *websocket.Conn, err error) {
 
 	upgrader := websocket.Upgrader{
 		CheckOrigin: func(r *http.Request) (ret bool) {
@@ -88,8 +120,9 @@ func UpgradeWebsocket(w http.ResponseWriter, r *http.Request, allowedOrigins []s
 
 			return matchOrigin(origins[0], allowedOrigins)
 		},
-		ReadBufferSize:  readBufferSize,
-		WriteBufferSize: writeBufferSize,
+		ReadBufferSize:    readBufferSize,
+		WriteBufferSize:   writeBufferSize,
+		EnableCompression: enableCompression,
 	}
 
 	conn, err = upgrader.Upgrade(w, r, nil)
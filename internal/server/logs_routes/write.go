@@ -53,26 +53,38 @@ func (lr *LogsRouter) WriteHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	managedLog, err := lr.manager.GetLog(name)
+	managedLog, err := lr.writeLog(name)
 	if err == logman.ErrNotExist {
 		api.WriteError(w, http.StatusNotFound, api.ErrLogNotFound)
 		logger.Debug(err)
 		return
 	}
 
+	if err == logman.ErrNamespaceQuota {
+		api.WriteError(w, http.StatusBadRequest, api.ErrNamespaceQuota)
+		logger.Debug(err)
+		return
+	}
+
 	if err != nil {
 		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
 		logger.Debug(err)
 		return
 	}
 
-	logWriter, err := managedLog.NewWriter(recio.ModeAuto)
+	logWriter, err := managedLog.NewWriter(clientID(r), recio.ModeAuto)
 	if err == logman.ErrUnavailable {
 		api.WriteError(w, http.StatusBadRequest, api.ErrLogNotAvailable)
 		logger.Debug(err)
 		return
 	}
 
+	if err == logman.ErrInsufficientStorage {
+		api.WriteError(w, http.StatusInsufficientStorage, api.ErrInsufficientStorage)
+		logger.Debug(err)
+		return
+	}
+
 	if err != nil {
 		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
 		logger.Debug(err)
@@ -95,6 +107,13 @@ func (lr *LogsRouter) WriteHandler(w http.ResponseWriter, r *http.Request) {
 	record := log.Record(payload)
 
 	_, err = logWriter.Write(&record)
+	if err == logman.ErrInsufficientStorage {
+		logWriter.Close()
+		api.WriteError(w, http.StatusInsufficientStorage, api.ErrInsufficientStorage)
+		logger.Debug(err)
+		return
+	}
+
 	if err != nil {
 		logWriter.Close()
 		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
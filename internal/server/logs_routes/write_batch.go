@@ -32,28 +32,53 @@ func (lr *LogsRouter) WriteBatchHandler(w http.ResponseWriter, r *http.Request)
 	vars := mux.Vars(r)
 	name := vars["name"]
 
-	managedLog, err := lr.manager.GetLog(name)
+	body, err := lr.requestBody(r)
+	if err == ErrUnsupportedEncoding {
+		api.WriteError(w, http.StatusUnsupportedMediaType, api.ErrUnsupportedEncoding)
+		logger.Debug(err)
+		return
+	}
+
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
+		logger.Debug(err)
+		return
+	}
+
+	managedLog, err := lr.writeLog(name)
 	if err == logman.ErrNotExist {
 		api.WriteError(w, http.StatusNotFound, api.ErrLogNotFound)
 		logger.Debug(err)
 		return
 	}
 
+	if err == logman.ErrNamespaceQuota {
+		api.WriteError(w, http.StatusBadRequest, api.ErrNamespaceQuota)
+		logger.Debug(err)
+		return
+	}
+
 	if err != nil {
 		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
 		logger.Debug(err)
 		return
 	}
 
-	bufferedReader := recio.NewBufferedReader(r.Body, lr.config.HTTPReadBufferSize, recio.ModeManual)
+	bufferedReader := recio.NewBufferedReader(body, lr.currentConfig().HTTPReadBufferSize, recio.ModeManual)
 
-	logWriter, err := managedLog.NewWriter(recio.ModeAuto)
+	logWriter, err := managedLog.NewWriter(clientID(r), recio.ModeAuto)
 	if err == logman.ErrUnavailable {
 		api.WriteError(w, http.StatusBadRequest, api.ErrLogNotAvailable)
 		logger.Debug(err)
 		return
 	}
 
+	if err == logman.ErrInsufficientStorage {
+		api.WriteError(w, http.StatusInsufficientStorage, api.ErrInsufficientStorage)
+		logger.Debug(err)
+		return
+	}
+
 	if err != nil {
 		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
 		logger.Debug(err)
@@ -67,6 +92,13 @@ func (lr *LogsRouter) WriteBatchHandler(w http.ResponseWriter, r *http.Request)
 	})
 
 	err = writeBatch(logWriter, bufferedReader)
+	if err == logman.ErrInsufficientStorage {
+		logWriter.Close()
+		api.WriteError(w, http.StatusInsufficientStorage, api.ErrInsufficientStorage)
+		logger.Debug(err)
+		return
+	}
+
 	if err != nil {
 		logWriter.Close()
 		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
@@ -0,0 +1,220 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs_routes
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/dataptive/styx/internal/logman"
+	"github.com/dataptive/styx/pkg/api"
+	"github.com/dataptive/styx/pkg/log"
+	"github.com/dataptive/styx/pkg/logger"
+	"github.com/dataptive/styx/pkg/recio"
+
+	"github.com/gorilla/mux"
+)
+
+var (
+	errInvalidJSONRecord = errors.New("server: invalid json record")
+)
+
+func (lr *LogsRouter) WriteJSONMatcher(r *http.Request, rm *mux.RouteMatch) (match bool) {
+
+	contentType := r.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	match = mediaType == api.RecordNDJSONMediaType || mediaType == api.RecordJSONMediaType
+
+	return match
+}
+
+func (lr *LogsRouter) WriteJSONHandler(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	name := vars["name"]
+
+	contentType := r.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		api.WriteError(w, http.StatusBadRequest, api.ErrUnknownError)
+		logger.Debug(err)
+		return
+	}
+
+	body, err := lr.requestBody(r)
+	if err == ErrUnsupportedEncoding {
+		api.WriteError(w, http.StatusUnsupportedMediaType, api.ErrUnsupportedEncoding)
+		logger.Debug(err)
+		return
+	}
+
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
+		logger.Debug(err)
+		return
+	}
+
+	managedLog, err := lr.writeLog(name)
+	if err == logman.ErrNotExist {
+		api.WriteError(w, http.StatusNotFound, api.ErrLogNotFound)
+		logger.Debug(err)
+		return
+	}
+
+	if err == logman.ErrNamespaceQuota {
+		api.WriteError(w, http.StatusBadRequest, api.ErrNamespaceQuota)
+		logger.Debug(err)
+		return
+	}
+
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
+		logger.Debug(err)
+		return
+	}
+
+	bufferedReader := bufio.NewReaderSize(body, lr.currentConfig().HTTPReadBufferSize)
+	decoder := json.NewDecoder(bufferedReader)
+
+	logWriter, err := managedLog.NewWriter(clientID(r), recio.ModeAuto)
+	if err == logman.ErrUnavailable {
+		api.WriteError(w, http.StatusBadRequest, api.ErrLogNotAvailable)
+		logger.Debug(err)
+		return
+	}
+
+	if err == logman.ErrInsufficientStorage {
+		api.WriteError(w, http.StatusInsufficientStorage, api.ErrInsufficientStorage)
+		logger.Debug(err)
+		return
+	}
+
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
+		logger.Debug(err)
+		return
+	}
+
+	var progress = log.SyncProgress{}
+
+	logWriter.HandleSync(func(syncProgress log.SyncProgress) {
+		progress = syncProgress
+	})
+
+	err = writeJSON(logWriter, decoder, mediaType == api.RecordJSONMediaType)
+	if err == errInvalidJSONRecord {
+		logWriter.Close()
+		api.WriteError(w, http.StatusBadRequest, api.ErrInvalidRecord)
+		logger.Debug(err)
+		return
+	}
+
+	if err == logman.ErrInsufficientStorage {
+		logWriter.Close()
+		api.WriteError(w, http.StatusInsufficientStorage, api.ErrInsufficientStorage)
+		logger.Debug(err)
+		return
+	}
+
+	if err != nil {
+		logWriter.Close()
+		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
+		logger.Debug(err)
+		return
+	}
+
+	err = logWriter.Flush()
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
+		logger.Debug(err)
+		return
+	}
+
+	err = logWriter.Close()
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
+		logger.Debug(err)
+		return
+	}
+
+	response := api.ProduceResponse(progress)
+
+	api.WriteResponse(w, http.StatusOK, response)
+}
+
+func writeJSON(lw *log.FaninWriter, dec *json.Decoder, array bool) (err error) {
+
+	jsonRecord := api.JSONRecord{}
+
+	if array {
+		token, err := dec.Token()
+		if err != nil {
+			return errInvalidJSONRecord
+		}
+
+		if token != json.Delim('[') {
+			return errInvalidJSONRecord
+		}
+	}
+
+	for {
+		if array && !dec.More() {
+			break
+		}
+
+		jsonRecord.Payload = nil
+		jsonRecord.PayloadBase64 = nil
+
+		err = dec.Decode(&jsonRecord)
+		if err == io.EOF && !array {
+			break
+		}
+
+		if err != nil {
+			return errInvalidJSONRecord
+		}
+
+		// Position and offset are assigned by the log and thus
+		// ignored when producing.
+		record := log.Record(jsonRecord.PayloadBase64)
+		if jsonRecord.PayloadBase64 == nil {
+			record = log.Record(jsonRecord.Payload)
+		}
+
+		_, err = lw.Write(&record)
+		if err != nil {
+			return err
+		}
+	}
+
+	if array {
+		_, err = dec.Token()
+		if err != nil {
+			return errInvalidJSONRecord
+		}
+	}
+
+	err = lw.Flush()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -16,6 +16,7 @@ package logs_routes
 
 import (
 	"io"
+	"net"
 	"net/http"
 	"strconv"
 
@@ -31,12 +32,14 @@ import (
 
 func (lr *LogsRouter) WriteTCPHandler(w http.ResponseWriter, r *http.Request) {
 
+	config := lr.currentConfig()
+
 	var err error
 
 	vars := mux.Vars(r)
 	name := vars["name"]
 
-	remoteTimeout := lr.config.TCPTimeout
+	remoteTimeout := config.TCPTimeout
 
 	// TODO: Change the header name to a more adequate one.
 	rawTimeout := r.Header.Get(api.TimeoutHeaderName)
@@ -50,52 +53,79 @@ func (lr *LogsRouter) WriteTCPHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	managedLog, err := lr.manager.GetLog(name)
+	managedLog, err := lr.writeLog(name)
 	if err == logman.ErrNotExist {
 		api.WriteError(w, http.StatusNotFound, api.ErrLogNotFound)
 		logger.Debug(err)
 		return
 	}
 
+	if err == logman.ErrNamespaceQuota {
+		api.WriteError(w, http.StatusBadRequest, api.ErrNamespaceQuota)
+		logger.Debug(err)
+		return
+	}
+
 	if err != nil {
 		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
 		logger.Debug(err)
 		return
 	}
 
-	logWriter, err := managedLog.NewWriter(recio.ModeAuto)
+	logWriter, err := managedLog.NewWriter(clientID(r), recio.ModeAuto)
 	if err == logman.ErrUnavailable {
 		api.WriteError(w, http.StatusBadRequest, api.ErrLogNotAvailable)
 		logger.Debug(err)
 		return
 	}
 
+	if err == logman.ErrInsufficientStorage {
+		api.WriteError(w, http.StatusInsufficientStorage, api.ErrInsufficientStorage)
+		logger.Debug(err)
+		return
+	}
+
 	if err != nil {
 		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
 		logger.Debug(err)
 		return
 	}
 
-	w.Header().Add(api.TimeoutHeaderName, strconv.Itoa(lr.config.TCPTimeout))
+	caps := lr.upgradeCapabilities(w, r, produceFeatures)
 
-	conn, err := UpgradeTCP(w)
+	w.Header().Add(api.TimeoutHeaderName, strconv.Itoa(config.TCPTimeout))
+
+	conn, err := UpgradeTCP(w, caps.Version)
 	if err != nil {
 		logger.Debug(err)
 		logWriter.Close()
 		return
 	}
 
-	err = conn.SetReadBuffer(lr.config.TCPReadBufferSize)
-	if err != nil {
-		logger.Warn(err)
-	}
+	lr.serveWriteTCP(conn, caps, logWriter, remoteTimeout)
+}
+
+// serveWriteTCP appends records received on a styx protocol connection once
+// the handshake completed, and closes both the log writer and the connection.
+func (lr *LogsRouter) serveWriteTCP(conn net.Conn, caps tcp.Capabilities, logWriter *log.FaninWriter, remoteTimeout int) {
+
+	var err error
+
+	lr.setConnBuffers(conn)
 
-	err = conn.SetWriteBuffer(lr.config.TCPWriteBufferSize)
+	compressedConn, err := compressConn(conn, caps.Compression)
 	if err != nil {
-		logger.Warn(err)
+		logger.Debug(err)
+		logWriter.Close()
+		conn.Close()
+		return
 	}
 
-	tr := tcp.NewTCPReader(conn, lr.config.TCPWriteBufferSize, lr.config.TCPReadBufferSize, lr.config.TCPTimeout, remoteTimeout, recio.ModeManual)
+	conn = compressedConn
+
+	config := lr.currentConfig()
+
+	tr := tcp.NewTCPReader(conn, config.TCPWriteBufferSize, config.TCPReadBufferSize, config.TCPTimeout, remoteTimeout, recio.ModeManual)
 
 	tr.HandleError(func(err error) {
 		logger.Debug(err)
@@ -147,7 +177,7 @@ func (lr *LogsRouter) WriteTCPHandler(w http.ResponseWriter, r *http.Request) {
 
 		// Send error to the client to give it
 		// a chance to close gracefully.
-		tr.WriteError(err)
+		tr.WriteError(protocolError(err))
 		tr.Flush()
 
 		// Finaly close tcp conn.
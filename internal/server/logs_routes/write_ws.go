@@ -34,33 +34,47 @@ func (lr *LogsRouter) WriteWSHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	name := vars["name"]
 
-	managedLog, err := lr.manager.GetLog(name)
+	managedLog, err := lr.writeLog(name)
 	if err == logman.ErrNotExist {
 		api.WriteError(w, http.StatusNotFound, api.ErrLogNotFound)
 		logger.Debug(err)
 		return
 	}
 
+	if err == logman.ErrNamespaceQuota {
+		api.WriteError(w, http.StatusBadRequest, api.ErrNamespaceQuota)
+		logger.Debug(err)
+		return
+	}
+
 	if err != nil {
 		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
 		logger.Debug(err)
 		return
 	}
 
-	logWriter, err := managedLog.NewWriter(recio.ModeAuto)
+	logWriter, err := managedLog.NewWriter(clientID(r), recio.ModeAuto)
 	if err == logman.ErrUnavailable {
 		api.WriteError(w, http.StatusBadRequest, api.ErrLogNotAvailable)
 		logger.Debug(err)
 		return
 	}
 
+	if err == logman.ErrInsufficientStorage {
+		api.WriteError(w, http.StatusInsufficientStorage, api.ErrInsufficientStorage)
+		logger.Debug(err)
+		return
+	}
+
 	if err != nil {
 		api.WriteError(w, http.StatusInternalServerError, api.ErrUnknownError)
 		logger.Debug(err)
 		return
 	}
 
-	conn, err := UpgradeWebsocket(w, r, lr.config.CORSAllowedOrigins, lr.config.WSReadBufferSize, lr.config.WSWriteBufferSize)
+	config := lr.currentConfig()
+
+	conn, err := UpgradeWebsocket(w, r, config.CORSAllowedOrigins, config.WSReadBufferSize, config.WSWriteBufferSize, config.WSCompression)
 	if err != nil {
 		logger.Debug(err)
 
@@ -69,6 +83,20 @@ func (lr *LogsRouter) WriteWSHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	err = writeWS(logWriter, conn)
+	if err == logman.ErrInsufficientStorage {
+		logger.Debug(err)
+
+		logWriter.Close()
+
+		// Let the client know why writes stopped
+		// before closing.
+		message := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, api.ErrInsufficientStorage.Message)
+		conn.WriteMessage(websocket.CloseMessage, message)
+
+		conn.Close()
+		return
+	}
+
 	if err != nil {
 		logger.Debug(err)
 
@@ -15,24 +15,42 @@
 package server
 
 import (
+	"net"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/dataptive/styx/internal/logman"
+	"github.com/dataptive/styx/internal/server/auth"
 	"github.com/dataptive/styx/internal/server/config"
 	"github.com/dataptive/styx/internal/server/logs_routes"
 	"github.com/dataptive/styx/pkg/api"
+	"github.com/dataptive/styx/pkg/logger"
 
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/cors"
 )
 
+// Reloader reloads the server config, returning the changed settings which
+// only apply on restart.
+type Reloader interface {
+	Reload() (restartRequired []string, err error)
+}
+
 type Router struct {
-	router http.Handler
-	config config.Config
+	router     http.Handler
+	logsRouter *logs_routes.LogsRouter
+	logManager *logman.LogManager
+	reloader   Reloader
+	config     config.Config
+	cors       *cors.Cors
+	configLock sync.RWMutex
+	conns      connCounter
+	startTime  time.Time
 }
 
-func NewRouter(logManager *logman.LogManager, config config.Config) (r *Router) {
+func NewRouter(logManager *logman.LogManager, config config.Config, authorizer *auth.Authorizer, reloader Reloader) (r *Router) {
 
 	router := mux.NewRouter()
 
@@ -40,25 +58,68 @@ func NewRouter(logManager *logman.LogManager, config config.Config) (r *Router)
 	router.MethodNotAllowedHandler = http.HandlerFunc(methodNotAllowedHandler)
 
 	r = &Router{
-		router: router,
-		config: config,
+		router:     router,
+		logManager: logManager,
+		reloader:   reloader,
+		config:     config,
+		cors:       newCORS(config),
+		startTime:  time.Now(),
 	}
 
-	logs_routes.RegisterRoutes(router.PathPrefix("/logs").Subrouter(), logManager, config)
+	r.logsRouter = logs_routes.RegisterRoutes(router.PathPrefix("/logs").Subrouter(), logManager, config, authorizer)
+	r.logsRouter.RegisterNamespaceRoutes(router.PathPrefix("/namespaces").Subrouter())
+	r.logsRouter.RegisterEventRoutes(router.PathPrefix("/events").Subrouter())
 
 	router.Handle("/metrics", promhttp.Handler())
+	router.HandleFunc("/storage", r.storageHandler).Methods(http.MethodGet)
+	router.HandleFunc("/reload", r.logsRouter.AuthorizeServer(auth.PermissionAdmin, r.reloadHandler)).Methods(http.MethodPost)
+	router.HandleFunc("/healthz", r.healthHandler).Methods(http.MethodGet, http.MethodHead)
+	router.HandleFunc("/readyz", r.readyHandler).Methods(http.MethodGet, http.MethodHead)
+	router.HandleFunc("/status", r.statusHandler).Methods(http.MethodGet)
+
+	router.Use(r.corsHandler)
 
-	c := cors.New(cors.Options{
-		AllowedOrigins:   r.config.CORSAllowedOrigins,
+	return r
+}
+
+func newCORS(config config.Config) (c *cors.Cors) {
+
+	c = cors.New(cors.Options{
+		AllowedOrigins:   config.CORSAllowedOrigins,
 		AllowedMethods:   []string{"GET", "HEAD", "POST", "PUT", "DELETE", "PATCH"},
 		AllowedHeaders:   []string{},
 		AllowCredentials: false,
 		MaxAge:           0,
 	})
 
-	router.Use(c.Handler)
+	return c
+}
 
-	return r
+// Reload applies the settings of config which may change while running, to
+// the requests and connections accepted from then on.
+func (r *Router) Reload(config config.Config, authorizer *auth.Authorizer) {
+
+	r.configLock.Lock()
+	r.config = config
+	r.cors = newCORS(config)
+	r.configLock.Unlock()
+
+	r.logsRouter.Reload(config, authorizer)
+}
+
+// corsHandler applies the CORS policy of the current config.
+func (r *Router) corsHandler(next http.Handler) (h http.Handler) {
+
+	h = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+
+		r.configLock.RLock()
+		c := r.cors
+		r.configLock.RUnlock()
+
+		c.ServeHTTP(w, req, next.ServeHTTP)
+	})
+
+	return h
 }
 
 func (r *Router) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
@@ -66,6 +127,42 @@ func (r *Router) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	r.router.ServeHTTP(rw, req)
 }
 
+// TrackListener wraps listener for the connections it accepts to be counted
+// in the server status.
+func (r *Router) TrackListener(listener net.Listener) (l net.Listener) {
+
+	return r.conns.track(listener)
+}
+
+// ServeStyx serves a connection accepted by the raw styx protocol listener.
+func (r *Router) ServeStyx(conn net.Conn) {
+
+	r.logsRouter.ServeStyx(conn)
+}
+
+func (r *Router) storageHandler(w http.ResponseWriter, req *http.Request) {
+
+	storageInfo := r.logManager.Storage()
+
+	api.WriteResponse(w, http.StatusOK, api.StorageInfo(storageInfo))
+}
+
+func (r *Router) reloadHandler(w http.ResponseWriter, req *http.Request) {
+
+	restartRequired, err := r.reloader.Reload()
+	if err != nil {
+		api.WriteError(w, http.StatusBadRequest, api.ErrInvalidConfig)
+		logger.Debug(err)
+		return
+	}
+
+	response := api.ReloadResponse{
+		RestartRequired: restartRequired,
+	}
+
+	api.WriteResponse(w, http.StatusOK, response)
+}
+
 // TODO: Panic handler?
 
 func notFoundHandler(w http.ResponseWriter, r *http.Request) {
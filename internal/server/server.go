@@ -16,16 +16,20 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/dataptive/styx/internal/logman"
 	"github.com/dataptive/styx/internal/metrics"
+	"github.com/dataptive/styx/internal/server/auth"
 	"github.com/dataptive/styx/internal/server/config"
 	"github.com/dataptive/styx/pkg/lockfile"
 	"github.com/dataptive/styx/pkg/logger"
@@ -39,18 +43,26 @@ var (
 	ErrShutdownTimedOut = errors.New("server: shutdown timeout exceeded")
 )
 
+// Server runs Styx from the config loaded from configPath, which is loaded
+// again on reload.
 type Server struct {
-	config  config.Config
-	pidFile *lockfile.LockFile
+	config          config.Config
+	configPath      string
+	pidFile         *lockfile.LockFile
+	metricsReporter *metrics.MetricsReporter
+	logManager      *logman.LogManager
+	router          *Router
+	reloadLock      sync.Mutex
 }
 
-func NewServer(config config.Config) (s *Server, err error) {
+func NewServer(config config.Config, configPath string) (s *Server, err error) {
 
 	pidFile := lockfile.New(config.PIDFile, os.FileMode(0644))
 
 	s = &Server{
-		config:  config,
-		pidFile: pidFile,
+		config:     config,
+		configPath: configPath,
+		pidFile:    pidFile,
 	}
 
 	return s, nil
@@ -86,13 +98,76 @@ func (s *Server) Run() (err error) {
 		return err
 	}
 
-	router := NewRouter(logManager, s.config)
+	var authorizer *auth.Authorizer
+
+	if s.config.Auth != nil {
+
+		authorizer, err = auth.NewAuthorizer(s.config.Auth)
+		if err != nil {
+			return err
+		}
+	}
+
+	router := NewRouter(logManager, s.config, authorizer, s)
+
+	s.metricsReporter = metricsReporter
+	s.logManager = logManager
+	s.router = router
 
 	server := &http.Server{
 		Addr:    s.config.BindAddress,
 		Handler: router,
 	}
 
+	if s.config.TLS != nil {
+
+		server.TLSConfig, err = newTLSConfig(s.config.TLS)
+		if err != nil {
+			return err
+		}
+	}
+
+	var styxListener net.Listener
+
+	if s.config.StyxBindAddress != "" {
+
+		styxListener, err = net.Listen("tcp", s.config.StyxBindAddress)
+		if err != nil {
+			return err
+		}
+
+		styxListener = router.TrackListener(styxListener)
+
+		if server.TLSConfig != nil {
+			styxListener = tls.NewListener(styxListener, server.TLSConfig)
+		}
+
+		logger.Infof("server: listening for Styx protocol connections on %s", s.config.StyxBindAddress)
+
+		go s.serveStyx(styxListener, router)
+	}
+
+	if s.config.UnixSocketPath != "" {
+
+		unixListener, err := s.listenUnix()
+		if err != nil {
+			return err
+		}
+
+		logger.Infof("server: listening for client connections on %s", s.config.UnixSocketPath)
+
+		go func() {
+			// Unix domain sockets are local and served without TLS,
+			// Shutdown closes the listener, which removes the socket file.
+			err := server.Serve(router.TrackListener(unixListener))
+			if err != nil && err != http.ErrServerClosed {
+				logger.Error(err)
+			}
+		}()
+	}
+
+	go s.handleReloadSignal()
+
 	done := make(chan struct{})
 
 	go func() {
@@ -114,6 +189,13 @@ func (s *Server) Run() (err error) {
 			logger.Fatal(err)
 		}
 
+		if styxListener != nil {
+			err = styxListener.Close()
+			if err != nil {
+				logger.Warn(err)
+			}
+		}
+
 		err = server.Shutdown(ctx)
 		if err != nil {
 
@@ -143,9 +225,20 @@ func (s *Server) Run() (err error) {
 		done <- struct{}{}
 	}()
 
+	listener, err := net.Listen("tcp", s.config.BindAddress)
+	if err != nil {
+		return err
+	}
+
 	logger.Infof("server: listening for client connections on %s", s.config.BindAddress)
 
-	err = server.ListenAndServe()
+	if server.TLSConfig != nil {
+		// Certificates are already loaded in TLSConfig.
+		err = server.ServeTLS(router.TrackListener(listener), "", "")
+	} else {
+		err = server.Serve(router.TrackListener(listener))
+	}
+
 	if err != nil && err != http.ErrServerClosed {
 		logger.Error(err)
 		return err
@@ -156,6 +249,118 @@ func (s *Server) Run() (err error) {
 	return nil
 }
 
+// Reload loads the config file again and applies the settings which may
+// change while running: CORS origins, buffer sizes and timeouts of new
+// connections, compression, metrics reporters, auth, log templates and
+// readiness rules. It returns the changed settings which only apply on
+// restart. An invalid config is rejected without changing the running server.
+func (s *Server) Reload() (restartRequired []string, err error) {
+
+	s.reloadLock.Lock()
+	defer s.reloadLock.Unlock()
+
+	logger.Infof("server: reloading config from %s", s.configPath)
+
+	c, err := config.Load(s.configPath)
+	if err != nil {
+		logger.Warnf("server: rejected config reload (%s)", err)
+		return nil, err
+	}
+
+	var authorizer *auth.Authorizer
+
+	if c.Auth != nil {
+
+		authorizer, err = auth.NewAuthorizer(c.Auth)
+		if err != nil {
+			logger.Warnf("server: rejected config reload (%s)", err)
+			return nil, err
+		}
+	}
+
+	err = s.metricsReporter.Reload(c.Metrics)
+	if err != nil {
+		logger.Warnf("server: rejected config reload (%s)", err)
+		return nil, err
+	}
+
+	s.logManager.SetTemplates(c.LogManager.Templates, c.LogManager.DefaultTemplate)
+
+	s.router.Reload(c, authorizer)
+
+	// Compare against the config the server was started with, for
+	// settings changed by a previous reload to be reported until restart.
+	restartRequired = config.RestartRequired(s.config, c)
+
+	for _, setting := range restartRequired {
+		logger.Warnf("server: %s changed, restart required to apply", setting)
+	}
+
+	return restartRequired, nil
+}
+
+// handleReloadSignal reloads the config on SIGHUP.
+func (s *Server) handleReloadSignal() {
+
+	signalChan := make(chan os.Signal, 1)
+
+	signal.Notify(signalChan, syscall.SIGHUP)
+
+	for range signalChan {
+		s.Reload()
+	}
+}
+
+func (s *Server) listenUnix() (listener net.Listener, err error) {
+
+	// Remove the socket file left behind by a crashed server, the
+	// execution lock ensures no other server is still using it.
+	fileInfo, err := os.Stat(s.config.UnixSocketPath)
+	if err == nil && fileInfo.Mode()&os.ModeSocket != 0 {
+
+		err = os.Remove(s.config.UnixSocketPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	listener, err = net.Listen("unix", s.config.UnixSocketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.config.UnixSocketMode != 0 {
+
+		err = os.Chmod(s.config.UnixSocketPath, s.config.UnixSocketMode)
+		if err != nil {
+			listener.Close()
+			return nil, err
+		}
+	}
+
+	return listener, nil
+}
+
+func (s *Server) serveStyx(listener net.Listener, router *Router) {
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+
+			netErr, ok := err.(net.Error)
+			if ok && netErr.Temporary() {
+				logger.Debug(err)
+				continue
+			}
+
+			// Listener was closed on shutdown.
+			return
+		}
+
+		go router.ServeStyx(conn)
+	}
+}
+
 func (s *Server) acquireExecutionLock() (err error) {
 
 	err = s.pidFile.Acquire()
@@ -0,0 +1,60 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+
+	"github.com/dataptive/styx/internal/server/config"
+)
+
+var (
+	ErrInvalidClientCA = errors.New("server: no certificate found in client CA file")
+)
+
+func newTLSConfig(c *config.TLSConfig) (tlsConfig *tls.Config, err error) {
+
+	certificate, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig = &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		ClientAuth:   c.ClientAuth,
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if c.ClientCAFile != "" {
+
+		pem, err := ioutil.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		clientCAs := x509.NewCertPool()
+
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return nil, ErrInvalidClientCA
+		}
+
+		tlsConfig.ClientCAs = clientCAs
+	}
+
+	return tlsConfig, nil
+}
@@ -28,6 +28,17 @@ var (
 	logNotAvailableErrorCode  = "log_not_available"
 	logInvalidNameCode        = "log_invalid_name"
 	missingLengthErrorCode    = "missing_content_length"
+	invalidRecordErrorCode    = "invalid_record"
+	unauthorizedErrorCode     = "unauthorized"
+	forbiddenErrorCode        = "forbidden"
+	insufficientStorageCode   = "insufficient_storage"
+	unsupportedEncodingCode   = "unsupported_encoding"
+	outOfRangeErrorCode       = "out_of_range"
+	namespaceNotFoundCode     = "namespace_not_found"
+	nameConflictCode          = "name_conflict"
+	namespaceQuotaCode        = "namespace_quota_exceeded"
+	invalidLabelCode          = "invalid_label"
+	invalidConfigCode         = "invalid_config"
 
 	defaultErrorMessage          = "api: unknown error"
 	methodNotAllowedErrorMessage = "api: method not allowed"
@@ -37,6 +48,17 @@ var (
 	logNotAvailableErrorMessage  = "api: log not available"
 	logInvalidNameMessage        = "api: log name invalid"
 	missingLengthErrorMessage    = "api: missing content-length"
+	invalidRecordErrorMessage    = "api: invalid record"
+	unauthorizedErrorMessage     = "api: unauthorized"
+	forbiddenErrorMessage        = "api: forbidden"
+	insufficientStorageMessage   = "api: insufficient storage"
+	unsupportedEncodingMessage   = "api: unsupported content encoding"
+	outOfRangeErrorMessage       = "api: position out of range"
+	namespaceNotFoundMessage     = "api: namespace not found"
+	nameConflictMessage          = "api: name conflicts with a log or namespace"
+	namespaceQuotaMessage        = "api: namespace quota exceeded"
+	invalidLabelMessage          = "api: invalid label"
+	invalidConfigMessage         = "api: invalid config"
 
 	ErrUnknownError         = NewError(defaultErrorCode, defaultErrorMessage)
 	ErrMethodNotAllowed     = NewError(methodNotAllowedErrorCode, methodNotAllowedErrorMessage)
@@ -46,6 +68,17 @@ var (
 	ErrLogNotAvailable      = NewError(logNotAvailableErrorCode, logNotAvailableErrorMessage)
 	ErrLogInvalidName       = NewError(logInvalidNameCode, logInvalidNameMessage)
 	ErrMissingContentLength = NewError(missingLengthErrorCode, missingLengthErrorMessage)
+	ErrInvalidRecord        = NewError(invalidRecordErrorCode, invalidRecordErrorMessage)
+	ErrUnauthorized         = NewError(unauthorizedErrorCode, unauthorizedErrorMessage)
+	ErrForbidden            = NewError(forbiddenErrorCode, forbiddenErrorMessage)
+	ErrInsufficientStorage  = NewError(insufficientStorageCode, insufficientStorageMessage)
+	ErrUnsupportedEncoding  = NewError(unsupportedEncodingCode, unsupportedEncodingMessage)
+	ErrOutOfRange           = NewError(outOfRangeErrorCode, outOfRangeErrorMessage)
+	ErrNamespaceNotFound    = NewError(namespaceNotFoundCode, namespaceNotFoundMessage)
+	ErrNameConflict         = NewError(nameConflictCode, nameConflictMessage)
+	ErrNamespaceQuota       = NewError(namespaceQuotaCode, namespaceQuotaMessage)
+	ErrInvalidLabel         = NewError(invalidLabelCode, invalidLabelMessage)
+	ErrInvalidConfig        = NewError(invalidConfigCode, invalidConfigMessage)
 )
 
 type Error struct {
@@ -0,0 +1,175 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcp
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/dataptive/styx/pkg/log"
+	"github.com/dataptive/styx/pkg/recio"
+)
+
+const (
+	// MaxBatchSize is the maximum size of the records carried by a batch
+	// message. Records larger than a batch are sent in record messages.
+	MaxBatchSize = 64 << 10 // 64 KB
+
+	batchHeaderSize = 4 + 4
+)
+
+var (
+	ErrInvalidBatch = errors.New("tcp: invalid batch message")
+)
+
+// BatchMessage carries several records under a single message. Records are
+// encoded back to back as they would be in record messages, and decoded
+// records point into the read buffer, as with record messages.
+type BatchMessage struct {
+	Count   int
+	Records []byte
+	offset  int
+	read    int
+}
+
+// newBatchMessage returns a batch message able to hold size bytes of
+// encoded records.
+func newBatchMessage(size int) (bm *BatchMessage) {
+
+	bm = &BatchMessage{
+		Records: make([]byte, 0, size),
+	}
+
+	return bm
+}
+
+func (bm *BatchMessage) Encode(p []byte) (n int, err error) {
+
+	if len(p) < batchHeaderSize+len(bm.Records) {
+		return 0, recio.ErrShortBuffer
+	}
+
+	binary.BigEndian.PutUint32(p, uint32(bm.Count))
+	n = 4
+
+	binary.BigEndian.PutUint32(p[n:], uint32(len(bm.Records)))
+	n += 4
+
+	n += copy(p[n:], bm.Records)
+
+	return n, nil
+}
+
+func (bm *BatchMessage) Decode(p []byte) (n int, err error) {
+
+	if len(p) < batchHeaderSize {
+		return 0, recio.ErrShortBuffer
+	}
+
+	count := int(binary.BigEndian.Uint32(p[:4]))
+	n = 4
+
+	size := int(binary.BigEndian.Uint32(p[n : n+4]))
+	n += 4
+
+	if size < 0 || count < 0 {
+		return 0, ErrInvalidBatch
+	}
+
+	if len(p) < n+size {
+		return 0, recio.ErrShortBuffer
+	}
+
+	bm.Count = count
+	bm.Records = p[n : n+size]
+	bm.offset = 0
+	bm.read = 0
+
+	n += size
+
+	return n, nil
+}
+
+// Append encodes r at the end of the batch. It returns false when the
+// record doesn't fit in the space left.
+func (bm *BatchMessage) Append(r *log.Record) (ok bool) {
+
+	size := len(bm.Records)
+
+	if size+r.Size() > cap(bm.Records) {
+		return false
+	}
+
+	n, err := r.Encode(bm.Records[size:cap(bm.Records)])
+	if err != nil {
+		return false
+	}
+
+	bm.Records = bm.Records[:size+n]
+	bm.Count++
+
+	return true
+}
+
+// batchSize returns the size of the batches sent through a write buffer of
+// bufferSize bytes, leaving room for the message headers.
+func batchSize(bufferSize int) (size int) {
+
+	size = bufferSize - 2 - batchHeaderSize
+
+	if size > MaxBatchSize {
+		size = MaxBatchSize
+	}
+
+	if size < 0 {
+		size = 0
+	}
+
+	return size
+}
+
+// Next decodes the next record of the batch into r. It returns io.EOF once
+// all the records were read.
+func (bm *BatchMessage) Next(r *log.Record) (n int, err error) {
+
+	if bm.offset == len(bm.Records) {
+
+		if bm.read != bm.Count {
+			return 0, ErrInvalidBatch
+		}
+
+		return 0, io.EOF
+	}
+
+	n, err = r.Decode(bm.Records[bm.offset:])
+	if err != nil {
+		return 0, ErrInvalidBatch
+	}
+
+	bm.offset += n
+	bm.read++
+
+	return n, nil
+}
+
+// Reset empties the batch.
+func (bm *BatchMessage) Reset() {
+
+	bm.Count = 0
+	bm.Records = bm.Records[:0]
+	bm.offset = 0
+	bm.read = 0
+}
@@ -0,0 +1,96 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcp
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/dataptive/styx/pkg/recio"
+)
+
+// Control actions carried by control messages.
+const (
+	ControlSeek   = iota + 1 // Move the stream to Position relative to Whence.
+	ControlPause             // Stop sending records until resumed.
+	ControlResume            // Send records again after a pause.
+	ControlCount             // Send Count more records before ending the stream, -1 for unlimited.
+)
+
+var (
+	// errDropped is returned internally for records sent before a seek
+	// was applied.
+	errDropped = errors.New("tcp: record dropped")
+)
+
+// ControlMessage is sent by a consumer to act on the record stream. The
+// server echoes seek messages with the absolute position the stream moved
+// to, right before the first record read from it.
+type ControlMessage struct {
+	Action   int
+	Whence   string
+	Position int64
+	Count    int64
+}
+
+func (cm *ControlMessage) Encode(p []byte) (n int, err error) {
+
+	if len(p) < 2+8+8 {
+		return 0, recio.ErrShortBuffer
+	}
+
+	binary.BigEndian.PutUint16(p, uint16(cm.Action))
+	n = 2
+
+	binary.BigEndian.PutUint64(p[n:], uint64(cm.Position))
+	n += 8
+
+	binary.BigEndian.PutUint64(p[n:], uint64(cm.Count))
+	n += 8
+
+	nn, err := encodeString(p[n:], cm.Whence)
+	if err != nil {
+		return 0, err
+	}
+
+	n += nn
+
+	return n, nil
+}
+
+func (cm *ControlMessage) Decode(p []byte) (n int, err error) {
+
+	if len(p) < 2+8+8 {
+		return 0, recio.ErrShortBuffer
+	}
+
+	cm.Action = int(binary.BigEndian.Uint16(p[:2]))
+	n = 2
+
+	cm.Position = int64(binary.BigEndian.Uint64(p[n : n+8]))
+	n += 8
+
+	cm.Count = int64(binary.BigEndian.Uint64(p[n : n+8]))
+	n += 8
+
+	nn, err := decodeString(p[n:], &cm.Whence)
+	if err != nil {
+		return 0, err
+	}
+
+	n += nn
+
+	return n, nil
+}
@@ -0,0 +1,166 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcp
+
+import (
+	"encoding/binary"
+	"sync"
+
+	"github.com/dataptive/styx/pkg/recio"
+)
+
+// CreditMessage is sent by the receiving side of a flow controlled stream to
+// allow its peer to send more records. Records and bytes are added to the
+// credits previously granted.
+type CreditMessage struct {
+	Records int64
+	Bytes   int64
+}
+
+func (cm *CreditMessage) Encode(p []byte) (n int, err error) {
+
+	if len(p) < 8+8 {
+		return 0, recio.ErrShortBuffer
+	}
+
+	binary.BigEndian.PutUint64(p, uint64(cm.Records))
+	n = 8
+
+	binary.BigEndian.PutUint64(p[n:], uint64(cm.Bytes))
+	n += 8
+
+	return n, nil
+}
+
+func (cm *CreditMessage) Decode(p []byte) (n int, err error) {
+
+	if len(p) < 8+8 {
+		return 0, recio.ErrShortBuffer
+	}
+
+	cm.Records = int64(binary.BigEndian.Uint64(p[:8]))
+	n = 8
+
+	cm.Bytes = int64(binary.BigEndian.Uint64(p[n : n+8]))
+	n += 8
+
+	return n, nil
+}
+
+// Credits accounts for the records and bytes a receiver allows its peer to
+// send. A kind of credit is only enforced once the receiver granted some,
+// and nothing can be sent before the first grant. Byte credits may be
+// overdrawn by a single record so that records larger than the window can
+// still be delivered.
+type Credits struct {
+	records      int64
+	bytes        int64
+	limitRecords bool
+	limitBytes   bool
+	closed       bool
+	lock         sync.Mutex
+	cond         *sync.Cond
+}
+
+func NewCredits() (c *Credits) {
+
+	c = &Credits{}
+	c.cond = sync.NewCond(&c.lock)
+
+	return c
+}
+
+// Grant adds records and bytes to the available credits and wakes up a
+// sender waiting for them. Negative values are ignored.
+func (c *Credits) Grant(records int64, bytes int64) {
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if records > 0 {
+		c.records += records
+		c.limitRecords = true
+	}
+
+	if bytes > 0 {
+		c.bytes += bytes
+		c.limitBytes = true
+	}
+
+	c.cond.Broadcast()
+}
+
+// Available reports whether a record can be sent without waiting.
+func (c *Credits) Available() (ok bool) {
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.available()
+}
+
+// Acquire waits until a record of size bytes can be sent and spends the
+// matching credits. It returns ErrClosed once the credits are closed.
+func (c *Credits) Acquire(size int) (err error) {
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for !c.closed && !c.available() {
+		c.cond.Wait()
+	}
+
+	if c.closed {
+		return ErrClosed
+	}
+
+	if c.limitRecords {
+		c.records--
+	}
+
+	if c.limitBytes {
+		c.bytes -= int64(size)
+	}
+
+	return nil
+}
+
+// Close unblocks senders waiting in Acquire.
+func (c *Credits) Close() {
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.closed = true
+
+	c.cond.Broadcast()
+}
+
+func (c *Credits) available() (ok bool) {
+
+	if !c.limitRecords && !c.limitBytes {
+		return false
+	}
+
+	if c.limitRecords && c.records <= 0 {
+		return false
+	}
+
+	if c.limitBytes && c.bytes <= 0 {
+		return false
+	}
+
+	return true
+}
@@ -16,23 +16,100 @@ package tcp
 
 import (
 	"errors"
+
+	"github.com/dataptive/styx/pkg/log"
+	"github.com/dataptive/styx/pkg/recio"
+)
+
+// Error codes sent in error messages and handshake rejections. Codes are
+// part of the protocol and must never be renumbered.
+const (
+	CodeUnknownError       = 0
+	CodeLogNotFound        = 1
+	CodeLogUnavailable     = 2
+	CodeRecordTooLarge     = 3
+	CodeLagging            = 4
+	CodeOutOfRange         = 5
+	CodeLogClosed          = 6
+	CodeTimeout            = 7
+	CodeQuotaExceeded      = 8
+	CodeUnauthorized       = 9
+	CodeForbidden          = 10
+	CodeInvalidParams      = 11
+	CodeInvalidHandshake   = 12
+	CodeUnsupportedVersion = 13
+	CodeInvalidDirection   = 14
+	CodeUnexpectedMessage  = 15
 )
 
 var (
-	ErrUnknownError = errors.New("tcp: unknown error")
+	ErrUnknownError   = errors.New("tcp: unknown error")
+	ErrLogNotFound    = errors.New("tcp: log not found")
+	ErrLogUnavailable = errors.New("tcp: log unavailable")
+	ErrRecordTooLarge = errors.New("tcp: record too large")
+	ErrLagging        = errors.New("tcp: lagging")
+	ErrOutOfRange     = errors.New("tcp: position out of range")
+	ErrLogClosed      = errors.New("tcp: log closed")
+	ErrTimeout        = errors.New("tcp: timeout")
+	ErrQuotaExceeded  = errors.New("tcp: quota exceeded")
+	ErrUnauthorized   = errors.New("tcp: unauthorized")
+	ErrForbidden      = errors.New("tcp: forbidden")
+	ErrInvalidParams  = errors.New("tcp: invalid params")
 
-	defaultErrorCode    = 0
+	defaultErrorCode    = CodeUnknownError
 	defaultErrorMessage = ErrUnknownError
 
 	errorsCodes = map[error]int{
-		// TODO: add tcp error codes.
+		ErrUnknownError:          CodeUnknownError,
+		ErrLogNotFound:           CodeLogNotFound,
+		ErrLogUnavailable:        CodeLogUnavailable,
+		ErrRecordTooLarge:        CodeRecordTooLarge,
+		ErrLagging:               CodeLagging,
+		ErrOutOfRange:            CodeOutOfRange,
+		ErrLogClosed:             CodeLogClosed,
+		ErrTimeout:               CodeTimeout,
+		ErrQuotaExceeded:         CodeQuotaExceeded,
+		ErrUnauthorized:          CodeUnauthorized,
+		ErrForbidden:             CodeForbidden,
+		ErrInvalidParams:         CodeInvalidParams,
+		ErrInvalidHandshake:      CodeInvalidHandshake,
+		ErrHandshakeTooLong:      CodeInvalidHandshake,
+		ErrUnsupportedVersion:    CodeUnsupportedVersion,
+		ErrInvalidDirection:      CodeInvalidDirection,
+		ErrUnkownMessageType:     CodeUnexpectedMessage,
+		ErrUnexpectedMessageType: CodeUnexpectedMessage,
+
+		log.ErrNotExist:       CodeLogNotFound,
+		log.ErrRecordTooLarge: CodeRecordTooLarge,
+		log.ErrLagging:        CodeLagging,
+		log.ErrOutOfRange:     CodeOutOfRange,
+		log.ErrClosed:         CodeLogClosed,
+		log.ErrTimeout:        CodeTimeout,
+		recio.ErrTooLarge:     CodeRecordTooLarge,
 	}
 
 	errorsMessages = map[int]error{
-		// TODO: add tcp error messages.
+		CodeUnknownError:       ErrUnknownError,
+		CodeLogNotFound:        ErrLogNotFound,
+		CodeLogUnavailable:     ErrLogUnavailable,
+		CodeRecordTooLarge:     ErrRecordTooLarge,
+		CodeLagging:            ErrLagging,
+		CodeOutOfRange:         ErrOutOfRange,
+		CodeLogClosed:          ErrLogClosed,
+		CodeTimeout:            ErrTimeout,
+		CodeQuotaExceeded:      ErrQuotaExceeded,
+		CodeUnauthorized:       ErrUnauthorized,
+		CodeForbidden:          ErrForbidden,
+		CodeInvalidParams:      ErrInvalidParams,
+		CodeInvalidHandshake:   ErrInvalidHandshake,
+		CodeUnsupportedVersion: ErrUnsupportedVersion,
+		CodeInvalidDirection:   ErrInvalidDirection,
+		CodeUnexpectedMessage:  ErrUnexpectedMessageType,
 	}
 )
 
+// GetErrorCode returns the protocol error code matching err, falling back
+// to CodeUnknownError for errors outside of the protocol taxonomy.
 func GetErrorCode(err error) (code int) {
 
 	code, ok := errorsCodes[err]
@@ -43,6 +120,8 @@ func GetErrorCode(err error) (code int) {
 	return code
 }
 
+// GetErrorMessage returns the error matching a protocol error code, falling
+// back to ErrUnknownError for codes it does not know about.
 func GetErrorMessage(code int) (err error) {
 
 	err, ok := errorsMessages[code]
@@ -52,3 +131,16 @@ func GetErrorMessage(code int) (err error) {
 
 	return err
 }
+
+// IsTemporary reports whether err is a protocol error that may go away when
+// retrying the same operation later, such as an unavailable log or an
+// exhausted storage quota.
+func IsTemporary(err error) (temporary bool) {
+
+	switch err {
+	case ErrLogUnavailable, ErrTimeout, ErrQuotaExceeded:
+		return true
+	}
+
+	return false
+}
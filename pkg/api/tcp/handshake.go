@@ -0,0 +1,447 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcp
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/dataptive/styx/pkg/recio"
+)
+
+const (
+	HandshakeMagic = "STYX"
+
+	handshakeHeaderSize   = 4 + 1 + 2
+	handshakeResponseSize = 4 + 1 + 1 + 2 + 4
+)
+
+const (
+	DirectionRead  = iota // Server sends records to the client.
+	DirectionWrite        // Client sends records to the server.
+)
+
+const (
+	HandshakeAccepted = iota
+	HandshakeRejected
+)
+
+var (
+	ErrInvalidHandshake   = errors.New("tcp: invalid handshake")
+	ErrUnsupportedVersion = errors.New("tcp: unsupported protocol version")
+	ErrHandshakeTooLong   = errors.New("tcp: handshake too long")
+	ErrInvalidDirection   = errors.New("tcp: invalid direction")
+)
+
+// HandshakeRequest is sent by a client connecting to the raw styx protocol
+// listener. It selects the log, the direction of the stream and, when
+// reading, where and how records are consumed.
+//
+// On the wire, the request starts with the "STYX" magic, the protocol
+// version and the size of the following fields. Compression lists the codecs
+// the client accepts, in an Accept-Encoding style, and may be omitted with
+// version 0. Features, only sent with version 1, lists the features the
+// client supports.
+type HandshakeRequest struct {
+	Version     int
+	Direction   int
+	Timeout     int
+	Position    int64
+	Count       int64
+	Follow      bool
+	Name        string
+	Whence      string
+	Filter      string
+	Token       string
+	Compression string
+	Features    []string
+}
+
+func (hr *HandshakeRequest) Encode(p []byte) (n int, err error) {
+
+	if len(p) < handshakeHeaderSize+1+4+8+8+1 {
+		return 0, recio.ErrShortBuffer
+	}
+
+	n = copy(p, HandshakeMagic)
+
+	p[n] = byte(hr.Version)
+	n += 1
+
+	// Leave room for body size.
+	n += 2
+
+	p[n] = byte(hr.Direction)
+	n += 1
+
+	binary.BigEndian.PutUint32(p[n:], uint32(hr.Timeout))
+	n += 4
+
+	binary.BigEndian.PutUint64(p[n:], uint64(hr.Position))
+	n += 8
+
+	binary.BigEndian.PutUint64(p[n:], uint64(hr.Count))
+	n += 8
+
+	p[n] = 0
+	if hr.Follow {
+		p[n] = 1
+	}
+	n += 1
+
+	fields := []string{hr.Name, hr.Whence, hr.Filter, hr.Token, hr.Compression}
+	if hr.Version >= ProtocolVersion1 {
+		fields = append(fields, FormatFeatures(hr.Features))
+	}
+
+	for _, s := range fields {
+
+		nn, err := encodeString(p[n:], s)
+		if err != nil {
+			return 0, err
+		}
+
+		n += nn
+	}
+
+	size := n - handshakeHeaderSize
+	if size > 1<<16-1 {
+		return 0, ErrHandshakeTooLong
+	}
+
+	binary.BigEndian.PutUint16(p[5:], uint16(size))
+
+	return n, nil
+}
+
+func (hr *HandshakeRequest) Decode(p []byte) (n int, err error) {
+
+	if len(p) < handshakeHeaderSize {
+		return 0, recio.ErrShortBuffer
+	}
+
+	if string(p[:4]) != HandshakeMagic {
+		return 0, ErrInvalidHandshake
+	}
+	n = 4
+
+	if int(p[n]) > LatestVersion {
+		return 0, ErrUnsupportedVersion
+	}
+
+	hr.Version = int(p[n])
+	n += 1
+
+	size := int(binary.BigEndian.Uint16(p[n : n+2]))
+	n += 2
+
+	if len(p) < n+size {
+		return 0, recio.ErrShortBuffer
+	}
+
+	if size < 1+4+8+8+1 {
+		return 0, ErrInvalidHandshake
+	}
+
+	hr.Direction = int(p[n])
+	n += 1
+
+	hr.Timeout = int(binary.BigEndian.Uint32(p[n : n+4]))
+	n += 4
+
+	hr.Position = int64(binary.BigEndian.Uint64(p[n : n+8]))
+	n += 8
+
+	hr.Count = int64(binary.BigEndian.Uint64(p[n : n+8]))
+	n += 8
+
+	hr.Follow = p[n] == 1
+	n += 1
+
+	for _, s := range []*string{&hr.Name, &hr.Whence, &hr.Filter, &hr.Token} {
+
+		nn, err := decodeString(p[n:handshakeHeaderSize+size], s)
+		if err != nil {
+			return 0, ErrInvalidHandshake
+		}
+
+		n += nn
+	}
+
+	// Compression was added after the other fields,
+	// version 0 clients not supporting it don't send it.
+	hr.Compression = ""
+	hr.Features = nil
+
+	if hr.Version == ProtocolVersion0 && n == handshakeHeaderSize+size {
+		return n, nil
+	}
+
+	nn, err := decodeString(p[n:handshakeHeaderSize+size], &hr.Compression)
+	if err != nil {
+		return 0, ErrInvalidHandshake
+	}
+
+	n += nn
+
+	if hr.Version >= ProtocolVersion1 {
+
+		var features string
+
+		nn, err := decodeString(p[n:handshakeHeaderSize+size], &features)
+		if err != nil {
+			return 0, ErrInvalidHandshake
+		}
+
+		n += nn
+
+		hr.Features = ParseFeatures(features)
+	}
+
+	return n, nil
+}
+
+// WriteTo writes the encoded request to w.
+func (hr *HandshakeRequest) WriteTo(w io.Writer) (n int64, err error) {
+
+	buf := make([]byte, handshakeHeaderSize+1<<16)
+
+	nn, err := hr.Encode(buf)
+	if err != nil {
+		return 0, err
+	}
+
+	nn, err = w.Write(buf[:nn])
+	if err != nil {
+		return int64(nn), err
+	}
+
+	return int64(nn), nil
+}
+
+// ReadFrom reads and decodes a request from r, without reading past its end.
+func (hr *HandshakeRequest) ReadFrom(r io.Reader) (n int64, err error) {
+
+	buf := make([]byte, handshakeHeaderSize+1<<16)
+
+	nn, err := io.ReadFull(r, buf[:handshakeHeaderSize])
+	n = int64(nn)
+	if err != nil {
+		return n, err
+	}
+
+	if string(buf[:4]) != HandshakeMagic {
+		return n, ErrInvalidHandshake
+	}
+
+	// Read the whole request before checking its version, so that
+	// the server can answer requests of unsupported versions.
+	size := int(binary.BigEndian.Uint16(buf[5:handshakeHeaderSize]))
+
+	nn, err = io.ReadFull(r, buf[handshakeHeaderSize:handshakeHeaderSize+size])
+	n += int64(nn)
+	if err != nil {
+		return n, err
+	}
+
+	_, err = hr.Decode(buf[:handshakeHeaderSize+size])
+	if err != nil {
+		return n, err
+	}
+
+	return n, nil
+}
+
+// HandshakeResponse is sent back by the server. When the handshake is
+// rejected, Code holds the error code and the server closes the connection.
+// Version is the protocol version of the connection, the one of the request
+// when supported by the server and its latest version otherwise. Compression
+// holds the codec selected among the ones offered by the client, the stream
+// following the handshake being compressed with it. Features, only sent with
+// version 1, lists the features enabled on the connection.
+type HandshakeResponse struct {
+	Version     int
+	Status      int
+	Code        int
+	Timeout     int
+	Compression string
+	Features    []string
+}
+
+func (hr *HandshakeResponse) Encode(p []byte) (n int, err error) {
+
+	if len(p) < handshakeResponseSize {
+		return 0, recio.ErrShortBuffer
+	}
+
+	n = copy(p, HandshakeMagic)
+
+	p[n] = byte(hr.Version)
+	n += 1
+
+	p[n] = byte(hr.Status)
+	n += 1
+
+	binary.BigEndian.PutUint16(p[n:], uint16(hr.Code))
+	n += 2
+
+	binary.BigEndian.PutUint32(p[n:], uint32(hr.Timeout))
+	n += 4
+
+	fields := []string{hr.Compression}
+	if hr.Version >= ProtocolVersion1 {
+		fields = append(fields, FormatFeatures(hr.Features))
+	}
+
+	for _, s := range fields {
+
+		nn, err := encodeString(p[n:], s)
+		if err != nil {
+			return 0, err
+		}
+
+		n += nn
+	}
+
+	return n, nil
+}
+
+func (hr *HandshakeResponse) Decode(p []byte) (n int, err error) {
+
+	if len(p) < handshakeResponseSize {
+		return 0, recio.ErrShortBuffer
+	}
+
+	if string(p[:4]) != HandshakeMagic {
+		return 0, ErrInvalidHandshake
+	}
+	n = 4
+
+	if int(p[n]) > LatestVersion {
+		return 0, ErrUnsupportedVersion
+	}
+
+	hr.Version = int(p[n])
+	n += 1
+
+	hr.Status = int(p[n])
+	n += 1
+
+	hr.Code = int(binary.BigEndian.Uint16(p[n : n+2]))
+	n += 2
+
+	hr.Timeout = int(binary.BigEndian.Uint32(p[n : n+4]))
+	n += 4
+
+	nn, err := decodeString(p[n:], &hr.Compression)
+	if err != nil {
+		return 0, err
+	}
+
+	n += nn
+
+	hr.Features = nil
+
+	if hr.Version >= ProtocolVersion1 {
+
+		var features string
+
+		nn, err := decodeString(p[n:], &features)
+		if err != nil {
+			return 0, err
+		}
+
+		n += nn
+
+		hr.Features = ParseFeatures(features)
+	}
+
+	return n, nil
+}
+
+// WriteTo writes the encoded response to w.
+func (hr *HandshakeResponse) WriteTo(w io.Writer) (n int64, err error) {
+
+	buf := make([]byte, handshakeResponseSize+2*(1<<16))
+
+	nn, err := hr.Encode(buf)
+	if err != nil {
+		return 0, err
+	}
+
+	nn, err = w.Write(buf[:nn])
+	if err != nil {
+		return int64(nn), err
+	}
+
+	return int64(nn), nil
+}
+
+// ReadFrom reads and decodes a response from r, without reading past its end.
+func (hr *HandshakeResponse) ReadFrom(r io.Reader) (n int64, err error) {
+
+	buf := make([]byte, handshakeResponseSize+2*(2+1<<16))
+
+	nn, err := io.ReadFull(r, buf[:handshakeResponseSize])
+	n = int64(nn)
+	if err != nil {
+		return n, err
+	}
+
+	if string(buf[:4]) != HandshakeMagic {
+		return n, ErrInvalidHandshake
+	}
+
+	if int(buf[4]) > LatestVersion {
+		return n, ErrUnsupportedVersion
+	}
+
+	// Read the variable length fields following
+	// the fixed size part of the response.
+	fieldsCount := 1
+	if int(buf[4]) >= ProtocolVersion1 {
+		fieldsCount = 2
+	}
+
+	end := handshakeResponseSize
+
+	for i := 0; i < fieldsCount; i++ {
+
+		nn, err = io.ReadFull(r, buf[end:end+2])
+		n += int64(nn)
+		if err != nil {
+			return n, err
+		}
+
+		size := int(binary.BigEndian.Uint16(buf[end : end+2]))
+		end += 2
+
+		nn, err = io.ReadFull(r, buf[end:end+size])
+		n += int64(nn)
+		if err != nil {
+			return n, err
+		}
+
+		end += size
+	}
+
+	_, err = hr.Decode(buf[:end])
+	if err != nil {
+		return n, err
+	}
+
+	return n, nil
+}
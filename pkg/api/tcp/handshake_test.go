@@ -0,0 +1,389 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHandshakeRequest_RoundTrip(t *testing.T) {
+
+	requests := []HandshakeRequest{
+		{
+			Version:     ProtocolVersion0,
+			Direction:   DirectionWrite,
+			Timeout:     30,
+			Name:        "ns/log",
+			Compression: "gzip",
+		},
+		{
+			Version:     ProtocolVersion1,
+			Direction:   DirectionRead,
+			Timeout:     10,
+			Position:    -5,
+			Count:       100,
+			Follow:      true,
+			Name:        "ns/log",
+			Whence:      "end",
+			Filter:      "json:type == \"a\"",
+			Token:       "token",
+			Compression: "lz4, gzip;q=0.5",
+			Features:    []string{FeatureMetadata},
+		},
+	}
+
+	for _, request := range requests {
+
+		buf := &bytes.Buffer{}
+
+		n, err := request.WriteTo(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if int(n) != buf.Len() {
+			t.Fatalf("write should report %d bytes but got %d", buf.Len(), n)
+		}
+
+		// Bytes following the request are left unread.
+		buf.WriteString("records")
+
+		decoded := HandshakeRequest{}
+
+		m, err := decoded.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if m != n {
+			t.Fatalf("read should report %d bytes but got %d", n, m)
+		}
+
+		if !reflect.DeepEqual(decoded, request) {
+			t.Fatalf("request should be %+v but got %+v", request, decoded)
+		}
+
+		if buf.String() != "records" {
+			t.Fatalf("read should stop at the end of the request but left %q", buf.String())
+		}
+	}
+}
+
+func TestHandshakeRequest_Version0WithoutCompression(t *testing.T) {
+
+	request := HandshakeRequest{
+		Version: ProtocolVersion0,
+		Name:    "log",
+		Token:   "token",
+	}
+
+	buf := make([]byte, handshakeHeaderSize+1<<16)
+
+	n, err := request.Encode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Version 0 clients predating compression end the request with the
+	// token.
+	n -= 2
+	binary.BigEndian.PutUint16(buf[5:], uint16(n-handshakeHeaderSize))
+
+	decoded := HandshakeRequest{}
+
+	_, err = decoded.ReadFrom(bytes.NewReader(buf[:n]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(decoded, request) {
+		t.Fatalf("request should be %+v but got %+v", request, decoded)
+	}
+}
+
+func TestHandshakeRequest_Truncated(t *testing.T) {
+
+	request := HandshakeRequest{
+		Version:     ProtocolVersion1,
+		Name:        "log",
+		Whence:      "start",
+		Token:       "token",
+		Compression: "gzip",
+		Features:    []string{FeatureMetadata},
+	}
+
+	encoded := testHandshake_EncodeRequest(t, request)
+
+	// Requests cut short on the wire.
+	for i := 0; i < len(encoded); i++ {
+
+		decoded := HandshakeRequest{}
+
+		_, err := decoded.ReadFrom(bytes.NewReader(encoded[:i]))
+		if err == nil {
+			t.Fatalf("request truncated to %d bytes should fail", i)
+		}
+	}
+
+	// Requests whose size leaves out some of their fields.
+	for size := 0; size < len(encoded)-handshakeHeaderSize; size++ {
+
+		truncated := append([]byte{}, encoded[:handshakeHeaderSize+size]...)
+		binary.BigEndian.PutUint16(truncated[5:], uint16(size))
+
+		decoded := HandshakeRequest{}
+
+		_, err := decoded.ReadFrom(bytes.NewReader(truncated))
+		if err != ErrInvalidHandshake {
+			t.Fatalf("request of size %d should fail with err = %s but got %v", size, ErrInvalidHandshake, err)
+		}
+	}
+}
+
+func TestHandshakeRequest_Oversized(t *testing.T) {
+
+	long := strings.Repeat("a", 1<<16)
+
+	requests := []HandshakeRequest{
+		{Version: ProtocolVersion1, Name: long},
+		{Version: ProtocolVersion1, Token: long[:1<<15], Filter: long[:1<<15]},
+	}
+
+	for _, request := range requests {
+
+		buf := &bytes.Buffer{}
+
+		_, err := request.WriteTo(buf)
+		if err == nil {
+			t.Fatalf("oversized request should fail")
+		}
+
+		if buf.Len() != 0 {
+			t.Fatalf("oversized request should not be written but got %d bytes", buf.Len())
+		}
+	}
+
+	// A field whose size runs past the end of the request.
+	encoded := testHandshake_EncodeRequest(t, HandshakeRequest{
+		Version: ProtocolVersion1,
+		Name:    "log",
+	})
+
+	binary.BigEndian.PutUint16(encoded[handshakeHeaderSize+1+4+8+8+1:], 1<<16-1)
+
+	decoded := HandshakeRequest{}
+
+	_, err := decoded.ReadFrom(bytes.NewReader(encoded))
+	if err != ErrInvalidHandshake {
+		t.Fatalf("request with an oversized field should fail with err = %s but got %v", ErrInvalidHandshake, err)
+	}
+}
+
+func TestHandshakeRequest_UnknownVersion(t *testing.T) {
+
+	encoded := testHandshake_EncodeRequest(t, HandshakeRequest{
+		Version:  ProtocolVersion1,
+		Name:     "log",
+		Features: []string{"future"},
+	})
+
+	encoded[4] = LatestVersion + 1
+
+	r := bytes.NewReader(encoded)
+	decoded := HandshakeRequest{}
+
+	n, err := decoded.ReadFrom(r)
+	if err != ErrUnsupportedVersion {
+		t.Fatalf("request should fail with err = %s but got %v", ErrUnsupportedVersion, err)
+	}
+
+	// The whole request is read for the server to answer it.
+	if int(n) != len(encoded) || r.Len() != 0 {
+		t.Fatalf("read should consume the %d bytes of the request but got %d", len(encoded), n)
+	}
+
+	encoded[0] = 'X'
+
+	_, err = decoded.ReadFrom(bytes.NewReader(encoded))
+	if err != ErrInvalidHandshake {
+		t.Fatalf("request with an invalid magic should fail with err = %s but got %v", ErrInvalidHandshake, err)
+	}
+}
+
+func TestHandshakeResponse_RoundTrip(t *testing.T) {
+
+	responses := []HandshakeResponse{
+		{
+			Version:     ProtocolVersion0,
+			Status:      HandshakeAccepted,
+			Timeout:     30,
+			Compression: "gzip",
+		},
+		{
+			Version:  ProtocolVersion1,
+			Status:   HandshakeRejected,
+			Code:     1 << 15,
+			Features: []string{},
+		},
+		{
+			Version:     ProtocolVersion1,
+			Status:      HandshakeAccepted,
+			Timeout:     10,
+			Compression: "lz4",
+			Features:    []string{FeatureMetadata},
+		},
+	}
+
+	for _, response := range responses {
+
+		buf := &bytes.Buffer{}
+
+		n, err := response.WriteTo(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		buf.WriteString("records")
+
+		decoded := HandshakeResponse{}
+
+		m, err := decoded.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if m != n {
+			t.Fatalf("read should report %d bytes but got %d", n, m)
+		}
+
+		if !reflect.DeepEqual(decoded, response) {
+			t.Fatalf("response should be %+v but got %+v", response, decoded)
+		}
+
+		if buf.String() != "records" {
+			t.Fatalf("read should stop at the end of the response but left %q", buf.String())
+		}
+	}
+}
+
+func TestHandshakeResponse_Truncated(t *testing.T) {
+
+	encoded := testHandshake_EncodeResponse(t, HandshakeResponse{
+		Version:     ProtocolVersion1,
+		Compression: "gzip",
+		Features:    []string{FeatureMetadata},
+	})
+
+	for i := 0; i < len(encoded); i++ {
+
+		decoded := HandshakeResponse{}
+
+		_, err := decoded.ReadFrom(bytes.NewReader(encoded[:i]))
+		if err == nil {
+			t.Fatalf("response truncated to %d bytes should fail", i)
+		}
+
+		_, err = decoded.Decode(encoded[:i])
+		if err == nil {
+			t.Fatalf("response truncated to %d bytes should fail to decode", i)
+		}
+	}
+}
+
+func TestHandshakeResponse_Oversized(t *testing.T) {
+
+	response := HandshakeResponse{
+		Version:     ProtocolVersion1,
+		Compression: strings.Repeat("a", 1<<16),
+	}
+
+	buf := &bytes.Buffer{}
+
+	_, err := response.WriteTo(buf)
+	if err != ErrStringTooLong {
+		t.Fatalf("oversized response should fail with err = %s but got %v", ErrStringTooLong, err)
+	}
+
+	// A field whose size runs past the end of the stream.
+	encoded := testHandshake_EncodeResponse(t, HandshakeResponse{
+		Version:     ProtocolVersion1,
+		Compression: "gzip",
+	})
+
+	binary.BigEndian.PutUint16(encoded[handshakeResponseSize:], 1<<16-1)
+
+	decoded := HandshakeResponse{}
+
+	_, err = decoded.ReadFrom(bytes.NewReader(encoded))
+	if err == nil {
+		t.Fatalf("response with an oversized field should fail")
+	}
+}
+
+func TestHandshakeResponse_UnknownVersion(t *testing.T) {
+
+	encoded := testHandshake_EncodeResponse(t, HandshakeResponse{
+		Version: ProtocolVersion1,
+	})
+
+	encoded[4] = LatestVersion + 1
+
+	decoded := HandshakeResponse{}
+
+	_, err := decoded.ReadFrom(bytes.NewReader(encoded))
+	if err != ErrUnsupportedVersion {
+		t.Fatalf("response should fail with err = %s but got %v", ErrUnsupportedVersion, err)
+	}
+
+	_, err = decoded.Decode(encoded)
+	if err != ErrUnsupportedVersion {
+		t.Fatalf("response should fail to decode with err = %s but got %v", ErrUnsupportedVersion, err)
+	}
+
+	encoded[0] = 'X'
+
+	_, err = decoded.ReadFrom(bytes.NewReader(encoded))
+	if err != ErrInvalidHandshake {
+		t.Fatalf("response with an invalid magic should fail with err = %s but got %v", ErrInvalidHandshake, err)
+	}
+}
+
+func testHandshake_EncodeRequest(t *testing.T, request HandshakeRequest) (encoded []byte) {
+
+	buf := &bytes.Buffer{}
+
+	_, err := request.WriteTo(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func testHandshake_EncodeResponse(t *testing.T, response HandshakeResponse) (encoded []byte) {
+
+	buf := &bytes.Buffer{}
+
+	_, err := response.WriteTo(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
@@ -28,6 +28,15 @@ const (
 	TypeAckMessage
 	TypeHeartbeatMessage
 	TypeErrorMessage
+	TypeSubscribeMessage
+	TypeUnsubscribeMessage
+	TypeLogRecordMessage
+	TypeSubscriptionEndMessage
+	TypeCreditMessage
+	TypeProgressMessage
+	TypeBatchMessage
+	TypeControlMessage
+	TypePositionMessage
 )
 
 var (
@@ -143,6 +152,16 @@ type Message struct {
 	ackMessage       AckMessage
 	heartbeatMessage HeartbeatMessage
 	errorMessage     ErrorMessage
+
+	subscribeMessage       SubscribeMessage
+	unsubscribeMessage     UnsubscribeMessage
+	logRecordMessage       LogRecordMessage
+	subscriptionEndMessage SubscriptionEndMessage
+	creditMessage          CreditMessage
+	progressMessage        ProgressMessage
+	batchMessage           BatchMessage
+	controlMessage         ControlMessage
+	positionMessage        PositionMessage
 }
 
 func (m *Message) Encode(p []byte) (n int, err error) {
@@ -182,6 +201,24 @@ func (m *Message) Decode(p []byte) (n int, err error) {
 		m.Payload = &m.heartbeatMessage
 	case TypeErrorMessage:
 		m.Payload = &m.errorMessage
+	case TypeSubscribeMessage:
+		m.Payload = &m.subscribeMessage
+	case TypeUnsubscribeMessage:
+		m.Payload = &m.unsubscribeMessage
+	case TypeLogRecordMessage:
+		m.Payload = &m.logRecordMessage
+	case TypeSubscriptionEndMessage:
+		m.Payload = &m.subscriptionEndMessage
+	case TypeCreditMessage:
+		m.Payload = &m.creditMessage
+	case TypeProgressMessage:
+		m.Payload = &m.progressMessage
+	case TypeBatchMessage:
+		m.Payload = &m.batchMessage
+	case TypeControlMessage:
+		m.Payload = &m.controlMessage
+	case TypePositionMessage:
+		m.Payload = &m.positionMessage
 	default:
 		return 0, ErrUnkownMessageType
 	}
@@ -0,0 +1,273 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcp
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/dataptive/styx/pkg/log"
+	"github.com/dataptive/styx/pkg/recio"
+)
+
+const (
+	EndReasonComplete     = iota // Count reached or end of log when not following.
+	EndReasonUnsubscribed        // Subscription canceled by the client.
+	EndReasonError               // Subscription aborted, see error code.
+)
+
+var (
+	ErrStringTooLong = errors.New("tcp: string too long")
+)
+
+// SubscribeMessage is sent by a multiplexing client to start consuming a
+// log under the given subscription ID.
+type SubscribeMessage struct {
+	ID       uint32
+	Position int64
+	Count    int64
+	Follow   bool
+	Whence   string
+	Name     string
+	Filter   string
+}
+
+func (sm *SubscribeMessage) Encode(p []byte) (n int, err error) {
+
+	if len(p) < 4+8+8+1 {
+		return 0, recio.ErrShortBuffer
+	}
+
+	binary.BigEndian.PutUint32(p, sm.ID)
+	n = 4
+
+	binary.BigEndian.PutUint64(p[n:], uint64(sm.Position))
+	n += 8
+
+	binary.BigEndian.PutUint64(p[n:], uint64(sm.Count))
+	n += 8
+
+	p[n] = 0
+	if sm.Follow {
+		p[n] = 1
+	}
+	n += 1
+
+	for _, s := range []string{sm.Whence, sm.Name, sm.Filter} {
+
+		nn, err := encodeString(p[n:], s)
+		if err != nil {
+			return 0, err
+		}
+
+		n += nn
+	}
+
+	return n, nil
+}
+
+func (sm *SubscribeMessage) Decode(p []byte) (n int, err error) {
+
+	if len(p) < 4+8+8+1 {
+		return 0, recio.ErrShortBuffer
+	}
+
+	sm.ID = binary.BigEndian.Uint32(p[:4])
+	n = 4
+
+	sm.Position = int64(binary.BigEndian.Uint64(p[n : n+8]))
+	n += 8
+
+	sm.Count = int64(binary.BigEndian.Uint64(p[n : n+8]))
+	n += 8
+
+	sm.Follow = p[n] == 1
+	n += 1
+
+	for _, s := range []*string{&sm.Whence, &sm.Name, &sm.Filter} {
+
+		nn, err := decodeString(p[n:], s)
+		if err != nil {
+			return 0, err
+		}
+
+		n += nn
+	}
+
+	return n, nil
+}
+
+// UnsubscribeMessage is sent by a multiplexing client to stop consuming
+// the log associated with a subscription ID.
+type UnsubscribeMessage struct {
+	ID uint32
+}
+
+func (um *UnsubscribeMessage) Encode(p []byte) (n int, err error) {
+
+	if len(p) < 4 {
+		return 0, recio.ErrShortBuffer
+	}
+
+	binary.BigEndian.PutUint32(p, um.ID)
+	n = 4
+
+	return n, nil
+}
+
+func (um *UnsubscribeMessage) Decode(p []byte) (n int, err error) {
+
+	if len(p) < 4 {
+		return 0, recio.ErrShortBuffer
+	}
+
+	um.ID = binary.BigEndian.Uint32(p[:4])
+	n = 4
+
+	return n, nil
+}
+
+// LogRecordMessage carries a record tagged with the subscription it
+// belongs to and its position in the log.
+type LogRecordMessage struct {
+	ID       uint32
+	Position int64
+	Record   log.Record
+}
+
+func (lrm *LogRecordMessage) Encode(p []byte) (n int, err error) {
+
+	if len(p) < 4+8 {
+		return 0, recio.ErrShortBuffer
+	}
+
+	binary.BigEndian.PutUint32(p, lrm.ID)
+	n = 4
+
+	binary.BigEndian.PutUint64(p[n:], uint64(lrm.Position))
+	n += 8
+
+	nn, err := lrm.Record.Encode(p[n:])
+	if err != nil {
+		return 0, err
+	}
+
+	n += nn
+
+	return n, nil
+}
+
+func (lrm *LogRecordMessage) Decode(p []byte) (n int, err error) {
+
+	if len(p) < 4+8 {
+		return 0, recio.ErrShortBuffer
+	}
+
+	lrm.ID = binary.BigEndian.Uint32(p[:4])
+	n = 4
+
+	lrm.Position = int64(binary.BigEndian.Uint64(p[n : n+8]))
+	n += 8
+
+	nn, err := lrm.Record.Decode(p[n:])
+	if err != nil {
+		return 0, err
+	}
+
+	n += nn
+
+	return n, nil
+}
+
+// SubscriptionEndMessage is sent by the server when a subscription stops
+// delivering records. Code is only meaningful with EndReasonError.
+type SubscriptionEndMessage struct {
+	ID     uint32
+	Reason int
+	Code   int
+}
+
+func (sem *SubscriptionEndMessage) Encode(p []byte) (n int, err error) {
+
+	if len(p) < 4+1+2 {
+		return 0, recio.ErrShortBuffer
+	}
+
+	binary.BigEndian.PutUint32(p, sem.ID)
+	n = 4
+
+	p[n] = byte(sem.Reason)
+	n += 1
+
+	binary.BigEndian.PutUint16(p[n:], uint16(sem.Code))
+	n += 2
+
+	return n, nil
+}
+
+func (sem *SubscriptionEndMessage) Decode(p []byte) (n int, err error) {
+
+	if len(p) < 4+1+2 {
+		return 0, recio.ErrShortBuffer
+	}
+
+	sem.ID = binary.BigEndian.Uint32(p[:4])
+	n = 4
+
+	sem.Reason = int(p[n])
+	n += 1
+
+	sem.Code = int(binary.BigEndian.Uint16(p[n : n+2]))
+	n += 2
+
+	return n, nil
+}
+
+func encodeString(p []byte, s string) (n int, err error) {
+
+	if len(s) > 1<<16-1 {
+		return 0, ErrStringTooLong
+	}
+
+	if len(p) < 2+len(s) {
+		return 0, recio.ErrShortBuffer
+	}
+
+	binary.BigEndian.PutUint16(p, uint16(len(s)))
+	n = 2
+
+	n += copy(p[n:], s)
+
+	return n, nil
+}
+
+func decodeString(p []byte, s *string) (n int, err error) {
+
+	if len(p) < 2 {
+		return 0, recio.ErrShortBuffer
+	}
+
+	size := int(binary.BigEndian.Uint16(p[:2]))
+	n = 2
+
+	if len(p) < n+size {
+		return 0, recio.ErrShortBuffer
+	}
+
+	*s = string(p[n : n+size])
+	n += size
+
+	return n, nil
+}
@@ -30,7 +30,7 @@ var (
 )
 
 type TCPPeer struct {
-	conn              *net.TCPConn
+	conn              net.Conn
 	messageWriter     *MessageWriter
 	messageReader     *MessageReader
 	heartbeaterClose  chan struct{}
@@ -50,7 +50,7 @@ type TCPPeer struct {
 	errorHandler      ErrorHandler
 }
 
-func NewTCPPeer(conn *net.TCPConn, writeBufferSize int, readBufferSize int, localTimeout int, remoteTimeout int, ioMode recio.IOMode) (tp *TCPPeer) {
+func NewTCPPeer(conn net.Conn, writeBufferSize int, readBufferSize int, localTimeout int, remoteTimeout int, ioMode recio.IOMode) (tp *TCPPeer) {
 
 	messageWriter := NewMessageWriter(conn, writeBufferSize, recio.ModeManual)
 	messageReader := NewMessageReader(conn, readBufferSize, recio.ModeManual)
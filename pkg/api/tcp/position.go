@@ -0,0 +1,53 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcp
+
+import (
+	"encoding/binary"
+
+	"github.com/dataptive/styx/pkg/recio"
+)
+
+// PositionMessage is sent by the server when the metadata feature was
+// negotiated, right before a record that doesn't follow the previous one in
+// the log. Position is the position of that record, the records following it
+// being at consecutive positions.
+type PositionMessage struct {
+	Position int64
+}
+
+func (pm *PositionMessage) Encode(p []byte) (n int, err error) {
+
+	if len(p) < 8 {
+		return 0, recio.ErrShortBuffer
+	}
+
+	binary.BigEndian.PutUint64(p, uint64(pm.Position))
+	n = 8
+
+	return n, nil
+}
+
+func (pm *PositionMessage) Decode(p []byte) (n int, err error) {
+
+	if len(p) < 8 {
+		return 0, recio.ErrShortBuffer
+	}
+
+	pm.Position = int64(binary.BigEndian.Uint64(p[:8]))
+	n = 8
+
+	return n, nil
+}
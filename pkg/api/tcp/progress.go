@@ -0,0 +1,68 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcp
+
+import (
+	"encoding/binary"
+
+	"github.com/dataptive/styx/pkg/recio"
+)
+
+// ProgressMessage is sent by a consumer to report the number of records it
+// processed since the beginning of the stream. When Cursor is not empty, the
+// position following the last processed record is committed to the named
+// cursor of the log.
+type ProgressMessage struct {
+	Count  int64
+	Cursor string
+}
+
+func (pm *ProgressMessage) Encode(p []byte) (n int, err error) {
+
+	if len(p) < 8 {
+		return 0, recio.ErrShortBuffer
+	}
+
+	binary.BigEndian.PutUint64(p, uint64(pm.Count))
+	n = 8
+
+	nn, err := encodeString(p[n:], pm.Cursor)
+	if err != nil {
+		return 0, err
+	}
+
+	n += nn
+
+	return n, nil
+}
+
+func (pm *ProgressMessage) Decode(p []byte) (n int, err error) {
+
+	if len(p) < 8 {
+		return 0, recio.ErrShortBuffer
+	}
+
+	pm.Count = int64(binary.BigEndian.Uint64(p[:8]))
+	n = 8
+
+	nn, err := decodeString(p[n:], &pm.Cursor)
+	if err != nil {
+		return 0, err
+	}
+
+	n += nn
+
+	return n, nil
+}
@@ -0,0 +1,174 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcp
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	ProtocolVersion0 = 0
+	ProtocolVersion1 = 1
+
+	// LatestVersion is the highest protocol version supported.
+	LatestVersion = ProtocolVersion1
+
+	protocolPrefix = "styx/"
+)
+
+// Features are capabilities of styx protocol v1 connections, enabled when
+// both peers list them during the handshake.
+const (
+	FeatureCompression = "compression"  // Stream compressed with the codec selected during the handshake.
+	FeatureBatch       = "batch"        // Records grouped in batch messages.
+	FeatureMetadata    = "metadata"     // Records sent along with their position.
+	FeatureFlowControl = "flow-control" // Records sent against credits granted by the receiver.
+	FeatureProgress    = "progress"     // Consumers report the records they processed.
+	FeatureControl     = "control"      // Consumers seek, pause and resume the record stream.
+)
+
+var (
+	// SupportedFeatures lists the features implemented by this package.
+	SupportedFeatures = []string{
+		FeatureCompression,
+		FeatureBatch,
+		FeatureMetadata,
+		FeatureFlowControl,
+		FeatureProgress,
+		FeatureControl,
+	}
+)
+
+// Capabilities hold the protocol version and the features negotiated during
+// the handshake of a connection, along with the selected compression codec.
+type Capabilities struct {
+	Version     int
+	Features    []string
+	Compression string
+}
+
+// Has reports whether feature was negotiated. Features are only available
+// with protocol v1 and later.
+func (c Capabilities) Has(feature string) (ok bool) {
+
+	if c.Version < ProtocolVersion1 {
+		return false
+	}
+
+	for _, f := range c.Features {
+		if f == feature {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ProtocolString returns the HTTP upgrade protocol string of version,
+// e.g. styx/1.
+func ProtocolString(version int) (s string) {
+
+	return protocolPrefix + strconv.Itoa(version)
+}
+
+// UpgradeString returns the value of the HTTP upgrade header offering all
+// the supported protocol versions, the latest first.
+func UpgradeString() (s string) {
+
+	protocols := []string{}
+
+	for version := LatestVersion; version >= ProtocolVersion0; version-- {
+		protocols = append(protocols, ProtocolString(version))
+	}
+
+	return strings.Join(protocols, ", ")
+}
+
+// NegotiateVersion returns the highest supported protocol version listed in
+// an HTTP upgrade header, ok being false when none is supported.
+func NegotiateVersion(upgrade string) (version int, ok bool) {
+
+	version = -1
+
+	for _, protocol := range strings.Split(upgrade, ",") {
+
+		protocol = strings.TrimSpace(protocol)
+		if !strings.HasPrefix(protocol, protocolPrefix) {
+			continue
+		}
+
+		v, err := strconv.Atoi(strings.TrimPrefix(protocol, protocolPrefix))
+		if err != nil {
+			continue
+		}
+
+		if v > LatestVersion || v < ProtocolVersion0 {
+			continue
+		}
+
+		if v > version {
+			version = v
+		}
+	}
+
+	if version == -1 {
+		return 0, false
+	}
+
+	return version, true
+}
+
+// ParseFeatures parses a comma separated list of features.
+func ParseFeatures(s string) (features []string) {
+
+	features = []string{}
+
+	for _, feature := range strings.Split(s, ",") {
+
+		feature = strings.TrimSpace(feature)
+		if feature == "" {
+			continue
+		}
+
+		features = append(features, feature)
+	}
+
+	return features
+}
+
+// FormatFeatures formats features as a comma separated list.
+func FormatFeatures(features []string) (s string) {
+
+	return strings.Join(features, ", ")
+}
+
+// NegotiateFeatures returns the supported features which are also offered,
+// in the order of supported. Unknown offered features are ignored.
+func NegotiateFeatures(offered []string, supported []string) (features []string) {
+
+	features = []string{}
+
+	for _, feature := range supported {
+		for _, o := range offered {
+			if o == feature {
+				features = append(features, feature)
+				break
+			}
+		}
+	}
+
+	return features
+}
@@ -15,36 +15,62 @@
 package tcp
 
 import (
+	"io"
 	"net"
+	"sync"
+	"sync/atomic"
 
 	"github.com/dataptive/styx/pkg/log"
 	"github.com/dataptive/styx/pkg/recio"
 )
 
 type TCPReader struct {
-	conn         *net.TCPConn
-	ioMode       recio.IOMode
-	tcpPeer      *TCPPeer
-	ackMessage   *AckMessage
-	errorMessage *ErrorMessage
-	messageIn    *Message
-	messageOut   *Message
-	mustFill     bool
+	conn            net.Conn
+	ioMode          recio.IOMode
+	tcpPeer         *TCPPeer
+	ackMessage      *AckMessage
+	errorMessage    *ErrorMessage
+	creditMessage   *CreditMessage
+	progressMessage *ProgressMessage
+	controlMessage  *ControlMessage
+	messageIn       *Message
+	messageOut      *Message
+	controlOut      *Message
+	controlLock     sync.Mutex
+	batchMessage    *BatchMessage
+	mustFill        bool
+	flowControl     bool
+	windowRecords   int64
+	windowBytes     int64
+	consumedRecords int64
+	consumedBytes   int64
+	count           int64
+	pendingSeeks    int64
+	nextPosition    int64
+	position        int64
 }
 
-func NewTCPReader(conn *net.TCPConn, writeBufferSize int, readBufferSize int, localTimeout int, remoteTimeout int, ioMode recio.IOMode) (tr *TCPReader) {
+func NewTCPReader(conn net.Conn, writeBufferSize int, readBufferSize int, localTimeout int, remoteTimeout int, ioMode recio.IOMode) (tr *TCPReader) {
 
 	tcpPeer := NewTCPPeer(conn, writeBufferSize, readBufferSize, localTimeout, remoteTimeout, ioMode)
 
 	tr = &TCPReader{
-		conn:         conn,
-		ioMode:       ioMode,
-		tcpPeer:      tcpPeer,
-		ackMessage:   &AckMessage{},
-		errorMessage: &ErrorMessage{},
-		messageIn:    &Message{},
-		messageOut:   &Message{},
-		mustFill:     false,
+		conn:            conn,
+		ioMode:          ioMode,
+		tcpPeer:         tcpPeer,
+		ackMessage:      &AckMessage{},
+		errorMessage:    &ErrorMessage{},
+		creditMessage:   &CreditMessage{},
+		progressMessage: &ProgressMessage{},
+		controlMessage:  &ControlMessage{},
+		messageIn:       &Message{},
+		messageOut:      &Message{},
+		controlOut:      &Message{},
+		batchMessage:    nil,
+		mustFill:        false,
+		flowControl:     false,
+		nextPosition:    -1,
+		position:        -1,
 	}
 
 	return tr
@@ -83,7 +109,7 @@ func (tr *TCPReader) WriteAck(progress *log.SyncProgress) (n int, err error) {
 
 func (tr *TCPReader) WriteError(er error) (n int, err error) {
 
-	tr.errorMessage.Code = GetErrorCode(err)
+	tr.errorMessage.Code = GetErrorCode(er)
 
 	tr.messageOut.Type = TypeErrorMessage
 	tr.messageOut.Payload = tr.errorMessage
@@ -96,6 +122,159 @@ func (tr *TCPReader) WriteError(er error) (n int, err error) {
 	return n, nil
 }
 
+// EnableFlowControl grants the peer an initial window of records and bytes,
+// a zero value leaving the matching kind of credit unlimited. Credits are
+// then granted back as records are read, once half of the window was
+// consumed.
+func (tr *TCPReader) EnableFlowControl(records int64, bytes int64) (err error) {
+
+	tr.flowControl = true
+	tr.windowRecords = records
+	tr.windowBytes = bytes
+
+	err = tr.writeCredit(records, bytes)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (tr *TCPReader) writeCredit(records int64, bytes int64) (err error) {
+
+	tr.creditMessage.Records = records
+	tr.creditMessage.Bytes = bytes
+
+	tr.messageOut.Type = TypeCreditMessage
+	tr.messageOut.Payload = tr.creditMessage
+
+	_, err = tr.tcpPeer.WriteMessage(tr.messageOut)
+	if err == recio.ErrMustFlush {
+
+		err = tr.Flush()
+		if err != nil {
+			return err
+		}
+
+		_, err = tr.tcpPeer.WriteMessage(tr.messageOut)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = tr.Flush()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// consume accounts for a record handed to the application, and grants
+// credits back to the peer once half of the window was consumed.
+func (tr *TCPReader) consume(r *log.Record) (err error) {
+
+	tr.consumedRecords++
+	tr.consumedBytes += int64(len(*r))
+
+	mustGrant := false
+
+	if tr.windowRecords > 0 && tr.consumedRecords >= (tr.windowRecords+1)/2 {
+		mustGrant = true
+	}
+
+	if tr.windowBytes > 0 && tr.consumedBytes >= (tr.windowBytes+1)/2 {
+		mustGrant = true
+	}
+
+	if !mustGrant {
+		return nil
+	}
+
+	records := int64(0)
+	if tr.windowRecords > 0 {
+		records = tr.consumedRecords
+	}
+
+	bytes := int64(0)
+	if tr.windowBytes > 0 {
+		bytes = tr.consumedBytes
+	}
+
+	err = tr.writeCredit(records, bytes)
+	if err != nil {
+		return err
+	}
+
+	tr.consumedRecords = 0
+	tr.consumedBytes = 0
+
+	return nil
+}
+
+// WriteProgress reports that count records were processed since the
+// beginning of the stream, committing the matching position to cursor when
+// not empty.
+func (tr *TCPReader) WriteProgress(count int64, cursor string) (n int, err error) {
+
+	tr.progressMessage.Count = count
+	tr.progressMessage.Cursor = cursor
+
+	tr.messageOut.Type = TypeProgressMessage
+	tr.messageOut.Payload = tr.progressMessage
+
+	n, err = tr.tcpPeer.WriteMessage(tr.messageOut)
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// WriteControl sends a control message acting on the record stream. It may
+// be called concurrently with Read and other controls. After a seek, Read
+// drops the records sent before the peer moved the stream.
+func (tr *TCPReader) WriteControl(control *ControlMessage) (n int, err error) {
+
+	tr.controlLock.Lock()
+	defer tr.controlLock.Unlock()
+
+	tr.controlOut.Type = TypeControlMessage
+	tr.controlOut.Payload = control
+
+	if control.Action == ControlSeek {
+		atomic.AddInt64(&tr.pendingSeeks, 1)
+	}
+
+	n, err = tr.tcpPeer.WriteMessage(tr.controlOut)
+	if err != nil {
+
+		if control.Action == ControlSeek {
+			atomic.AddInt64(&tr.pendingSeeks, -1)
+		}
+
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// Position returns the position in the log of the last record read, or -1
+// when unknown. The peer only sends the position of every record when the
+// metadata feature was negotiated.
+func (tr *TCPReader) Position() (position int64) {
+
+	return tr.position
+}
+
+// Count returns the number of records received since the beginning of the
+// stream, including records dropped after a seek.
+func (tr *TCPReader) Count() (count int64) {
+
+	return atomic.LoadInt64(&tr.count)
+}
+
 func (tr *TCPReader) Flush() (err error) {
 
 	err = tr.tcpPeer.Flush()
@@ -122,6 +301,20 @@ func (tr *TCPReader) Read(r *log.Record) (n int, err error) {
 
 	autoFill := false
 
+	// Serve the records of the last batch message before reading
+	// the next message, which would overwrite them.
+	for tr.batchMessage != nil {
+
+		n, err = tr.readBatch(r)
+		if err == errDropped {
+			continue
+		}
+
+		if err != io.EOF {
+			return n, err
+		}
+	}
+
 Retry:
 	if tr.mustFill {
 		if tr.ioMode == recio.ModeManual && !autoFill {
@@ -150,8 +343,53 @@ Retry:
 
 	case *RecordMessage:
 		*r = v.Record
+
+		err = tr.receive(r)
+		if err == errDropped {
+			autoFill = true
+			goto Retry
+		}
+
+		if err != nil {
+			return 0, err
+		}
+
 		return n, nil
 
+	case *BatchMessage:
+		tr.batchMessage = v
+
+		for tr.batchMessage != nil {
+
+			n, err = tr.readBatch(r)
+			if err == errDropped {
+				continue
+			}
+
+			if err != io.EOF {
+				return n, err
+			}
+		}
+
+		// Empty batch or dropped records.
+		autoFill = true
+		goto Retry
+
+	case *ControlMessage:
+		if v.Action == ControlSeek {
+			atomic.AddInt64(&tr.pendingSeeks, -1)
+			tr.nextPosition = v.Position
+		}
+
+		autoFill = true
+		goto Retry
+
+	case *PositionMessage:
+		tr.nextPosition = v.Position
+
+		autoFill = true
+		goto Retry
+
 	case *ErrorMessage:
 		err = GetErrorMessage(v.Code)
 		return 0, err
@@ -168,6 +406,55 @@ Retry:
 	return n, nil
 }
 
+// readBatch reads the next record of the current batch message. It returns
+// io.EOF once the batch was fully read, and errDropped when the record must
+// be skipped.
+func (tr *TCPReader) readBatch(r *log.Record) (n int, err error) {
+
+	n, err = tr.batchMessage.Next(r)
+	if err == io.EOF {
+		tr.batchMessage = nil
+		return 0, err
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	err = tr.receive(r)
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// receive accounts for a record received from the peer. It returns
+// errDropped when the record was sent before a pending seek and must not be
+// handed to the application.
+func (tr *TCPReader) receive(r *log.Record) (err error) {
+
+	atomic.AddInt64(&tr.count, 1)
+
+	tr.position = tr.nextPosition
+	if tr.nextPosition >= 0 {
+		tr.nextPosition++
+	}
+
+	if tr.flowControl {
+		err = tr.consume(r)
+		if err != nil {
+			return err
+		}
+	}
+
+	if atomic.LoadInt64(&tr.pendingSeeks) > 0 {
+		return errDropped
+	}
+
+	return nil
+}
+
 func (tr *TCPReader) HandleError(h ErrorHandler) {
 
 	tr.tcpPeer.errorHandler = h
@@ -22,34 +22,70 @@ import (
 	"github.com/dataptive/styx/pkg/recio"
 )
 
+// closeWriter is implemented by connections supporting half-close, such as
+// TCP and Unix domain socket connections.
+type closeWriter interface {
+	CloseWrite() error
+}
+
+// ProgressHandler is called when the peer reports the number of records it
+// processed since the beginning of the stream, along with the cursor to
+// commit, if any.
+type ProgressHandler func(count int64, cursor string)
+
+// ControlHandler is called when the peer sends a control message.
+type ControlHandler func(control ControlMessage)
+
 type TCPWriter struct {
-	conn          *net.TCPConn
-	ioMode        recio.IOMode
-	tcpPeer       *TCPPeer
-	recordMessage *RecordMessage
-	errorMessage  *ErrorMessage
-	messageIn     *Message
-	messageOut    *Message
-	readerDone    chan struct{}
-	syncHandler   log.SyncHandler
-	errorHandler  ErrorHandler
+	conn            net.Conn
+	ioMode          recio.IOMode
+	bufferSize      int
+	tcpPeer         *TCPPeer
+	recordMessage   *RecordMessage
+	batchMessage    *BatchMessage
+	errorMessage    *ErrorMessage
+	controlMessage  *ControlMessage
+	positionMessage *PositionMessage
+	messageIn       *Message
+	messageOut      *Message
+	readerDone      chan struct{}
+	credits         *Credits
+	flowControl     bool
+	batch           bool
+	metadata        bool
+	nextPosition    int64
+	syncHandler     log.SyncHandler
+	progressHandler ProgressHandler
+	controlHandler  ControlHandler
+	errorHandler    ErrorHandler
 }
 
-func NewTCPWriter(conn *net.TCPConn, writeBufferSize int, readBufferSize int, localTimeout int, remoteTimeout int, ioMode recio.IOMode) (tw *TCPWriter) {
+func NewTCPWriter(conn net.Conn, writeBufferSize int, readBufferSize int, localTimeout int, remoteTimeout int, ioMode recio.IOMode) (tw *TCPWriter) {
 
 	tcpPeer := NewTCPPeer(conn, writeBufferSize, readBufferSize, localTimeout, remoteTimeout, ioMode)
 
 	tw = &TCPWriter{
-		conn:          conn,
-		ioMode:        ioMode,
-		tcpPeer:       tcpPeer,
-		recordMessage: &RecordMessage{},
-		errorMessage:  &ErrorMessage{},
-		messageIn:     &Message{},
-		messageOut:    &Message{},
-		readerDone:    make(chan struct{}),
-		syncHandler:   nil,
-		errorHandler:  nil,
+		conn:            conn,
+		ioMode:          ioMode,
+		bufferSize:      writeBufferSize,
+		tcpPeer:         tcpPeer,
+		recordMessage:   &RecordMessage{},
+		batchMessage:    nil,
+		errorMessage:    &ErrorMessage{},
+		controlMessage:  &ControlMessage{},
+		positionMessage: &PositionMessage{},
+		messageIn:       &Message{},
+		messageOut:      &Message{},
+		readerDone:      make(chan struct{}),
+		credits:         NewCredits(),
+		flowControl:     false,
+		batch:           false,
+		metadata:        false,
+		nextPosition:    -1,
+		syncHandler:     nil,
+		progressHandler: nil,
+		controlHandler:  nil,
+		errorHandler:    nil,
 	}
 
 	go tw.reader()
@@ -59,7 +95,15 @@ func NewTCPWriter(conn *net.TCPConn, writeBufferSize int, readBufferSize int, lo
 
 func (tw *TCPWriter) Close() (err error) {
 
-	err = tw.conn.CloseWrite()
+	// Half-close the connection to signal the end of the stream
+	// while still receiving acks, when the transport supports it.
+	cw, ok := tw.conn.(closeWriter)
+	if ok {
+		err = cw.CloseWrite()
+	} else {
+		err = tw.conn.Close()
+	}
+
 	if err != nil {
 		return err
 	}
@@ -71,6 +115,10 @@ func (tw *TCPWriter) Close() (err error) {
 		return err
 	}
 
+	if !ok {
+		return nil
+	}
+
 	err = tw.conn.Close()
 	if err != nil {
 		return err
@@ -79,8 +127,98 @@ func (tw *TCPWriter) Close() (err error) {
 	return nil
 }
 
+// EnableFlowControl makes Write wait for credits granted by the peer before
+// sending each record. It must be called before the first Write.
+func (tw *TCPWriter) EnableFlowControl() {
+
+	tw.flowControl = true
+}
+
+// EnableBatch makes Write group records in batch messages, sent when full
+// or on Flush. It must be called before the first Write.
+func (tw *TCPWriter) EnableBatch() {
+
+	tw.batch = true
+	tw.batchMessage = newBatchMessage(batchSize(tw.bufferSize))
+}
+
+// EnableMetadata makes WriteAt send the position of records to the peer. It
+// must be called before the first Write.
+func (tw *TCPWriter) EnableMetadata() {
+
+	tw.metadata = true
+}
+
+// WriteAt writes a record read from position. When metadata is enabled, a
+// position message is sent before records not following the previous one.
+func (tw *TCPWriter) WriteAt(r *log.Record, position int64) (n int, err error) {
+
+	if tw.metadata && position != tw.nextPosition {
+
+		// Send pending records before the position.
+		err = tw.writeBatch()
+		if err != nil {
+			return 0, err
+		}
+
+		tw.positionMessage.Position = position
+
+		tw.messageOut.Type = TypePositionMessage
+		tw.messageOut.Payload = tw.positionMessage
+
+		_, err = tw.tcpPeer.WriteMessage(tw.messageOut)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err = tw.Write(r)
+	if err != nil {
+		return 0, err
+	}
+
+	tw.nextPosition = position + 1
+
+	return n, nil
+}
+
 func (tw *TCPWriter) Write(r *log.Record) (n int, err error) {
 
+	if tw.flowControl {
+
+		// Flush buffered records before waiting, the peer
+		// won't grant more credits until it receives them.
+		if !tw.credits.Available() {
+			err = tw.Flush()
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		err = tw.credits.Acquire(len(*r))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if tw.batch {
+
+		if tw.batchMessage.Append(r) {
+			return r.Size(), nil
+		}
+
+		err = tw.writeBatch()
+		if err != nil {
+			return 0, err
+		}
+
+		if tw.batchMessage.Append(r) {
+			return r.Size(), nil
+		}
+
+		// Records larger than a batch are sent on their own.
+	}
+
 	tw.recordMessage.Record = *r
 
 	tw.messageOut.Type = TypeRecordMessage
@@ -94,8 +232,35 @@ func (tw *TCPWriter) Write(r *log.Record) (n int, err error) {
 	return n, nil
 }
 
+// writeBatch writes the pending batch message, if any. The batch is kept
+// when the peer must be flushed first.
+func (tw *TCPWriter) writeBatch() (err error) {
+
+	if !tw.batch || tw.batchMessage.Count == 0 {
+		return nil
+	}
+
+	tw.messageOut.Type = TypeBatchMessage
+	tw.messageOut.Payload = tw.batchMessage
+
+	_, err = tw.tcpPeer.WriteMessage(tw.messageOut)
+	if err != nil {
+		return err
+	}
+
+	tw.batchMessage.Reset()
+
+	return nil
+}
+
 func (tw *TCPWriter) WriteError(er error) (n int, err error) {
 
+	// Send pending records before the error.
+	err = tw.writeBatch()
+	if err != nil {
+		return 0, err
+	}
+
 	tw.errorMessage.Code = GetErrorCode(er)
 
 	tw.messageOut.Type = TypeErrorMessage
@@ -109,8 +274,50 @@ func (tw *TCPWriter) WriteError(er error) (n int, err error) {
 	return n, nil
 }
 
+// WriteSeek tells the peer that the records following were read from
+// position, after it asked to seek.
+func (tw *TCPWriter) WriteSeek(position int64) (n int, err error) {
+
+	// Send pending records before the seek.
+	err = tw.writeBatch()
+	if err != nil {
+		return 0, err
+	}
+
+	tw.controlMessage.Action = ControlSeek
+	tw.controlMessage.Position = position
+
+	tw.messageOut.Type = TypeControlMessage
+	tw.messageOut.Payload = tw.controlMessage
+
+	n, err = tw.tcpPeer.WriteMessage(tw.messageOut)
+	if err != nil {
+		return 0, err
+	}
+
+	// The peer knows the position of the records following.
+	tw.nextPosition = position
+
+	return n, nil
+}
+
 func (tw *TCPWriter) Flush() (err error) {
 
+	err = tw.writeBatch()
+	if err == recio.ErrMustFlush {
+
+		err = tw.tcpPeer.Flush()
+		if err != nil {
+			return err
+		}
+
+		err = tw.writeBatch()
+	}
+
+	if err != nil {
+		return err
+	}
+
 	err = tw.tcpPeer.Flush()
 	if err != nil {
 		return err
@@ -124,6 +331,16 @@ func (tw *TCPWriter) HandleSync(h log.SyncHandler) {
 	tw.syncHandler = h
 }
 
+func (tw *TCPWriter) HandleProgress(h ProgressHandler) {
+
+	tw.progressHandler = h
+}
+
+func (tw *TCPWriter) HandleControl(h ControlHandler) {
+
+	tw.controlHandler = h
+}
+
 func (tw *TCPWriter) HandleError(h ErrorHandler) {
 
 	tw.errorHandler = h
@@ -132,6 +349,7 @@ func (tw *TCPWriter) HandleError(h ErrorHandler) {
 
 func (tw *TCPWriter) reader() {
 
+loop:
 	for {
 
 		_, err := tw.tcpPeer.ReadMessage(tw.messageIn)
@@ -170,6 +388,25 @@ func (tw *TCPWriter) reader() {
 
 			continue
 
+		case *CreditMessage:
+			tw.credits.Grant(v.Records, v.Bytes)
+
+			continue
+
+		case *ProgressMessage:
+			if tw.progressHandler != nil {
+				tw.progressHandler(v.Count, v.Cursor)
+			}
+
+			continue
+
+		case *ControlMessage:
+			if tw.controlHandler != nil {
+				tw.controlHandler(*v)
+			}
+
+			continue
+
 		case *ErrorMessage:
 			err = GetErrorMessage(v.Code)
 
@@ -178,7 +415,7 @@ func (tw *TCPWriter) reader() {
 			}
 
 			// Shutdown goroutine.
-			break
+			break loop
 
 		case *HeartbeatMessage:
 			// Ignore.
@@ -193,5 +430,8 @@ func (tw *TCPWriter) reader() {
 		}
 	}
 
+	// Unblock writes waiting for credits.
+	tw.credits.Close()
+
 	tw.readerDone <- struct{}{}
 }
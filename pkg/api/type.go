@@ -15,17 +15,37 @@
 package api
 
 import (
+	"encoding/json"
 	"errors"
+	"time"
 
 	"github.com/dataptive/styx/internal/logman"
 	"github.com/dataptive/styx/pkg/log"
 )
 
 const (
-	TimeoutHeaderName     = "X-Styx-Timeout"
-	RecordLinesMediaType  = "application/vnd.styx.line-delimited"
-	RecordBinaryMediaType = "application/vnd.styx.binary-records"
-	StyxProtocolString    = "styx/0"
+	TimeoutHeaderName      = "X-Styx-Timeout"
+	PositionHeaderName     = "X-Styx-Position"
+	NextPositionHeaderName = "X-Styx-Next-Position"
+	LastEventIDHeaderName  = "Last-Event-ID"
+	EventIDHeaderName      = "X-Styx-Event-ID"
+	RecordLinesMediaType   = "application/vnd.styx.line-delimited"
+	RecordBinaryMediaType  = "application/vnd.styx.binary-records"
+	RecordNDJSONMediaType  = "application/x-ndjson"
+	RecordJSONMediaType    = "application/vnd.styx.records+json"
+	EventStreamMediaType   = "text/event-stream"
+	CompressionHeaderName  = "X-Styx-Compression"
+	FeaturesHeaderName     = "X-Styx-Features"
+	StyxProtocolString     = "styx/0"
+)
+
+const (
+	ActionSubscribe   = "subscribe"
+	ActionUnsubscribe = "unsubscribe"
+	ActionSeek        = "seek"
+	ActionPause       = "pause"
+	ActionResume      = "resume"
+	ActionCount       = "count"
 )
 
 var (
@@ -34,24 +54,75 @@ var (
 
 //
 type LogInfo struct {
-	Name          string           `json:"name"`
-	Status        logman.LogStatus `json:"status"`
-	RecordCount   int64            `json:"record_count"`
-	FileSize      int64            `json:"file_size"`
-	StartPosition int64            `json:"start_position"`
-	EndPosition   int64            `json:"end_position"`
+	Name             string             `json:"name"`
+	Status           logman.LogStatus   `json:"status"`
+	RecordCount      int64              `json:"record_count"`
+	FileSize         int64              `json:"file_size"`
+	StartPosition    int64              `json:"start_position"`
+	EndPosition      int64              `json:"end_position"`
+	WriteRecordsRate float64            `json:"write_records_rate"`
+	WriteBytesRate   float64            `json:"write_bytes_rate"`
+	ReadRecordsRate  float64            `json:"read_records_rate"`
+	ReadBytesRate    float64            `json:"read_bytes_rate"`
+	Owner            string             `json:"owner"`
+	Description      string             `json:"description"`
+	SchemaURL        string             `json:"schema_url"`
+	Labels           map[string]string  `json:"labels"`
+	LastScan         *logman.ScanResult `json:"last_scan"`
+}
+
+//
+type StorageInfo struct {
+	Status logman.StorageStatus `json:"status"`
+	Usage  int64                `json:"usage"`
+	Free   int64                `json:"free"`
+}
+
+// HealthInfo reports the server process is alive.
+type HealthInfo struct {
+	Status string `json:"status"`
+}
+
+// ReadinessInfo reports whether the logs required by the readiness rules are
+// all available, UnavailableLogs counting those which aren't.
+type ReadinessInfo struct {
+	Ready           bool `json:"ready"`
+	UnavailableLogs int  `json:"unavailable_logs"`
+}
+
+// ServerStatus summarizes the state of the server. Uptime is in seconds and
+// Logs counts logs by status.
+type ServerStatus struct {
+	Version     string                   `json:"version"`
+	Uptime      int64                    `json:"uptime"`
+	Ready       bool                     `json:"ready"`
+	Storage     StorageInfo              `json:"storage"`
+	Logs        map[logman.LogStatus]int `json:"logs"`
+	Connections int64                    `json:"connections"`
+}
+
+// ReloadResponse lists the settings of the reloaded config file which only
+// apply on restart.
+type ReloadResponse struct {
+	RestartRequired []string `json:"restart_required"`
 }
 
 //
 type LogConfig struct {
-	MaxRecordSize   int   `schema:"max_record_size"`
-	IndexAfterSize  int64 `schema:"index_after_size"`
-	SegmentMaxCount int64 `schema:"segment_max_count"`
-	SegmentMaxSize  int64 `schema:"segment_max_size"`
-	SegmentMaxAge   int64 `schema:"segment_max_age"`
-	LogMaxCount     int64 `schema:"log_max_count"`
-	LogMaxSize      int64 `schema:"log_max_size"`
-	LogMaxAge       int64 `schema:"log_max_age"`
+	MaxRecordSize   int   `schema:"max_record_size" json:"max_record_size"`
+	IndexAfterSize  int64 `schema:"index_after_size" json:"index_after_size"`
+	SegmentMaxCount int64 `schema:"segment_max_count" json:"segment_max_count"`
+	SegmentMaxSize  int64 `schema:"segment_max_size" json:"segment_max_size"`
+	SegmentMaxAge   int64 `schema:"segment_max_age" json:"segment_max_age"`
+	LogMaxCount     int64 `schema:"log_max_count" json:"log_max_count"`
+	LogMaxSize      int64 `schema:"log_max_size" json:"log_max_size"`
+	LogMaxAge       int64 `schema:"log_max_age" json:"log_max_age"`
+}
+
+//
+type ListLogsParams struct {
+	Namespace string   `schema:"namespace"`
+	Labels    []string `schema:"label"`
 }
 
 //
@@ -61,6 +132,7 @@ type ListLogsResponse []LogInfo
 type CreateLogForm struct {
 	Name string `schema:"name,required"`
 	*LogConfig
+	LogMetadata
 }
 
 //
@@ -69,23 +141,176 @@ type CreateLogResponse LogInfo
 //
 type GetLogResponse LogInfo
 
+//
+type LogMetadata struct {
+	Owner       string   `schema:"owner"`
+	Description string   `schema:"description"`
+	SchemaURL   string   `schema:"schema_url"`
+	Labels      []string `schema:"label"`
+}
+
+//
+type SetLogMetadataForm LogMetadata
+
+//
+type SetLogMetadataResponse LogInfo
+
+//
+type RenameLogForm struct {
+	Name     string `schema:"name,required"`
+	AliasTTL int64  `schema:"alias_ttl"`
+}
+
+//
+type RenameLogResponse LogInfo
+
+//
+type ScanLogResponse LogInfo
+
 //
 type RestoreLogParams struct {
 	Name string `schema:"name,required"`
 }
 
+// Event reports a change in the lifecycle of a log.
+type Event struct {
+	ID             int64            `json:"id"`
+	Time           time.Time        `json:"time"`
+	Type           logman.EventType `json:"type"`
+	Name           string           `json:"name"`
+	Status         logman.LogStatus `json:"status"`
+	PreviousStatus logman.LogStatus `json:"previous_status,omitempty"`
+	PreviousName   string           `json:"previous_name,omitempty"`
+}
+
+// ListEventsParams selects the events following the one with ID Since,
+// waiting up to Timeout seconds for one when there are none yet.
+type ListEventsParams struct {
+	Since   int64 `schema:"since"`
+	Timeout int64 `schema:"timeout"`
+}
+
+//
+type ListEventsResponse []Event
+
+//
+type NamespaceInfo struct {
+	Name      string    `json:"name"`
+	LogCount  int64     `json:"log_count"`
+	Usage     int64     `json:"usage"`
+	LogConfig LogConfig `json:"log_config"`
+	MaxLogs   int64     `json:"max_logs"`
+	MaxUsage  int64     `json:"max_usage"`
+}
+
+//
+type ListNamespacesResponse []NamespaceInfo
+
+//
+type GetNamespaceResponse NamespaceInfo
+
+//
+type SetNamespaceForm struct {
+	*LogConfig
+	MaxLogs  int64 `schema:"max_logs"`
+	MaxUsage int64 `schema:"max_usage"`
+}
+
+//
+type SetNamespaceResponse NamespaceInfo
+
+//
+type SessionInfo struct {
+	ID                int64     `json:"id"`
+	Log               string    `json:"log"`
+	Client            string    `json:"client"`
+	RemoteAddr        string    `json:"remote_addr"`
+	StartedAt         time.Time `json:"started_at"`
+	SentPosition      int64     `json:"sent_position"`
+	ProcessedPosition int64     `json:"processed_position"`
+	Lag               int64     `json:"lag"`
+	Cursor            string    `json:"cursor"`
+	ProgressAt        time.Time `json:"progress_at"`
+}
+
+//
+type ListSessionsResponse []SessionInfo
+
+//
+type CursorInfo struct {
+	Name     string `json:"name"`
+	Position int64  `json:"position"`
+}
+
+//
+type ListCursorsResponse []CursorInfo
+
 //
 type ProduceResponse struct {
 	Position int64 `json:"position"`
 	Count    int64 `json:"count"`
 }
 
+//
+type JSONRecord struct {
+	Position      int64           `json:"position"`
+	Offset        int64           `json:"offset"`
+	Payload       json.RawMessage `json:"payload,omitempty"`
+	PayloadBase64 []byte          `json:"payload_base64,omitempty"`
+}
+
+//
+type MultiplexControl struct {
+	Action   string     `json:"action"`
+	ID       uint32     `json:"id"`
+	Name     string     `json:"name"`
+	Whence   log.Whence `json:"whence"`
+	Position int64      `json:"position"`
+	Count    int64      `json:"count"`
+	Follow   bool       `json:"follow"`
+	Filter   string     `json:"filter"`
+}
+
+//
+type MultiplexEvent struct {
+	ID uint32 `json:"id"`
+	*JSONRecord
+	End   string `json:"end,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
 //
 type ConsumeParams struct {
 	Whence   log.Whence `schema:"whence"`
 	Position int64      `schema:"position"`
 	Count    int64      `schema:"count"`
 	Follow   bool       `schema:"follow"`
+	Filter   string     `schema:"filter"`
+}
+
+//
+type FlowControlParams struct {
+	FlowControl bool `schema:"flow_control"`
+}
+
+//
+type MetadataParams struct {
+	Metadata bool `schema:"metadata"`
+}
+
+//
+type Credit struct {
+	Records int64 `json:"records"`
+	Bytes   int64 `json:"bytes"`
+}
+
+//
+type ConsumerControl struct {
+	Action   string     `json:"action"`
+	Whence   log.Whence `json:"whence,omitempty"`
+	Position int64      `json:"position"`
+	Count    int64      `json:"count,omitempty"`
+	Error    string     `json:"error,omitempty"`
 }
 
 //
@@ -15,17 +15,32 @@
 package client
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/dataptive/styx/pkg/api"
+	"github.com/dataptive/styx/pkg/compress"
 
 	"github.com/gorilla/schema"
 )
 
 var (
+	DefaultClientOptions = ClientOptions{
+		TLSConfig:   nil,
+		Token:       "",
+		Compression: "",
+	}
+
 	DefaultLogConfig = LogConfig{
 		MaxRecordSize:   1 << 20, // 1MB
 		IndexAfterSize:  1 << 20, // 1MB
@@ -40,16 +55,63 @@ var (
 
 //
 type Client struct {
-	baseURL    string
-	httpClient *http.Client
+	baseURL     string
+	httpClient  *http.Client
+	dial        dialer
+	tlsConfig   *tls.Config
+	token       string
+	compression string
 }
 
 //
+type ClientOptions struct {
+	// TLSConfig is used with https:// and styxs:// base URLs, nil
+	// meaning the system roots are used to verify the server.
+	TLSConfig *tls.Config
+
+	// Token is sent to servers requiring authentication.
+	Token string
+
+	// Compression is the codec requested to compress styx protocol
+	// streams and JSON records, among compress.Gzip and compress.Snappy.
+	// Servers not supporting it fall back to uncompressed streams.
+	Compression string
+}
+
+// NewClient returns a client for the server at baseURL. Besides http and
+// https URLs, unix:///path/to/socket URLs reach the server through its Unix
+// domain socket and styx://host:port URLs through its raw styx protocol
+// listener, styxs://host:port when it uses TLS.
 func NewClient(baseURL string) (c *Client) {
 
+	return NewClientWithOptions(baseURL, DefaultClientOptions)
+}
+
+//
+func NewClientWithOptions(baseURL string, options ClientOptions) (c *Client) {
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// The transport adds HTTP/2 to the config, keep ours untouched.
+	transport.TLSClientConfig = options.TLSConfig.Clone()
+
 	c = &Client{
-		baseURL:    baseURL,
-		httpClient: &http.Client{},
+		baseURL:     baseURL,
+		httpClient:  &http.Client{Transport: newTokenTransport(transport, options.Token)},
+		dial:        dialTCP,
+		tlsConfig:   options.TLSConfig,
+		token:       options.Token,
+		compression: options.Compression,
+	}
+
+	u, err := url.Parse(baseURL)
+	if err == nil && u.Scheme == UnixScheme {
+
+		c.baseURL = unixBaseURL
+		c.dial = dialUnix(u.Path)
+
+		transport.DialContext = func(ctx context.Context, network string, address string) (conn net.Conn, err error) {
+			return c.dial(address)
+		}
 	}
 
 	return c
@@ -58,8 +120,38 @@ func NewClient(baseURL string) (c *Client) {
 //
 func (c *Client) ListLogs() (r ListLogsResponse, err error) {
 
+	return c.ListNamespaceLogs("")
+}
+
+// ListNamespaceLogs returns the logs nested in the namespace, all logs when
+// namespace is empty.
+func (c *Client) ListNamespaceLogs(namespace string) (r ListLogsResponse, err error) {
+
+	params := ListLogsParams{
+		Namespace: namespace,
+	}
+
+	return c.ListLogsWithParams(params)
+}
+
+// ListLogsWithParams returns the logs nested in the params namespace and
+// holding all the params labels, given as "name=value" pairs or label names.
+func (c *Client) ListLogsWithParams(params ListLogsParams) (r ListLogsResponse, err error) {
+
 	endpoint := fmt.Sprintf("%s/logs", c.baseURL)
 
+	encoder := schema.NewEncoder()
+	query := url.Values{}
+
+	err = encoder.Encode(params, query)
+	if err != nil {
+		return r, err
+	}
+
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
 	resp, err := c.httpClient.Get(endpoint)
 	if err != nil {
 		return r, err
@@ -79,13 +171,20 @@ func (c *Client) ListLogs() (r ListLogsResponse, err error) {
 //
 func (c *Client) CreateLog(name string, config LogConfig) (r CreateLogResponse, err error) {
 
+	return c.CreateLogWithMetadata(name, config, LogMetadata{})
+}
+
+// CreateLogWithMetadata creates a log described by metadata.
+func (c *Client) CreateLogWithMetadata(name string, config LogConfig, metadata LogMetadata) (r CreateLogResponse, err error) {
+
 	endpoint := fmt.Sprintf("%s/logs", c.baseURL)
 
 	encoder := schema.NewEncoder()
 
 	logForm := createLogForm{
-		Name:      name,
-		LogConfig: &config,
+		Name:        name,
+		LogConfig:   &config,
+		LogMetadata: metadata,
 	}
 	form := url.Values{}
 
@@ -94,6 +193,8 @@ func (c *Client) CreateLog(name string, config LogConfig) (r CreateLogResponse,
 		return r, err
 	}
 
+	encodeLabels(metadata.Labels, form)
+
 	resp, err := c.httpClient.PostForm(endpoint, form)
 	if err != nil {
 		return r, err
@@ -110,6 +211,52 @@ func (c *Client) CreateLog(name string, config LogConfig) (r CreateLogResponse,
 	return r, nil
 }
 
+// SetLogMetadata replaces the metadata of the named log.
+func (c *Client) SetLogMetadata(name string, metadata LogMetadata) (r SetLogMetadataResponse, err error) {
+
+	endpoint := fmt.Sprintf("%s/logs/%s/metadata", c.baseURL, name)
+
+	encoder := schema.NewEncoder()
+	form := url.Values{}
+
+	err = encoder.Encode(metadata, form)
+	if err != nil {
+		return r, err
+	}
+
+	encodeLabels(metadata.Labels, form)
+
+	req, err := http.NewRequest(http.MethodPut, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return r, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return r, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = api.ReadError(resp.Body)
+		return r, err
+	}
+
+	api.ReadResponse(resp.Body, &r)
+
+	return r, nil
+}
+
+// encodeLabels adds labels to form as "name=value" pairs.
+func encodeLabels(labels map[string]string, form url.Values) {
+
+	for name, value := range labels {
+		form.Add("label", name+"="+value)
+	}
+}
+
 //
 func (c *Client) GetLog(name string) (r GetLogResponse, err error) {
 
@@ -131,6 +278,119 @@ func (c *Client) GetLog(name string) (r GetLogResponse, err error) {
 	return r, nil
 }
 
+// ListSessions returns the consumer connections reading the named log.
+func (c *Client) ListSessions(name string) (r ListSessionsResponse, err error) {
+
+	endpoint := fmt.Sprintf("%s/logs/%s/sessions", c.baseURL, name)
+
+	resp, err := c.httpClient.Get(endpoint)
+	if err != nil {
+		return r, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = api.ReadError(resp.Body)
+		return r, err
+	}
+
+	api.ReadResponse(resp.Body, &r)
+
+	return r, nil
+}
+
+// ListCursors returns the positions committed to the cursors of the named
+// log.
+func (c *Client) ListCursors(name string) (r ListCursorsResponse, err error) {
+
+	endpoint := fmt.Sprintf("%s/logs/%s/cursors", c.baseURL, name)
+
+	resp, err := c.httpClient.Get(endpoint)
+	if err != nil {
+		return r, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = api.ReadError(resp.Body)
+		return r, err
+	}
+
+	api.ReadResponse(resp.Body, &r)
+
+	return r, nil
+}
+
+// GetStorage returns the storage usage of the server against its quota.
+func (c *Client) GetStorage() (r StorageInfo, err error) {
+
+	endpoint := fmt.Sprintf("%s/storage", c.baseURL)
+
+	resp, err := c.httpClient.Get(endpoint)
+	if err != nil {
+		return r, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = api.ReadError(resp.Body)
+		return r, err
+	}
+
+	api.ReadResponse(resp.Body, &r)
+
+	return r, nil
+}
+
+// GetStatus returns the version, uptime, readiness, storage usage, log counts
+// by status and open connections of the server.
+func (c *Client) GetStatus() (r ServerStatus, err error) {
+
+	endpoint := fmt.Sprintf("%s/status", c.baseURL)
+
+	resp, err := c.httpClient.Get(endpoint)
+	if err != nil {
+		return r, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = api.ReadError(resp.Body)
+		return r, err
+	}
+
+	api.ReadResponse(resp.Body, &r)
+
+	return r, nil
+}
+
+// Reload makes the server load its config file again, returning the changed
+// settings which only apply on restart.
+func (c *Client) Reload() (r ReloadResponse, err error) {
+
+	endpoint := fmt.Sprintf("%s/reload", c.baseURL)
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, nil)
+	if err != nil {
+		return r, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return r, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = api.ReadError(resp.Body)
+		return r, err
+	}
+
+	api.ReadResponse(resp.Body, &r)
+
+	return r, nil
+}
+
 //
 func (c *Client) DeleteLog(name string) (err error) {
 
@@ -155,6 +415,111 @@ func (c *Client) DeleteLog(name string) (err error) {
 	return nil
 }
 
+// ListNamespaces returns the namespaces holding logs.
+func (c *Client) ListNamespaces() (r ListNamespacesResponse, err error) {
+
+	endpoint := fmt.Sprintf("%s/namespaces", c.baseURL)
+
+	resp, err := c.httpClient.Get(endpoint)
+	if err != nil {
+		return r, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = api.ReadError(resp.Body)
+		return r, err
+	}
+
+	api.ReadResponse(resp.Body, &r)
+
+	return r, nil
+}
+
+// GetNamespace returns the details of the named namespace.
+func (c *Client) GetNamespace(name string) (r GetNamespaceResponse, err error) {
+
+	endpoint := fmt.Sprintf("%s/namespaces/%s", c.baseURL, name)
+
+	resp, err := c.httpClient.Get(endpoint)
+	if err != nil {
+		return r, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = api.ReadError(resp.Body)
+		return r, err
+	}
+
+	api.ReadResponse(resp.Body, &r)
+
+	return r, nil
+}
+
+// SetNamespace creates the named namespace or replaces its config.
+func (c *Client) SetNamespace(name string, config NamespaceConfig) (r SetNamespaceResponse, err error) {
+
+	endpoint := fmt.Sprintf("%s/namespaces/%s", c.baseURL, name)
+
+	encoder := schema.NewEncoder()
+	form := url.Values{}
+
+	err = encoder.Encode(config, form)
+	if err != nil {
+		return r, err
+	}
+
+	body := strings.NewReader(form.Encode())
+
+	req, err := http.NewRequest(http.MethodPut, endpoint, body)
+	if err != nil {
+		return r, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return r, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = api.ReadError(resp.Body)
+		return r, err
+	}
+
+	api.ReadResponse(resp.Body, &r)
+
+	return r, nil
+}
+
+// DeleteNamespace deletes the named namespace along with all the logs and
+// namespaces it holds.
+func (c *Client) DeleteNamespace(name string) (err error) {
+
+	endpoint := fmt.Sprintf("%s/namespaces/%s", c.baseURL, name)
+
+	req, err := http.NewRequest(http.MethodDelete, endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = api.ReadError(resp.Body)
+		return err
+	}
+
+	return nil
+}
+
 //
 func (c *Client) TruncateLog(name string) (err error) {
 
@@ -179,6 +544,103 @@ func (c *Client) TruncateLog(name string) (err error) {
 	return nil
 }
 
+// RenameLog moves the named log to newName. When aliasTTL is positive, the
+// log remains reachable under its previous name for aliasTTL seconds.
+func (c *Client) RenameLog(name string, newName string, aliasTTL int) (r RenameLogResponse, err error) {
+
+	endpoint := fmt.Sprintf("%s/logs/%s/rename", c.baseURL, name)
+
+	encoder := schema.NewEncoder()
+
+	renameForm := renameLogForm{
+		Name:     newName,
+		AliasTTL: aliasTTL,
+	}
+	form := url.Values{}
+
+	err = encoder.Encode(renameForm, form)
+	if err != nil {
+		return r, err
+	}
+
+	resp, err := c.httpClient.PostForm(endpoint, form)
+	if err != nil {
+		return r, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = api.ReadError(resp.Body)
+		return r, err
+	}
+
+	api.ReadResponse(resp.Body, &r)
+
+	return r, nil
+}
+
+// ScanLog checks and repairs the files of the named log, and reopens it.
+func (c *Client) ScanLog(name string) (r ScanLogResponse, err error) {
+
+	endpoint := fmt.Sprintf("%s/logs/%s/scan", c.baseURL, name)
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, nil)
+	if err != nil {
+		return r, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return r, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = api.ReadError(resp.Body)
+		return r, err
+	}
+
+	api.ReadResponse(resp.Body, &r)
+
+	return r, nil
+}
+
+// ListEvents long polls the lifecycle events of the logs the client can
+// read. It returns the ID of the last event considered, to be given as
+// params.Since on the next call.
+func (c *Client) ListEvents(params ListEventsParams) (events []Event, lastID int64, err error) {
+
+	encoder := schema.NewEncoder()
+	queryParams := url.Values{}
+
+	err = encoder.Encode(params, queryParams)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	endpoint := fmt.Sprintf("%s/events?%s", c.baseURL, queryParams.Encode())
+
+	resp, err := c.httpClient.Get(endpoint)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = api.ReadError(resp.Body)
+		return nil, 0, err
+	}
+
+	lastID, err = strconv.ParseInt(resp.Header.Get(api.EventIDHeaderName), 10, 64)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	api.ReadResponse(resp.Body, &events)
+
+	return events, lastID, nil
+}
+
 //
 func (c *Client) BackupLog(name string, w io.Writer) (err error) {
 
@@ -227,6 +689,113 @@ func (c *Client) RestoreLog(name string, r io.Reader) (err error) {
 	return nil
 }
 
+//
+func (c *Client) ReadJSON(name string, params ConsumerParams) (records []JSONRecord, nextPosition int64, err error) {
+
+	encoder := schema.NewEncoder()
+	queryParams := url.Values{}
+
+	err = encoder.Encode(params, queryParams)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	endpoint := fmt.Sprintf("%s/logs/%s/records?%s", c.baseURL, name, queryParams.Encode())
+
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req.Header.Add("Accept", api.RecordJSONMediaType)
+
+	if c.compression != "" {
+		req.Header.Add("Accept-Encoding", c.compression)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = api.ReadError(resp.Body)
+		return nil, 0, err
+	}
+
+	body, err := compress.NewReader(resp.Header.Get("Content-Encoding"), resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer body.Close()
+
+	err = json.NewDecoder(body).Decode(&records)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Drain body so that trailers are available.
+	_, err = io.Copy(ioutil.Discard, body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	_, err = io.Copy(ioutil.Discard, resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	nextPosition, err = strconv.ParseInt(resp.Trailer.Get(api.NextPositionHeaderName), 10, 64)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return records, nextPosition, nil
+}
+
+//
+func (c *Client) WriteJSON(name string, records []JSONRecord) (r ProduceResponse, err error) {
+
+	endpoint := fmt.Sprintf("%s/logs/%s/records", c.baseURL, name)
+
+	body, err := json.Marshal(records)
+	if err != nil {
+		return r, err
+	}
+
+	body, err = c.compressBody(body)
+	if err != nil {
+		return r, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return r, err
+	}
+
+	req.Header.Add("Content-Type", api.RecordJSONMediaType)
+
+	if c.compression != "" && c.compression != compress.Identity {
+		req.Header.Add("Content-Encoding", c.compression)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return r, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = api.ReadError(resp.Body)
+		return r, err
+	}
+
+	api.ReadResponse(resp.Body, &r)
+
+	return r, nil
+}
+
 // func (c *Client) Produce(name string, record log.Record) (r ProduceResponse, err error) {
 
 // 	endpoint := fmt.Sprintf("%s/logs/%s/records", c.baseURL, name)
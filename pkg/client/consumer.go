@@ -15,13 +15,11 @@
 package client
 
 import (
-	"bufio"
+	"errors"
 	"net"
 	"net/http"
 	"net/url"
-	"strconv"
 
-	"github.com/dataptive/styx/pkg/api"
 	"github.com/dataptive/styx/pkg/api/tcp"
 	"github.com/dataptive/styx/pkg/log"
 	"github.com/dataptive/styx/pkg/recio"
@@ -35,6 +33,9 @@ var (
 		ReadBufferSize:  1 << 20, // 1 MB
 		WriteBufferSize: 1 << 20, // 1 MB
 		IOMode:          recio.ModeAuto,
+		WindowRecords:   0,       // Unlimited
+		WindowBytes:     4 << 20, // 4 MB
+		Batch:           true,
 	}
 
 	DefaultConsumerParams = ConsumerParams{
@@ -43,6 +44,10 @@ var (
 		Count:    -1,
 		Follow:   false,
 	}
+
+	ErrProgressUnsupported = errors.New("client: server does not support progress reports")
+	ErrControlUnsupported  = errors.New("client: server does not support stream controls")
+	ErrMetadataUnsupported = errors.New("client: server does not support metadata")
 )
 
 const (
@@ -54,7 +59,8 @@ const (
 
 //
 type Consumer struct {
-	reader *tcp.TCPReader
+	reader       *tcp.TCPReader
+	capabilities tcp.Capabilities
 }
 
 //
@@ -63,6 +69,7 @@ type ConsumerParams struct {
 	Position int64  `schema:"position"`
 	Count    int64  `schema:"count"`
 	Follow   bool   `schema:"follow"`
+	Filter   string `schema:"filter,omitempty"`
 }
 
 //
@@ -71,80 +78,91 @@ type ConsumerOptions struct {
 	ReadBufferSize  int
 	WriteBufferSize int
 	IOMode          recio.IOMode
+	WindowRecords   int64
+	WindowBytes     int64
+	Batch           bool
 }
 
 //
 func (c *Client) NewConsumer(name string, params ConsumerParams, options ConsumerOptions) (co *Consumer, err error) {
 
-	encoder := schema.NewEncoder()
-	queryParams := url.Values{}
+	var conn net.Conn
+	var remoteTimeout int
+	var caps tcp.Capabilities
 
-	err = encoder.Encode(params, queryParams)
-	if err != nil {
-		return nil, err
-	}
+	offered := []string{tcp.FeatureCompression, tcp.FeatureMetadata, tcp.FeatureProgress, tcp.FeatureControl}
 
-	endpoint := c.baseURL + "/logs/" + name + "/records?" + queryParams.Encode()
+	// Flow control is only requested when a window is set, the server
+	// wouldn't send anything otherwise.
+	if options.WindowRecords > 0 || options.WindowBytes > 0 {
+		offered = append(offered, tcp.FeatureFlowControl)
+	}
 
-	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
-	if err != nil {
-		return nil, err
+	if options.Batch {
+		offered = append(offered, tcp.FeatureBatch)
 	}
 
-	req.Header.Add("Connection", "upgrade")
-	req.Header.Add("Upgrade", api.StyxProtocolString)
-	req.Header.Add(api.TimeoutHeaderName, strconv.Itoa(options.ReadTimeout))
+	features := c.features(offered)
 
-	var tcpConn *net.TCPConn
+	if IsStyxURL(c.baseURL) {
 
-	u, err := url.Parse(endpoint)
-	if err != nil {
-		return nil, err
-	}
+		request := &tcp.HandshakeRequest{
+			Direction: tcp.DirectionRead,
+			Timeout:   options.ReadTimeout,
+			Name:      name,
+			Whence:    params.Whence,
+			Position:  params.Position,
+			Count:     params.Count,
+			Follow:    params.Follow,
+			Filter:    params.Filter,
+			Features:  features,
+		}
 
-	conn, err := net.Dial("tcp", u.Host)
-	if err != nil {
-		return nil, err
-	}
+		conn, remoteTimeout, caps, err = c.dialStyx(request)
+	} else {
 
-	err = req.Write(conn)
-	if err != nil {
-		return nil, err
-	}
+		encoder := schema.NewEncoder()
+		queryParams := url.Values{}
 
-	br := bufio.NewReader(newByteReader(conn))
+		err = encoder.Encode(params, queryParams)
+		if err != nil {
+			return nil, err
+		}
 
-	resp, err := http.ReadResponse(br, req)
-	if err != nil {
-		return nil, err
+		endpoint := c.baseURL + "/logs/" + name + "/records?" + queryParams.Encode()
+
+		conn, remoteTimeout, caps, err = c.upgrade(http.MethodGet, endpoint, options.ReadTimeout, features)
 	}
 
-	if resp.StatusCode != http.StatusSwitchingProtocols {
-		err = api.ReadError(resp.Body)
+	if err != nil {
 		return nil, err
 	}
 
-	var remoteTimeout int
+	reader := tcp.NewTCPReader(conn, options.WriteBufferSize, options.ReadBufferSize, options.ReadTimeout, remoteTimeout, options.IOMode)
 
-	rawTimeout := resp.Header.Get(api.TimeoutHeaderName)
-	if rawTimeout != "" {
-		remoteTimeout, err = strconv.Atoi(rawTimeout)
+	if caps.Has(tcp.FeatureFlowControl) {
+		err = reader.EnableFlowControl(options.WindowRecords, options.WindowBytes)
 		if err != nil {
+			reader.Close()
 			return nil, err
 		}
 	}
 
-	tcpConn = conn.(*net.TCPConn)
-
-	reader := tcp.NewTCPReader(tcpConn, options.WriteBufferSize, options.ReadBufferSize, options.ReadTimeout, remoteTimeout, options.IOMode)
-
 	co = &Consumer{
-		reader: reader,
+		reader:       reader,
+		capabilities: caps,
 	}
 
 	return co, nil
 }
 
+// Capabilities returns the protocol version and the features negotiated
+// with the server.
+func (co *Consumer) Capabilities() (caps tcp.Capabilities) {
+
+	return co.capabilities
+}
+
 //
 func (co *Consumer) Read(r *log.Record) (n int, err error) {
 
@@ -156,6 +174,139 @@ func (co *Consumer) Read(r *log.Record) (n int, err error) {
 	return n, nil
 }
 
+// Position returns the position in the log of the last record read.
+func (co *Consumer) Position() (position int64, err error) {
+
+	if !co.capabilities.Has(tcp.FeatureMetadata) {
+		return -1, ErrMetadataUnsupported
+	}
+
+	return co.reader.Position(), nil
+}
+
+// Ack reports to the server that all the records read so far were
+// processed.
+func (co *Consumer) Ack() (err error) {
+
+	return co.progress("")
+}
+
+// Commit reports to the server that all the records read so far were
+// processed, and commits the position following them to the named cursor.
+func (co *Consumer) Commit(cursor string) (err error) {
+
+	return co.progress(cursor)
+}
+
+func (co *Consumer) progress(cursor string) (err error) {
+
+	if !co.capabilities.Has(tcp.FeatureProgress) {
+		return ErrProgressUnsupported
+	}
+
+	// Records dropped after a seek count as processed.
+	count := co.reader.Count()
+
+	_, err = co.reader.WriteProgress(count, cursor)
+	if err == recio.ErrMustFlush {
+
+		err = co.reader.Flush()
+		if err != nil {
+			return err
+		}
+
+		_, err = co.reader.WriteProgress(count, cursor)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = co.reader.Flush()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Seek moves the stream to position relative to whence. Records read
+// afterwards come from the new position, records sent from the previous one
+// in the meantime being dropped. It may be called while another goroutine
+// reads records.
+func (co *Consumer) Seek(position int64, whence string) (err error) {
+
+	control := &tcp.ControlMessage{
+		Action:   tcp.ControlSeek,
+		Whence:   whence,
+		Position: position,
+	}
+
+	return co.control(control)
+}
+
+// Pause stops the server from sending records until Resume is called.
+// Records already sent can still be read.
+func (co *Consumer) Pause() (err error) {
+
+	control := &tcp.ControlMessage{
+		Action: tcp.ControlPause,
+	}
+
+	return co.control(control)
+}
+
+// Resume restarts a paused stream.
+func (co *Consumer) Resume() (err error) {
+
+	control := &tcp.ControlMessage{
+		Action: tcp.ControlResume,
+	}
+
+	return co.control(control)
+}
+
+// SetCount ends the stream after count more records, or never when count is
+// negative.
+func (co *Consumer) SetCount(count int64) (err error) {
+
+	control := &tcp.ControlMessage{
+		Action: tcp.ControlCount,
+		Count:  count,
+	}
+
+	return co.control(control)
+}
+
+func (co *Consumer) control(control *tcp.ControlMessage) (err error) {
+
+	if !co.capabilities.Has(tcp.FeatureControl) {
+		return ErrControlUnsupported
+	}
+
+	_, err = co.reader.WriteControl(control)
+	if err == recio.ErrMustFlush {
+
+		err = co.reader.Flush()
+		if err != nil {
+			return err
+		}
+
+		_, err = co.reader.WriteControl(control)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = co.reader.Flush()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 //
 func (co *Consumer) Close() (err error) {
 
@@ -0,0 +1,249 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/dataptive/styx/pkg/api/tcp"
+	"github.com/dataptive/styx/pkg/log"
+	"github.com/dataptive/styx/pkg/recio"
+)
+
+var (
+	ErrAlreadySubscribed = errors.New("client: already subscribed")
+	ErrNotSubscribed     = errors.New("client: not subscribed")
+)
+
+// EndHandler is called when the server ends a subscription. err is nil when
+// the subscription completed or was canceled with Unsubscribe.
+type EndHandler func(name string, err error)
+
+// MultiplexConsumer consumes many logs over a single styx protocol
+// connection, each subscription having its own start position.
+type MultiplexConsumer struct {
+	conn             net.Conn
+	tcpPeer          *tcp.TCPPeer
+	subscriptions    map[uint32]string
+	nextID           uint32
+	lock             sync.Mutex
+	messageIn        *tcp.Message
+	messageOut       *tcp.Message
+	subscribeMessage *tcp.SubscribeMessage
+	endHandler       EndHandler
+	capabilities     tcp.Capabilities
+}
+
+//
+func (c *Client) NewMultiplexConsumer(options ConsumerOptions) (mc *MultiplexConsumer, err error) {
+
+	endpoint := c.baseURL + "/logs/records"
+
+	conn, remoteTimeout, caps, err := c.upgrade(http.MethodGet, endpoint, options.ReadTimeout, c.features([]string{tcp.FeatureCompression}))
+	if err != nil {
+		return nil, err
+	}
+
+	tcpPeer := tcp.NewTCPPeer(conn, options.WriteBufferSize, options.ReadBufferSize, options.ReadTimeout, remoteTimeout, recio.ModeAuto)
+
+	mc = &MultiplexConsumer{
+		conn:             conn,
+		tcpPeer:          tcpPeer,
+		subscriptions:    make(map[uint32]string),
+		nextID:           0,
+		messageIn:        &tcp.Message{},
+		messageOut:       &tcp.Message{},
+		subscribeMessage: &tcp.SubscribeMessage{},
+		endHandler:       nil,
+		capabilities:     caps,
+	}
+
+	return mc, nil
+}
+
+// Capabilities returns the protocol version and the features negotiated
+// with the server.
+func (mc *MultiplexConsumer) Capabilities() (caps tcp.Capabilities) {
+
+	return mc.capabilities
+}
+
+// Subscribe starts consuming the named log. Records start flowing as soon as
+// the server processes the subscription.
+func (mc *MultiplexConsumer) Subscribe(name string, params ConsumerParams) (err error) {
+
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+
+	for _, subscribed := range mc.subscriptions {
+		if subscribed == name {
+			return ErrAlreadySubscribed
+		}
+	}
+
+	id := mc.nextID
+	mc.nextID++
+
+	mc.subscriptions[id] = name
+
+	mc.subscribeMessage.ID = id
+	mc.subscribeMessage.Name = name
+	mc.subscribeMessage.Whence = params.Whence
+	mc.subscribeMessage.Position = params.Position
+	mc.subscribeMessage.Count = params.Count
+	mc.subscribeMessage.Follow = params.Follow
+	mc.subscribeMessage.Filter = params.Filter
+
+	mc.messageOut.Type = tcp.TypeSubscribeMessage
+	mc.messageOut.Payload = mc.subscribeMessage
+
+	err = mc.writeMessage(mc.messageOut)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Unsubscribe stops consuming the named log. Records already in flight may
+// still be returned by Read until the end of the subscription is received.
+func (mc *MultiplexConsumer) Unsubscribe(name string) (err error) {
+
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+
+	for id, subscribed := range mc.subscriptions {
+
+		if subscribed != name {
+			continue
+		}
+
+		unsubscribeMessage := &tcp.UnsubscribeMessage{
+			ID: id,
+		}
+
+		mc.messageOut.Type = tcp.TypeUnsubscribeMessage
+		mc.messageOut.Payload = unsubscribeMessage
+
+		err = mc.writeMessage(mc.messageOut)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	return ErrNotSubscribed
+}
+
+// Read returns the next record of any subscription, along with the name of
+// its log and its position.
+func (mc *MultiplexConsumer) Read(r *log.Record) (name string, position int64, err error) {
+
+	for {
+		_, err = mc.tcpPeer.ReadMessage(mc.messageIn)
+		if err != nil {
+			return "", 0, err
+		}
+
+		switch v := mc.messageIn.Payload.(type) {
+
+		case *tcp.LogRecordMessage:
+
+			mc.lock.Lock()
+			name = mc.subscriptions[v.ID]
+			mc.lock.Unlock()
+
+			*r = v.Record
+
+			return name, v.Position, nil
+
+		case *tcp.SubscriptionEndMessage:
+
+			mc.lock.Lock()
+			name = mc.subscriptions[v.ID]
+			delete(mc.subscriptions, v.ID)
+			mc.lock.Unlock()
+
+			var er error
+			if v.Reason == tcp.EndReasonError {
+				er = tcp.GetErrorMessage(v.Code)
+			}
+
+			if mc.endHandler != nil {
+				mc.endHandler(name, er)
+			}
+
+			continue
+
+		case *tcp.ErrorMessage:
+			err = tcp.GetErrorMessage(v.Code)
+			return "", 0, err
+
+		case *tcp.HeartbeatMessage:
+			// Ignore.
+			continue
+
+		default:
+			return "", 0, tcp.ErrUnexpectedMessageType
+		}
+	}
+}
+
+//
+func (mc *MultiplexConsumer) Close() (err error) {
+
+	err = mc.tcpPeer.Close()
+	if err != nil {
+		return err
+	}
+
+	err = mc.conn.Close()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//
+func (mc *MultiplexConsumer) HandleEnd(h EndHandler) {
+
+	mc.endHandler = h
+}
+
+//
+func (mc *MultiplexConsumer) HandleError(h ErrorHandler) {
+
+	mc.tcpPeer.HandleError(tcp.ErrorHandler(h))
+}
+
+func (mc *MultiplexConsumer) writeMessage(m *tcp.Message) (err error) {
+
+	_, err = mc.tcpPeer.WriteMessage(m)
+	if err != nil {
+		return err
+	}
+
+	err = mc.tcpPeer.Flush()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
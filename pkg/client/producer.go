@@ -15,13 +15,11 @@
 package client
 
 import (
-	"bufio"
+	"io"
 	"net"
 	"net/http"
-	"net/url"
-	"strconv"
+	"sync"
 
-	"github.com/dataptive/styx/pkg/api"
 	"github.com/dataptive/styx/pkg/api/tcp"
 	"github.com/dataptive/styx/pkg/log"
 	"github.com/dataptive/styx/pkg/recio"
@@ -33,6 +31,7 @@ var (
 		ReadBufferSize:  1 << 20, // 1 MB
 		WriteBufferSize: 1 << 20, // 1 MB
 		IOMode:          recio.ModeAuto,
+		Batch:           true,
 	}
 )
 
@@ -44,7 +43,11 @@ type ErrorHandler func(err error)
 
 //
 type Producer struct {
-	writer *tcp.TCPWriter
+	writer       *tcp.TCPWriter
+	capabilities tcp.Capabilities
+	errorHandler ErrorHandler
+	remoteErr    error
+	errLock      sync.Mutex
 }
 
 //
@@ -53,78 +56,78 @@ type ProducerOptions struct {
 	ReadBufferSize  int
 	WriteBufferSize int
 	IOMode          recio.IOMode
+	Batch           bool
 }
 
 //
 func (c *Client) NewProducer(name string, options ProducerOptions) (p *Producer, err error) {
 
-	endpoint := c.baseURL + "/logs/" + name + "/records"
+	var conn net.Conn
+	var remoteTimeout int
+	var caps tcp.Capabilities
 
-	req, err := http.NewRequest(http.MethodPost, endpoint, nil)
-	if err != nil {
-		return nil, err
+	offered := []string{tcp.FeatureCompression}
+	if options.Batch {
+		offered = append(offered, tcp.FeatureBatch)
 	}
 
-	req.Header.Add("Connection", "upgrade")
-	req.Header.Add("Upgrade", api.StyxProtocolString)
-	req.Header.Add(api.TimeoutHeaderName, strconv.Itoa(options.ReadTimeout))
-
-	var tcpConn *net.TCPConn
+	features := c.features(offered)
 
-	u, err := url.Parse(endpoint)
-	if err != nil {
-		return nil, err
-	}
+	if IsStyxURL(c.baseURL) {
 
-	conn, err := net.Dial("tcp", u.Host)
-	if err != nil {
-		return nil, err
-	}
+		request := &tcp.HandshakeRequest{
+			Direction: tcp.DirectionWrite,
+			Timeout:   options.ReadTimeout,
+			Name:      name,
+			Features:  features,
+		}
 
-	err = req.Write(conn)
-	if err != nil {
-		return nil, err
-	}
+		conn, remoteTimeout, caps, err = c.dialStyx(request)
+	} else {
 
-	br := bufio.NewReader(newByteReader(conn))
+		endpoint := c.baseURL + "/logs/" + name + "/records"
 
-	resp, err := http.ReadResponse(br, req)
-	if err != nil {
-		return nil, err
+		conn, remoteTimeout, caps, err = c.upgrade(http.MethodPost, endpoint, options.ReadTimeout, features)
 	}
 
-	if resp.StatusCode != http.StatusSwitchingProtocols {
-		err = api.ReadError(resp.Body)
+	if err != nil {
 		return nil, err
 	}
 
-	var remoteTimeout int
+	writer := tcp.NewTCPWriter(conn, options.WriteBufferSize, options.ReadBufferSize, options.ReadTimeout, remoteTimeout, options.IOMode)
 
-	rawTimeout := resp.Header.Get(api.TimeoutHeaderName)
-	if rawTimeout != "" {
-		remoteTimeout, err = strconv.Atoi(rawTimeout)
-		if err != nil {
-			return nil, err
-		}
+	if caps.Has(tcp.FeatureBatch) {
+		writer.EnableBatch()
 	}
 
-	tcpConn = conn.(*net.TCPConn)
-
-	writer := tcp.NewTCPWriter(tcpConn, options.WriteBufferSize, options.ReadBufferSize, options.ReadTimeout, remoteTimeout, options.IOMode)
-
 	p = &Producer{
-		writer: writer,
+		writer:       writer,
+		capabilities: caps,
 	}
 
+	writer.HandleError(p.handleError)
+
 	return p, nil
 }
 
+// Capabilities returns the protocol version and the features negotiated
+// with the server.
+func (p *Producer) Capabilities() (caps tcp.Capabilities) {
+
+	return p.capabilities
+}
+
 //
 func (p *Producer) Write(r *log.Record) (n int, err error) {
 
+	err = p.error()
+	if err != nil {
+		return 0, err
+	}
+
 	n, err = p.writer.Write(r)
 	if err != nil {
-		return n, err
+		return n, p.errorOr(err)
 	}
 
 	return n, nil
@@ -133,11 +136,16 @@ func (p *Producer) Write(r *log.Record) (n int, err error) {
 //
 func (p *Producer) Flush() (err error) {
 
-	err = p.writer.Flush()
+	err = p.error()
 	if err != nil {
 		return err
 	}
 
+	err = p.writer.Flush()
+	if err != nil {
+		return p.errorOr(err)
+	}
+
 	return nil
 }
 
@@ -146,10 +154,12 @@ func (p *Producer) Close() (err error) {
 
 	err = p.writer.Close()
 	if err != nil {
-		return err
+		return p.errorOr(err)
 	}
 
-	return nil
+	// Closing waits for the server to acknowledge the end of the
+	// stream, report any error it sent in the meantime.
+	return p.error()
 }
 
 //
@@ -161,5 +171,47 @@ func (p *Producer) HandleSync(h SyncHandler) {
 //
 func (p *Producer) HandleError(h ErrorHandler) {
 
-	p.writer.HandleError(tcp.ErrorHandler(h))
+	p.errLock.Lock()
+	defer p.errLock.Unlock()
+
+	p.errorHandler = h
+}
+
+// handleError records the first error reported by the server, such as
+// tcp.ErrQuotaExceeded or tcp.ErrRecordTooLarge, so that subsequent calls to
+// Write and Flush return it instead of a generic connection error.
+func (p *Producer) handleError(err error) {
+
+	p.errLock.Lock()
+
+	if p.remoteErr == nil && err != io.EOF {
+		p.remoteErr = err
+	}
+
+	h := p.errorHandler
+
+	p.errLock.Unlock()
+
+	if h != nil {
+		h(err)
+	}
+}
+
+func (p *Producer) error() (err error) {
+
+	p.errLock.Lock()
+	defer p.errLock.Unlock()
+
+	return p.remoteErr
+}
+
+// errorOr returns the error reported by the server if any, err otherwise.
+func (p *Producer) errorOr(err error) (er error) {
+
+	remoteErr := p.error()
+	if remoteErr != nil {
+		return remoteErr
+	}
+
+	return err
 }
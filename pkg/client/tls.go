@@ -0,0 +1,74 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+)
+
+var (
+	ErrInvalidCA         = errors.New("client: no certificate found in CA file")
+	ErrIncompleteKeyPair = errors.New("client: both certificate and key files are required")
+)
+
+type TLSOptions struct {
+	CAFile             string // Verify the server against this CA instead of system roots.
+	CertFile           string // Client certificate, for servers requiring one.
+	KeyFile            string // Client certificate key.
+	InsecureSkipVerify bool   // Do not verify the server certificate.
+}
+
+// LoadTLSConfig builds a TLS config suitable for ClientOptions from files.
+func LoadTLSConfig(options TLSOptions) (tlsConfig *tls.Config, err error) {
+
+	tlsConfig = &tls.Config{
+		InsecureSkipVerify: options.InsecureSkipVerify,
+	}
+
+	if options.CAFile != "" {
+
+		pem, err := ioutil.ReadFile(options.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		rootCAs := x509.NewCertPool()
+
+		if !rootCAs.AppendCertsFromPEM(pem) {
+			return nil, ErrInvalidCA
+		}
+
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	if options.CertFile != "" || options.KeyFile != "" {
+
+		if options.CertFile == "" || options.KeyFile == "" {
+			return nil, ErrIncompleteKeyPair
+		}
+
+		certificate, err := tls.LoadX509KeyPair(options.CertFile, options.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
@@ -14,31 +14,120 @@
 
 package client
 
+import (
+	"encoding/json"
+	"time"
+)
+
 //
 type LogInfo struct {
-	Name          string           `json:"name"`
-	Status        string           `json:"status"`
-	RecordCount   int64            `json:"record_count"`
-	FileSize      int64            `json:"file_size"`
-	StartPosition int64            `json:"start_position"`
-	EndPosition   int64            `json:"end_position"`
+	Name             string            `json:"name"`
+	Status           string            `json:"status"`
+	RecordCount      int64             `json:"record_count"`
+	FileSize         int64             `json:"file_size"`
+	StartPosition    int64             `json:"start_position"`
+	EndPosition      int64             `json:"end_position"`
+	WriteRecordsRate float64           `json:"write_records_rate"`
+	WriteBytesRate   float64           `json:"write_bytes_rate"`
+	ReadRecordsRate  float64           `json:"read_records_rate"`
+	ReadBytesRate    float64           `json:"read_bytes_rate"`
+	Owner            string            `json:"owner"`
+	Description      string            `json:"description"`
+	SchemaURL        string            `json:"schema_url"`
+	Labels           map[string]string `json:"labels"`
+	LastScan         *ScanResult       `json:"last_scan"`
+}
+
+// ScanResult records the outcome of the last scan of a log. Status is "ok"
+// when its files were found sound or repaired, and Error details why they
+// weren't.
+type ScanResult struct {
+	Time   time.Time `json:"time"`
+	Status string    `json:"status"`
+	Error  string    `json:"error"`
+}
+
+// Event reports a change in the lifecycle of a log, among "created",
+// "deleted", "truncated", "restored", "renamed" and "status_changed". Status
+// is the status of the log following the change. A "reset" event about no log
+// tells that the events following the requested one were lost.
+type Event struct {
+	ID             int64     `json:"id"`
+	Time           time.Time `json:"time"`
+	Type           string    `json:"type"`
+	Name           string    `json:"name"`
+	Status         string    `json:"status"`
+	PreviousStatus string    `json:"previous_status,omitempty"`
+	PreviousName   string    `json:"previous_name,omitempty"`
+}
+
+// ListEventsParams selects the events following the one with ID Since, or
+// those to come when negative, waiting up to Timeout seconds for one.
+type ListEventsParams struct {
+	Since   int64 `schema:"since"`
+	Timeout int   `schema:"timeout"`
 }
 
 //
+type StorageInfo struct {
+	Status string `json:"status"`
+	Usage  int64  `json:"usage"`
+	Free   int64  `json:"free"`
+}
+
+// ServerStatus summarizes the state of the server. Uptime is in seconds and
+// Logs counts logs by status.
+type ServerStatus struct {
+	Version     string         `json:"version"`
+	Uptime      int64          `json:"uptime"`
+	Ready       bool           `json:"ready"`
+	Storage     StorageInfo    `json:"storage"`
+	Logs        map[string]int `json:"logs"`
+	Connections int64          `json:"connections"`
+}
+
+// ReloadResponse lists the settings of the reloaded server config which only
+// apply on restart.
+type ReloadResponse struct {
+	RestartRequired []string `json:"restart_required"`
+}
+
+// LogConfig holds the settings of a log. Zero fields are left to the
+// defaults of the namespaces holding the log, or to the server defaults.
 type LogConfig struct {
-	MaxRecordSize   int   `schema:"max_record_size"`
-	IndexAfterSize  int64 `schema:"index_after_size"`
-	SegmentMaxCount int64 `schema:"segment_max_count"`
-	SegmentMaxSize  int64 `schema:"segment_max_size"`
-	SegmentMaxAge   int64 `schema:"segment_max_age"`
-	LogMaxCount     int64 `schema:"log_max_count"`
-	LogMaxSize      int64 `schema:"log_max_size"`
-	LogMaxAge       int64 `schema:"log_max_age"`
+	MaxRecordSize   int   `schema:"max_record_size,omitempty" json:"max_record_size"`
+	IndexAfterSize  int64 `schema:"index_after_size,omitempty" json:"index_after_size"`
+	SegmentMaxCount int64 `schema:"segment_max_count,omitempty" json:"segment_max_count"`
+	SegmentMaxSize  int64 `schema:"segment_max_size,omitempty" json:"segment_max_size"`
+	SegmentMaxAge   int64 `schema:"segment_max_age,omitempty" json:"segment_max_age"`
+	LogMaxCount     int64 `schema:"log_max_count,omitempty" json:"log_max_count"`
+	LogMaxSize      int64 `schema:"log_max_size,omitempty" json:"log_max_size"`
+	LogMaxAge       int64 `schema:"log_max_age,omitempty" json:"log_max_age"`
+}
+
+// LogMetadata describes a log for its users.
+type LogMetadata struct {
+	Owner       string            `schema:"owner,omitempty"`
+	Description string            `schema:"description,omitempty"`
+	SchemaURL   string            `schema:"schema_url,omitempty"`
+	Labels      map[string]string `schema:"-"`
+}
+
+//
+type ListLogsParams struct {
+	Namespace string   `schema:"namespace,omitempty"`
+	Labels    []string `schema:"label,omitempty"`
 }
 
 type createLogForm struct {
 	Name string `schema:"name,required"`
 	*LogConfig
+	LogMetadata
+}
+
+type renameLogForm struct {
+	Name     string `schema:"name,required"`
+	AliasTTL int    `schema:"alias_ttl,omitempty"`
 }
 
 //
@@ -52,9 +141,87 @@ type ListLogsResponse []LogInfo
 //
 type CreateLogResponse LogInfo
 
+//
+type SetLogMetadataResponse LogInfo
+
 //
 type GetLogResponse LogInfo
 
+//
+type RenameLogResponse LogInfo
+
+//
+type ScanLogResponse LogInfo
+
+//
+type NamespaceInfo struct {
+	Name      string    `json:"name"`
+	LogCount  int64     `json:"log_count"`
+	Usage     int64     `json:"usage"`
+	LogConfig LogConfig `json:"log_config"`
+	MaxLogs   int64     `json:"max_logs"`
+	MaxUsage  int64     `json:"max_usage"`
+}
+
+// NamespaceConfig holds the settings of a namespace. Non zero fields of
+// LogConfig are the defaults of the logs created in the namespace. MaxLogs
+// and MaxUsage cap the number and the size of all the logs it holds, zero
+// meaning no limit.
+type NamespaceConfig struct {
+	LogConfig
+	MaxLogs  int64 `schema:"max_logs,omitempty"`
+	MaxUsage int64 `schema:"max_usage,omitempty"`
+}
+
+//
+type ListNamespacesResponse []NamespaceInfo
+
+//
+type GetNamespaceResponse NamespaceInfo
+
+//
+type SetNamespaceResponse NamespaceInfo
+
+//
+type SessionInfo struct {
+	ID                int64     `json:"id"`
+	Log               string    `json:"log"`
+	Client            string    `json:"client"`
+	RemoteAddr        string    `json:"remote_addr"`
+	StartedAt         time.Time `json:"started_at"`
+	SentPosition      int64     `json:"sent_position"`
+	ProcessedPosition int64     `json:"processed_position"`
+	Lag               int64     `json:"lag"`
+	Cursor            string    `json:"cursor"`
+	ProgressAt        time.Time `json:"progress_at"`
+}
+
+//
+type ListSessionsResponse []SessionInfo
+
+//
+type CursorInfo struct {
+	Name     string `json:"name"`
+	Position int64  `json:"position"`
+}
+
+//
+type ListCursorsResponse []CursorInfo
+
+//
+type ProduceResponse struct {
+	Position int64 `json:"position"`
+	Count    int64 `json:"count"`
+}
+
+//
+type JSONRecord struct {
+	Position      int64           `json:"position"`
+	Offset        int64           `json:"offset"`
+	Payload       json.RawMessage `json:"payload,omitempty"`
+	PayloadBase64 []byte          `json:"payload_base64,omitempty"`
+}
+
 // //
 // type ProduceResponse struct {
 // 	Position int64 `json:"position"`
@@ -15,9 +15,439 @@
 package client
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"errors"
 	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/dataptive/styx/pkg/api"
+	"github.com/dataptive/styx/pkg/api/tcp"
+	"github.com/dataptive/styx/pkg/compress"
+)
+
+const (
+	// StyxScheme is the base URL scheme of servers reached through their raw
+	// styx protocol listener, e.g. styx://localhost:7124. Such clients can
+	// only produce and consume records.
+	StyxScheme = "styx"
+
+	// StyxTLSScheme is the base URL scheme of raw styx protocol listeners
+	// using TLS.
+	StyxTLSScheme = "styxs"
+
+	// UnixScheme is the base URL scheme of servers reached through their
+	// Unix domain socket, e.g. unix:///var/run/styx.sock.
+	UnixScheme = "unix"
+
+	// unixBaseURL replaces unix base URLs in HTTP requests, the host part
+	// being ignored when dialing the socket.
+	unixBaseURL = "http://unix"
+)
+
+var (
+	ErrUpgradeFailed = errors.New("client: server did not switch to the styx protocol")
+
+	errVersionRejected = errors.New("client: protocol version rejected")
+	errUpgradeIgnored  = errors.New("client: protocol upgrade ignored")
+
+	defaultPorts = map[string]string{
+		"http":  "80",
+		"https": "443",
+	}
 )
 
+// dialer opens the connections of a client, to a TCP address or to a Unix
+// domain socket.
+type dialer func(address string) (conn net.Conn, err error)
+
+func dialTCP(address string) (conn net.Conn, err error) {
+
+	return net.Dial("tcp", address)
+}
+
+func dialUnix(path string) (d dialer) {
+
+	d = func(address string) (conn net.Conn, err error) {
+		return net.Dial("unix", path)
+	}
+
+	return d
+}
+
+// IsStyxURL reports whether u points to a raw styx protocol listener.
+func IsStyxURL(u string) (ok bool) {
+
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+
+	return parsed.Scheme == StyxScheme || parsed.Scheme == StyxTLSScheme
+}
+
+// connect opens a connection to the server at u, performing the TLS
+// handshake for https and styxs URLs.
+func (c *Client) connect(u *url.URL) (conn net.Conn, err error) {
+
+	address := u.Host
+
+	port, ok := defaultPorts[u.Scheme]
+	if ok && u.Port() == "" {
+		address = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	conn, err = c.dial(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "https" && u.Scheme != StyxTLSScheme {
+		return conn, nil
+	}
+
+	tlsConfig := &tls.Config{}
+	if c.tlsConfig != nil {
+		tlsConfig = c.tlsConfig.Clone()
+	}
+
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = u.Hostname()
+	}
+
+	// Connection upgrades are only supported by HTTP/1.1.
+	tlsConfig.NextProtos = nil
+	if u.Scheme == "https" {
+		tlsConfig.NextProtos = []string{"http/1.1"}
+	}
+
+	tlsConn := tls.Client(conn, tlsConfig)
+
+	err = tlsConn.Handshake()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return tlsConn, nil
+}
+
+// dialStyx connects to a raw styx protocol listener and performs the binary
+// handshake, returning the connection along with the timeout announced by
+// the server and the negotiated capabilities. The latest protocol version is
+// requested first, the handshake being retried with the version answered by
+// servers not supporting it. The features offered are taken from request.
+func (c *Client) dialStyx(request *tcp.HandshakeRequest) (conn net.Conn, remoteTimeout int, caps tcp.Capabilities, err error) {
+
+	request.Version = tcp.LatestVersion
+	request.Token = c.token
+	request.Compression = c.compression
+
+	conn, response, err := c.handshakeStyx(request)
+	if err == errVersionRejected {
+		request.Version = response.Version
+		conn, response, err = c.handshakeStyx(request)
+	}
+
+	if err == errVersionRejected {
+		err = tcp.GetErrorMessage(response.Code)
+	}
+
+	if err != nil {
+		return nil, 0, caps, err
+	}
+
+	caps = tcp.Capabilities{
+		Version:     response.Version,
+		Features:    response.Features,
+		Compression: response.Compression,
+	}
+
+	conn, err = compressConn(conn, caps.Compression)
+	if err != nil {
+		return nil, 0, caps, err
+	}
+
+	return conn, response.Timeout, caps, nil
+}
+
+// handshakeStyx opens a connection to a raw styx protocol listener and sends
+// request. It returns errVersionRejected along with the response when the
+// server rejected the handshake with another protocol version.
+func (c *Client) handshakeStyx(request *tcp.HandshakeRequest) (conn net.Conn, response tcp.HandshakeResponse, err error) {
+
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return nil, response, err
+	}
+
+	conn, err = c.connect(u)
+	if err != nil {
+		return nil, response, err
+	}
+
+	_, err = request.WriteTo(conn)
+	if err != nil {
+		conn.Close()
+		return nil, response, err
+	}
+
+	_, err = response.ReadFrom(conn)
+	if err != nil {
+		conn.Close()
+		return nil, response, err
+	}
+
+	if response.Status != tcp.HandshakeAccepted {
+		conn.Close()
+
+		if response.Version != request.Version {
+			return nil, response, errVersionRejected
+		}
+
+		return nil, response, tcp.GetErrorMessage(response.Code)
+	}
+
+	return conn, response, nil
+}
+
+// upgrade performs the styx protocol HTTP handshake on a new connection and
+// returns the connection along with the timeout announced by the server and
+// the negotiated capabilities. All the supported protocol versions are
+// offered, the handshake being retried with version 0 when the server
+// answers with a plain HTTP response instead of switching protocols.
+func (c *Client) upgrade(method string, endpoint string, timeout int, features []string) (conn net.Conn, remoteTimeout int, caps tcp.Capabilities, err error) {
+
+	conn, resp, err := c.upgradeRequest(method, endpoint, timeout, tcp.UpgradeString(), features)
+	if err == errUpgradeIgnored {
+		conn, resp, err = c.upgradeRequest(method, endpoint, timeout, tcp.ProtocolString(tcp.ProtocolVersion0), features)
+	}
+
+	if err == errUpgradeIgnored {
+		err = ErrUpgradeFailed
+	}
+
+	if err != nil {
+		return nil, 0, caps, err
+	}
+
+	rawTimeout := resp.Header.Get(api.TimeoutHeaderName)
+	if rawTimeout != "" {
+		remoteTimeout, err = strconv.Atoi(rawTimeout)
+		if err != nil {
+			conn.Close()
+			return nil, 0, caps, err
+		}
+	}
+
+	version, ok := tcp.NegotiateVersion(resp.Header.Get("Upgrade"))
+	if !ok {
+		conn.Close()
+		return nil, 0, caps, ErrUpgradeFailed
+	}
+
+	caps = tcp.Capabilities{
+		Version:     version,
+		Features:    tcp.ParseFeatures(resp.Header.Get(api.FeaturesHeaderName)),
+		Compression: resp.Header.Get(api.CompressionHeaderName),
+	}
+
+	conn, err = compressConn(conn, caps.Compression)
+	if err != nil {
+		return nil, 0, caps, err
+	}
+
+	return conn, remoteTimeout, caps, nil
+}
+
+// upgradeRequest sends a styx protocol upgrade request offering the
+// protocols listed in upgrade and features. It returns errUpgradeIgnored when the server
+// answered with a successful plain HTTP response.
+func (c *Client) upgradeRequest(method string, endpoint string, timeout int, upgrade string, features []string) (conn net.Conn, resp *http.Response, err error) {
+
+	req, err := http.NewRequest(method, endpoint, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req.Header.Add("Connection", "upgrade")
+	req.Header.Add("Upgrade", upgrade)
+	req.Header.Add(api.TimeoutHeaderName, strconv.Itoa(timeout))
+
+	if len(features) > 0 {
+		req.Header.Add(api.FeaturesHeaderName, tcp.FormatFeatures(features))
+	}
+
+	if c.compression != "" {
+		req.Header.Add(api.CompressionHeaderName, c.compression)
+	}
+
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	conn, err = c.connect(req.URL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = req.Write(conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	br := bufio.NewReader(newByteReader(conn))
+
+	resp, err = http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		conn.Close()
+		return nil, nil, errUpgradeIgnored
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		err = upgradeError(api.ReadError(resp.Body))
+		conn.Close()
+		return nil, nil, err
+	}
+
+	return conn, resp, nil
+}
+
+// upgradeError translates an API error rejecting a styx protocol upgrade
+// into its protocol counterpart, so that producers and consumers report
+// the same errors whether they connect over HTTP or raw styx.
+func upgradeError(err error) (er error) {
+
+	apiErr, ok := err.(*api.Error)
+	if !ok {
+		return err
+	}
+
+	switch apiErr.Code {
+	case api.ErrLogNotFound.Code:
+		return tcp.ErrLogNotFound
+
+	case api.ErrLogNotAvailable.Code:
+		return tcp.ErrLogUnavailable
+
+	case api.ErrInsufficientStorage.Code:
+		return tcp.ErrQuotaExceeded
+
+	case api.ErrOutOfRange.Code:
+		return tcp.ErrOutOfRange
+
+	case api.ErrUnauthorized.Code:
+		return tcp.ErrUnauthorized
+
+	case api.ErrForbidden.Code:
+		return tcp.ErrForbidden
+	}
+
+	return err
+}
+
+// features returns the protocol features offered to the server among
+// offered, leaving out compression when the client doesn't request a codec.
+func (c *Client) features(offered []string) (features []string) {
+
+	features = []string{}
+
+	for _, feature := range offered {
+
+		if feature == tcp.FeatureCompression && c.compression == "" {
+			continue
+		}
+
+		features = append(features, feature)
+	}
+
+	return features
+}
+
+// compressConn wraps conn to compress its stream with the codec selected by
+// the server, which may be empty when the server doesn't support compression.
+// The connection is closed on error.
+func compressConn(conn net.Conn, codec string) (c net.Conn, err error) {
+
+	if codec == "" || codec == compress.Identity {
+		return conn, nil
+	}
+
+	c, err = compress.NewConn(conn, codec)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// compressBody compresses a request body with the client codec.
+func (c *Client) compressBody(body []byte) (compressed []byte, err error) {
+
+	if c.compression == "" || c.compression == compress.Identity {
+		return body, nil
+	}
+
+	buf := &bytes.Buffer{}
+
+	cw, err := compress.NewWriter(c.compression, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = cw.Write(body)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cw.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// tokenTransport authenticates every request of the HTTP client.
+type tokenTransport struct {
+	base  http.RoundTripper
+	token string
+}
+
+func newTokenTransport(base http.RoundTripper, token string) (tt *tokenTransport) {
+
+	tt = &tokenTransport{
+		base:  base,
+		token: token,
+	}
+
+	return tt
+}
+
+func (tt *tokenTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+
+	if tt.token == "" {
+		return tt.base.RoundTrip(req)
+	}
+
+	// Round trippers must not modify the original request.
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+tt.token)
+
+	return tt.base.RoundTrip(req)
+}
+
 type byteReader struct {
 	reader io.Reader
 }
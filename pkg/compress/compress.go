@@ -0,0 +1,209 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compress
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/golang/snappy"
+)
+
+const (
+	Identity = "identity"
+	Gzip     = "gzip"
+	Snappy   = "snappy"
+)
+
+var (
+	ErrUnsupportedCodec = errors.New("compress: unsupported codec")
+
+	// Codecs lists the supported codecs, fastest first.
+	Codecs = []string{Snappy, Gzip}
+)
+
+// Writer compresses data written to it. Flush writes any pending compressed
+// data to the underlying writer, and Close terminates the compressed stream
+// without closing the underlying writer.
+type Writer interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// Supported reports whether codec is a supported codec, identity included.
+func Supported(codec string) (ok bool) {
+
+	if codec == "" || codec == Identity {
+		return true
+	}
+
+	for _, supported := range Codecs {
+		if codec == supported {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewWriter returns a Writer compressing to w with codec. The identity
+// codec, or an empty one, returns a Writer passing data through.
+func NewWriter(codec string, w io.Writer) (cw Writer, err error) {
+
+	switch codec {
+
+	case "", Identity:
+		cw = &identityWriter{writer: w}
+
+	case Gzip:
+		cw = gzip.NewWriter(w)
+
+	case Snappy:
+		cw = snappy.NewBufferedWriter(w)
+
+	default:
+		return nil, ErrUnsupportedCodec
+	}
+
+	return cw, nil
+}
+
+// NewReader returns a reader decompressing data read from r with codec. The
+// identity codec, or an empty one, returns a reader passing data through.
+func NewReader(codec string, r io.Reader) (cr io.ReadCloser, err error) {
+
+	switch codec {
+
+	case "", Identity:
+		cr = ioutil.NopCloser(r)
+
+	case Gzip:
+		cr = &gzipReader{reader: r}
+
+	case Snappy:
+		cr = ioutil.NopCloser(snappy.NewReader(r))
+
+	default:
+		return nil, ErrUnsupportedCodec
+	}
+
+	return cr, nil
+}
+
+// Negotiate picks the codec to use from an Accept-Encoding style header,
+// listing codecs optionally weighted with a q parameter. Codecs are tried in
+// the order of preferred, the first one accepted by the header being
+// returned. Identity is returned when none is accepted.
+func Negotiate(header string, preferred []string) (codec string) {
+
+	accepted := make(map[string]float64)
+
+	for _, entry := range strings.Split(header, ",") {
+
+		parts := strings.Split(entry, ";")
+
+		name := strings.ToLower(strings.TrimSpace(parts[0]))
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+
+		for _, param := range parts[1:] {
+
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+
+			value, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+			if err == nil {
+				q = value
+			}
+		}
+
+		accepted[name] = q
+	}
+
+	for _, candidate := range preferred {
+
+		q, exists := accepted[candidate]
+		if !exists {
+			q, exists = accepted["*"]
+		}
+
+		if exists && q > 0 {
+			return candidate
+		}
+	}
+
+	return Identity
+}
+
+type identityWriter struct {
+	writer io.Writer
+}
+
+func (iw *identityWriter) Write(p []byte) (n int, err error) {
+
+	return iw.writer.Write(p)
+}
+
+func (iw *identityWriter) Flush() (err error) {
+
+	return nil
+}
+
+func (iw *identityWriter) Close() (err error) {
+
+	return nil
+}
+
+// gzipReader delays reading the gzip header until the first read, so that
+// creating a reader never blocks on the underlying one.
+type gzipReader struct {
+	reader io.Reader
+	gzip   *gzip.Reader
+}
+
+func (gr *gzipReader) Read(p []byte) (n int, err error) {
+
+	if gr.gzip == nil {
+
+		gr.gzip, err = gzip.NewReader(gr.reader)
+		if err != nil {
+			return 0, err
+		}
+
+		// Stop at the end of the stream instead of
+		// waiting for a following one.
+		gr.gzip.Multistream(false)
+	}
+
+	return gr.gzip.Read(p)
+}
+
+func (gr *gzipReader) Close() (err error) {
+
+	if gr.gzip == nil {
+		return nil
+	}
+
+	return gr.gzip.Close()
+}
@@ -0,0 +1,161 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compress
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+)
+
+// Tests that data written with every codec reads back unchanged.
+func TestCodecs_RoundTrip(t *testing.T) {
+
+	payload := bytes.Repeat([]byte("styx compress round trip "), 1000)
+
+	for _, codec := range []string{Identity, Gzip, Snappy} {
+
+		buf := &bytes.Buffer{}
+
+		cw, err := NewWriter(codec, buf)
+		if err != nil {
+			t.Fatalf("new writer for %s failed with err == %s", codec, err)
+		}
+
+		_, err = cw.Write(payload)
+		if err != nil {
+			t.Fatalf("write with %s failed with err == %s", codec, err)
+		}
+
+		err = cw.Close()
+		if err != nil {
+			t.Fatalf("close with %s failed with err == %s", codec, err)
+		}
+
+		if codec != Identity && buf.Len() >= len(payload) {
+			t.Fatalf("%s did not compress payload", codec)
+		}
+
+		cr, err := NewReader(codec, buf)
+		if err != nil {
+			t.Fatalf("new reader for %s failed with err == %s", codec, err)
+		}
+
+		decoded, err := ioutil.ReadAll(cr)
+		if err != nil {
+			t.Fatalf("read with %s failed with err == %s", codec, err)
+		}
+
+		if !bytes.Equal(decoded, payload) {
+			t.Fatalf("%s payload mismatch", codec)
+		}
+	}
+}
+
+// Tests rejection of unknown codecs.
+func TestCodecs_Unsupported(t *testing.T) {
+
+	_, err := NewWriter("brotli", &bytes.Buffer{})
+	if err != ErrUnsupportedCodec {
+		t.Fatalf("new writer should have failed with ErrUnsupportedCodec, got %v", err)
+	}
+
+	_, err = NewReader("brotli", &bytes.Buffer{})
+	if err != ErrUnsupportedCodec {
+		t.Fatalf("new reader should have failed with ErrUnsupportedCodec, got %v", err)
+	}
+}
+
+// Tests codec selection from Accept-Encoding style headers.
+func TestNegotiate(t *testing.T) {
+
+	preferred := []string{Snappy, Gzip}
+
+	cases := []struct {
+		header string
+		codec  string
+	}{
+		{"", Identity},
+		{"gzip", Gzip},
+		{"gzip, snappy", Snappy},
+		{"snappy;q=0, gzip", Gzip},
+		{"GZIP;q=0.5", Gzip},
+		{"*", Snappy},
+		{"*, snappy;q=0", Gzip},
+		{"br, deflate", Identity},
+	}
+
+	for _, c := range cases {
+
+		codec := Negotiate(c.header, preferred)
+		if codec != c.codec {
+			t.Fatalf("negotiate of %q returned %s, expected %s", c.header, codec, c.codec)
+		}
+	}
+
+	codec := Negotiate("gzip", nil)
+	if codec != Identity {
+		t.Fatalf("negotiate without codecs returned %s, expected %s", codec, Identity)
+	}
+}
+
+// Tests that each write on a compressed connection reaches the peer without
+// waiting for more data, and that half-closing ends the peer stream.
+func TestConn_Stream(t *testing.T) {
+
+	for _, codec := range []string{Gzip, Snappy} {
+
+		client, server := net.Pipe()
+
+		cc, err := NewConn(client, codec)
+		if err != nil {
+			t.Fatalf("new conn for %s failed with err == %s", codec, err)
+		}
+
+		sc, err := NewConn(server, codec)
+		if err != nil {
+			t.Fatalf("new conn for %s failed with err == %s", codec, err)
+		}
+
+		go func() {
+			cc.Write([]byte("hello"))
+			cc.Write([]byte("world"))
+			cc.CloseWrite()
+		}()
+
+		buf := make([]byte, 5)
+
+		for _, expected := range []string{"hello", "world"} {
+
+			_, err = io.ReadFull(sc, buf)
+			if err != nil {
+				t.Fatalf("read with %s failed with err == %s", codec, err)
+			}
+
+			if string(buf) != expected {
+				t.Fatalf("read %q with %s, expected %q", buf, codec, expected)
+			}
+		}
+
+		_, err = sc.Read(buf)
+		if err != io.EOF {
+			t.Fatalf("read with %s should have failed with io.EOF, got %v", codec, err)
+		}
+
+		sc.Close()
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compress
+
+import (
+	"io"
+	"net"
+	"sync"
+)
+
+type closeWriter interface {
+	CloseWrite() error
+}
+
+// Conn compresses a connection stream in both directions. Each write is
+// flushed to the underlying connection, so that callers buffering their
+// writes keep control over latency.
+type Conn struct {
+	net.Conn
+	writer      Writer
+	reader      io.ReadCloser
+	writeLock   sync.Mutex
+	writeClosed bool
+}
+
+// NewConn returns a connection compressing data written to conn and
+// decompressing data read from it with codec.
+func NewConn(conn net.Conn, codec string) (c *Conn, err error) {
+
+	writer, err := NewWriter(codec, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := NewReader(codec, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	c = &Conn{
+		Conn:        conn,
+		writer:      writer,
+		reader:      reader,
+		writeClosed: false,
+	}
+
+	return c, nil
+}
+
+func (c *Conn) Read(p []byte) (n int, err error) {
+
+	return c.reader.Read(p)
+}
+
+func (c *Conn) Write(p []byte) (n int, err error) {
+
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	n, err = c.writer.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	err = c.writer.Flush()
+	if err != nil {
+		return n, err
+	}
+
+	return n, nil
+}
+
+// CloseWrite terminates the compressed stream and half-closes the underlying
+// connection, which is closed instead when it doesn't support half-close.
+func (c *Conn) CloseWrite() (err error) {
+
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	if !c.writeClosed {
+
+		c.writeClosed = true
+
+		err = c.writer.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	cw, ok := c.Conn.(closeWriter)
+	if !ok {
+		return c.Conn.Close()
+	}
+
+	return cw.CloseWrite()
+}
@@ -0,0 +1,218 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+const (
+	KindPrefix = "prefix"
+	KindRegexp = "regexp"
+	KindJSON   = "json"
+)
+
+var (
+	ErrInvalidFilter    = errors.New("filter: invalid filter expression")
+	ErrUnknownKind      = errors.New("filter: unknown filter kind")
+	ErrInvalidPredicate = errors.New("filter: invalid json predicate")
+)
+
+// Filter decides whether a record payload should be delivered to a consumer.
+type Filter interface {
+	Match(p []byte) bool
+}
+
+// Parse builds a Filter from an expression of the form "kind:argument".
+//
+// Supported kinds are:
+//
+//	prefix:<bytes>			payload starts with <bytes>
+//	regexp:<expression>		payload matches the regular expression
+//	json:<field> == <value>		payload is a JSON object whose field equals
+//					the JSON encoded <value>, != is also
+//					supported and fields can be dotted paths
+//
+// An empty expression returns a nil Filter, meaning every record matches.
+func Parse(expr string) (f Filter, err error) {
+
+	if expr == "" {
+		return nil, nil
+	}
+
+	index := strings.IndexByte(expr, ':')
+	if index == -1 {
+		return nil, ErrInvalidFilter
+	}
+
+	kind := expr[:index]
+	arg := expr[index+1:]
+
+	switch kind {
+	case KindPrefix:
+		f = &PrefixFilter{
+			Prefix: []byte(arg),
+		}
+
+	case KindRegexp:
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		f = &RegexpFilter{
+			Regexp: re,
+		}
+
+	case KindJSON:
+		f, err = parseJSONFilter(arg)
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, ErrUnknownKind
+	}
+
+	return f, nil
+}
+
+// Match is a nil safe helper that reports whether p matches f.
+func Match(f Filter, p []byte) (match bool) {
+
+	if f == nil {
+		return true
+	}
+
+	return f.Match(p)
+}
+
+type PrefixFilter struct {
+	Prefix []byte
+}
+
+func (pf *PrefixFilter) Match(p []byte) (match bool) {
+
+	return bytes.HasPrefix(p, pf.Prefix)
+}
+
+type RegexpFilter struct {
+	Regexp *regexp.Regexp
+}
+
+func (rf *RegexpFilter) Match(p []byte) (match bool) {
+
+	return rf.Regexp.Match(p)
+}
+
+type JSONFilter struct {
+	Path   []string
+	Value  interface{}
+	Negate bool
+}
+
+func parseJSONFilter(predicate string) (jf *JSONFilter, err error) {
+
+	index, op := findOperator(predicate)
+	if index == -1 {
+		return nil, ErrInvalidPredicate
+	}
+
+	field := strings.TrimSpace(predicate[:index])
+	rawValue := strings.TrimSpace(predicate[index+len(op):])
+
+	if field == "" || rawValue == "" {
+		return nil, ErrInvalidPredicate
+	}
+
+	var value interface{}
+
+	err = json.Unmarshal([]byte(rawValue), &value)
+	if err != nil {
+		return nil, ErrInvalidPredicate
+	}
+
+	jf = &JSONFilter{
+		Path:   strings.Split(field, "."),
+		Value:  value,
+		Negate: op == "!=",
+	}
+
+	return jf, nil
+}
+
+// findOperator returns the index of the first operator of a predicate, and
+// the operator itself. Operators are looked for left to right and before any
+// quote, so that those held by a string value are left alone.
+func findOperator(predicate string) (index int, op string) {
+
+	operators := []string{"==", "!="}
+
+	for i := 0; i < len(predicate); i++ {
+
+		if predicate[i] == '"' {
+			break
+		}
+
+		for _, op := range operators {
+			if strings.HasPrefix(predicate[i:], op) {
+				return i, op
+			}
+		}
+	}
+
+	return -1, ""
+}
+
+func (jf *JSONFilter) Match(p []byte) (match bool) {
+
+	var document interface{}
+
+	err := json.Unmarshal(p, &document)
+	if err != nil {
+		return false
+	}
+
+	current := document
+	found := true
+
+	for _, key := range jf.Path {
+
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			found = false
+			break
+		}
+
+		current, ok = object[key]
+		if !ok {
+			found = false
+			break
+		}
+	}
+
+	equal := found && reflect.DeepEqual(current, jf.Value)
+
+	if jf.Negate {
+		return !equal
+	}
+
+	return equal
+}
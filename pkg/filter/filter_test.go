@@ -0,0 +1,92 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"testing"
+)
+
+// Tests that an empty expression yields a filter matching every record.
+func TestParse_Empty(t *testing.T) {
+
+	f, err := Parse("")
+	if err != nil {
+		t.Fatalf("parse failed with err == %s", err)
+	}
+
+	if !Match(f, []byte("anything")) {
+		t.Fatalf("empty filter should match every record")
+	}
+}
+
+// Tests rejection of malformed expressions.
+func TestParse_Invalid(t *testing.T) {
+
+	expressions := []string{
+		"noprefix",
+		"unknown:value",
+		"regexp:(",
+		"json:type",
+		"json:type == ",
+		"json:type == unquoted",
+	}
+
+	for _, expr := range expressions {
+
+		_, err := Parse(expr)
+		if err == nil {
+			t.Fatalf("parse of %q should have failed", expr)
+		}
+	}
+}
+
+// Tests matching of every supported filter kind.
+func TestFilter_Match(t *testing.T) {
+
+	cases := []struct {
+		expr    string
+		payload string
+		match   bool
+	}{
+		{"prefix:ERROR", "ERROR disk full", true},
+		{"prefix:ERROR", "INFO started", false},
+		{"regexp:^user-[0-9]+$", "user-42", true},
+		{"regexp:^user-[0-9]+$", "user-abc", false},
+		{`json:type == "order.created"`, `{"type":"order.created"}`, true},
+		{`json:type == "order.created"`, `{"type":"order.deleted"}`, false},
+		{`json:type != "order.created"`, `{"type":"order.deleted"}`, true},
+		{`json:order.amount == 12`, `{"order":{"amount":12}}`, true},
+		{`json:order.amount == 12`, `{"order":{"amount":13}}`, false},
+		{`json:type == "order.created"`, `not json`, false},
+		{`json:missing == true`, `{"type":"order.created"}`, false},
+		{`json:type != "a==b"`, `{"type":"a==b"}`, false},
+		{`json:type != "a==b"`, `{"type":"c"}`, true},
+		{`json:type == "a!=b"`, `{"type":"a!=b"}`, true},
+		{`json:type == "a!=b"`, `{"type":"c"}`, false},
+	}
+
+	for _, c := range cases {
+
+		f, err := Parse(c.expr)
+		if err != nil {
+			t.Fatalf("parse of %q failed with err == %s", c.expr, err)
+		}
+
+		match := Match(f, []byte(c.payload))
+		if match != c.match {
+			t.Fatalf("filter %q on %q should have returned %t", c.expr, c.payload, c.match)
+		}
+	}
+}
@@ -17,6 +17,7 @@ package log
 import (
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/dataptive/styx/pkg/recio"
 )
@@ -147,6 +148,8 @@ type FaninWriter struct {
 	pendingLock     sync.Mutex
 	syncChan        chan SyncProgress
 	syncHandler     SyncHandler
+	limiter         Limiter
+	reserved        bool
 	notifierStop    chan struct{}
 	notifierDone    chan struct{}
 	closed          bool
@@ -166,6 +169,8 @@ func NewFaninWriter(f *Fanin, ioMode recio.IOMode) (fw *FaninWriter) {
 		pendingLock:     sync.Mutex{},
 		syncChan:        make(chan SyncProgress, 1),
 		syncHandler:     nil,
+		limiter:         nil,
+		reserved:        false,
 		notifierStop:    make(chan struct{}),
 		notifierDone:    make(chan struct{}),
 		closed:          false,
@@ -184,6 +189,12 @@ func (fw *FaninWriter) HandleSync(h SyncHandler) {
 	fw.syncHandler = h
 }
 
+// SetLimiter throttles writes with l.
+func (fw *FaninWriter) SetLimiter(l Limiter) {
+
+	fw.limiter = l
+}
+
 func (fw *FaninWriter) Close() (err error) {
 
 	fw.closeLock.Lock()
@@ -213,6 +224,30 @@ func (fw *FaninWriter) Write(r *Record) (n int, err error) {
 		return 0, ErrClosed
 	}
 
+	// A record retried after ErrMustFlush in manual mode was already
+	// reserved.
+	if fw.limiter != nil && !fw.reserved {
+
+		delay, err := fw.limiter.Reserve(len(*r))
+		if err != nil {
+			return 0, err
+		}
+
+		fw.reserved = true
+
+		if delay > 0 {
+
+			// Release the write lock while throttled to
+			// let other writers through.
+			err = fw.Flush()
+			if err != nil {
+				return 0, err
+			}
+
+			time.Sleep(delay)
+		}
+	}
+
 Retry:
 	if !fw.ownsLock {
 		fw.closeLock.Lock()
@@ -237,12 +272,15 @@ Retry:
 
 		err = fw.Flush()
 		if err != nil {
+			fw.reserved = false
 			return 0, err
 		}
 
 		goto Retry
 	}
 
+	fw.reserved = false
+
 	if err != nil {
 		return n, err
 	}
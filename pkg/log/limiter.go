@@ -0,0 +1,28 @@
+// Copyright 2021 Dataptive SAS.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"time"
+)
+
+// Limiter throttles the records flowing through a FaninWriter or a
+// LogReader.
+type Limiter interface {
+	// Reserve accounts for a record of size bytes and returns how long the
+	// caller must wait before letting it through, or an error when the
+	// record must be rejected.
+	Reserve(size int) (delay time.Duration, err error)
+}
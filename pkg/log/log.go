@@ -42,16 +42,17 @@ const (
 )
 
 var (
-	ErrExist      = errors.New("log: already exists")
-	ErrNotExist   = errors.New("log: does not exist")
-	ErrBadVersion = errors.New("log: bad version")
-	ErrCorrupt    = errors.New("log: corrupt")
-	ErrOutOfRange = errors.New("log: out of range")
-	ErrLagging    = errors.New("log: lagging")
-	ErrLocked     = errors.New("log: locked")
-	ErrOrphaned   = errors.New("log: orphaned")
-	ErrClosed     = errors.New("log: closed")
-	ErrTimeout    = errors.New("log: timeout")
+	ErrExist       = errors.New("log: already exists")
+	ErrNotExist    = errors.New("log: does not exist")
+	ErrBadVersion  = errors.New("log: bad version")
+	ErrCorrupt     = errors.New("log: corrupt")
+	ErrOutOfRange  = errors.New("log: out of range")
+	ErrLagging     = errors.New("log: lagging")
+	ErrLocked      = errors.New("log: locked")
+	ErrOrphaned    = errors.New("log: orphaned")
+	ErrClosed      = errors.New("log: closed")
+	ErrTimeout     = errors.New("log: timeout")
+	ErrInterrupted = errors.New("log: interrupted")
 
 	now = clock.New(time.Second)
 )
@@ -176,6 +177,41 @@ func Delete(path string) (err error) {
 	return nil
 }
 
+// Rename moves the log directory at path to newPath, which must not exist.
+// The log must be closed.
+func Rename(path string, newPath string) (err error) {
+
+	_, err = os.Lstat(newPath)
+	if err == nil {
+		return ErrExist
+	}
+
+	if !os.IsNotExist(err) {
+		return err
+	}
+
+	err = os.Rename(path, newPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrNotExist
+		}
+
+		return err
+	}
+
+	err = syncDirectory(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+
+	err = syncDirectory(filepath.Dir(newPath))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func Truncate(path string) (err error) {
 
 	names, err := listSegments(path)
@@ -710,6 +746,27 @@ func (l *Log) Backup(w io.Writer) (err error) {
 	return nil
 }
 
+// DropOldestSegment deletes the oldest segment of the log ahead of retention,
+// releasing its space. The last segment is never deleted, in which case
+// dropped is false.
+func (l *Log) DropOldestSegment() (dropped bool, err error) {
+
+	count := 0
+
+	err = l.deleteSegments(func(desc segmentDescriptor) bool {
+		count += 1
+		return count > 1
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	dropped = count > 0
+
+	return dropped, nil
+}
+
 func (l *Log) Subscribe(subscriber chan Stat) {
 
 	l.subscribersLock.Lock()
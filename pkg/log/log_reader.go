@@ -36,10 +36,12 @@ type LogReader struct {
 	startPosition int64
 	endPosition   int64
 	notifyChan    chan Stat
+	interrupt     chan struct{}
 	closed        bool
 	closeLock     sync.Mutex
 	deadline      <-chan time.Time
 	deadlineTimer *time.Timer
+	limiter       Limiter
 }
 
 func newLogReader(l *Log, bufferSize int, follow bool, ioMode recio.IOMode) (lr *LogReader, err error) {
@@ -60,9 +62,11 @@ func newLogReader(l *Log, bufferSize int, follow bool, ioMode recio.IOMode) (lr
 		startPosition: 0,
 		endPosition:   0,
 		notifyChan:    make(chan Stat, 1),
+		interrupt:     make(chan struct{}, 1),
 		closed:        false,
 		closeLock:     sync.Mutex{},
 		deadlineTimer: deadlineTimer,
+		limiter:       nil,
 	}
 
 	err = lr.openFirstSegment()
@@ -110,6 +114,23 @@ func (lr *LogReader) Close() (err error) {
 	return nil
 }
 
+// Interrupt wakes up a Fill waiting for records in follow mode, which then
+// fails with ErrInterrupted. Unlike other methods, it may be called from any
+// goroutine. When no Fill is waiting, the next one to wait is interrupted.
+func (lr *LogReader) Interrupt() {
+
+	select {
+	case lr.interrupt <- struct{}{}:
+	default:
+	}
+}
+
+// SetLimiter throttles reads with l.
+func (lr *LogReader) SetLimiter(l Limiter) {
+
+	lr.limiter = l
+}
+
 func (lr *LogReader) Tell() (position int64, offset int64) {
 
 	return lr.position, lr.offset
@@ -190,6 +211,18 @@ Retry:
 		lr.mustWait = true
 	}
 
+	if lr.limiter != nil {
+
+		delay, err := lr.limiter.Reserve(len(*r))
+		if err != nil {
+			return 0, err
+		}
+
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+	}
+
 	return n, nil
 }
 
@@ -205,6 +238,8 @@ Retry:
 			}
 		case <-lr.deadline:
 			return ErrTimeout
+		case <-lr.interrupt:
+			return ErrInterrupted
 		}
 
 		lr.updateBoundaries()
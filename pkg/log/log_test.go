@@ -288,6 +288,51 @@ func TestLog_Delete(t *testing.T) {
 	}
 }
 
+// Tests that logs can be renamed, but not over an existing log.
+func TestLog_Rename(t *testing.T) {
+
+	path := t.TempDir()
+	name := filepath.Join(path, "test")
+	newName := filepath.Join(path, "renamed")
+
+	config := DefaultConfig
+	options := DefaultOptions
+
+	testLog_Write(t, name, config, options, 10, 10, 0)
+	testLog_Write(t, filepath.Join(path, "other"), config, options, 0, 0, 0)
+
+	err := Rename(name, filepath.Join(path, "other"))
+	if err != ErrExist {
+		t.Fatalf("rename should have failed with err = %s but got %v", ErrExist, err)
+	}
+
+	err = Rename(name, newName)
+	if err != nil {
+		t.Fatalf("rename should have succeeded but failed with err = %s", err)
+	}
+
+	_, err = os.Stat(name)
+	if !os.IsNotExist(err) {
+		t.Fatal("rename should have moved the log but it still exists")
+	}
+
+	l, err := Open(newName, options)
+	if err != nil {
+		t.Fatalf("open should have succeeded but failed with err = %s", err)
+	}
+	defer l.Close()
+
+	stat := l.Stat()
+	if stat.EndPosition != 10 {
+		t.Fatalf("renamed log should hold 10 records but holds %d", stat.EndPosition)
+	}
+
+	err = Rename(name, newName+"-again")
+	if err != ErrNotExist {
+		t.Fatalf("rename should have failed with err = %s but got %v", ErrNotExist, err)
+	}
+}
+
 // Helper function for testing segment roll and retention.
 func testLog_Write(t *testing.T, path string, config Config, options Options, recordCount int, payloadSize int, delayMs int) {
 
@@ -1152,3 +1197,247 @@ func TestLog_ForceClose(t *testing.T) {
 		t.Fatalf("fill should have failed with error ErrClosed but got err = %s", err)
 	}
 }
+
+type delayLimiter struct {
+	delay time.Duration
+	count int
+	size  int
+}
+
+func (dl *delayLimiter) Reserve(size int) (delay time.Duration, err error) {
+
+	dl.count += 1
+	dl.size += size
+
+	return dl.delay, nil
+}
+
+// Tests that readers account each record to their limiter and wait for the
+// delay it returns.
+func TestLog_ReaderLimiter(t *testing.T) {
+
+	payloadSize := 100
+	recordCount := 10
+
+	config := DefaultConfig
+	options := DefaultOptions
+
+	path := t.TempDir()
+	name := filepath.Join(path, "test")
+
+	l, err := Create(name, config, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	lw, err := l.NewWriter(1<<20, recio.ModeAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := make([]byte, payloadSize)
+	r := Record(payload)
+
+	for i := 0; i < recordCount; i++ {
+		_, err := lw.Write(&r)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = lw.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = lw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lr, err := l.NewReader(1<<20, false, recio.ModeAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lr.Close()
+
+	limiter := &delayLimiter{delay: 10 * time.Millisecond}
+	lr.SetLimiter(limiter)
+
+	start := time.Now()
+
+	for i := 0; i < recordCount; i++ {
+		_, err := lr.Read(&r)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	elapsed := time.Since(start)
+
+	if limiter.count != recordCount {
+		t.Fatalf("limiter should have reserved %d records but reserved %d", recordCount, limiter.count)
+	}
+
+	if limiter.size != recordCount*payloadSize {
+		t.Fatalf("limiter should have reserved %d bytes but reserved %d", recordCount*payloadSize, limiter.size)
+	}
+
+	if elapsed < time.Duration(recordCount)*limiter.delay {
+		t.Fatalf("reads should have been delayed at least %s but took %s", time.Duration(recordCount)*limiter.delay, elapsed)
+	}
+}
+
+// Tests that fanin writers in manual mode account each record to their
+// limiter once, even when retried after ErrMustFlush.
+func TestLog_FaninWriterLimiter(t *testing.T) {
+
+	payloadSize := 100
+	recordCount := 100
+
+	config := DefaultConfig
+	options := DefaultOptions
+
+	path := t.TempDir()
+	name := filepath.Join(path, "test")
+
+	l, err := Create(name, config, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	lw, err := l.NewWriter(1<<10, recio.ModeManual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lw.Close()
+
+	fanin := NewFanin(lw)
+	defer fanin.Close()
+
+	fw := NewFaninWriter(fanin, recio.ModeManual)
+	defer fw.Close()
+
+	limiter := &delayLimiter{}
+	fw.SetLimiter(limiter)
+
+	payload := make([]byte, payloadSize)
+	r := Record(payload)
+
+	flushes := 0
+
+	for i := 0; i < recordCount; i++ {
+		_, err := fw.Write(&r)
+		if err == recio.ErrMustFlush {
+			flushes++
+
+			err = fw.Flush()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = fw.Write(&r)
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = fw.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if flushes == 0 {
+		t.Fatalf("writes should have returned ErrMustFlush")
+	}
+
+	if limiter.count != recordCount {
+		t.Fatalf("limiter should have reserved %d records but reserved %d", recordCount, limiter.count)
+	}
+
+	if limiter.size != recordCount*payloadSize {
+		t.Fatalf("limiter should have reserved %d bytes but reserved %d", recordCount*payloadSize, limiter.size)
+	}
+}
+
+// Tests that dropping segments ahead of retention deletes the oldest segment
+// first and never deletes the last segment.
+func TestLog_DropOldestSegment(t *testing.T) {
+
+	payloadSize := 100
+	recordCount := 10
+
+	config := DefaultConfig
+	config.SegmentMaxCount = 2
+	options := DefaultOptions
+
+	path := t.TempDir()
+	name := filepath.Join(path, "test")
+
+	l, err := Create(name, config, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	lw, err := l.NewWriter(1<<20, recio.ModeAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := make([]byte, payloadSize)
+	r := Record(payload)
+
+	for i := 0; i < recordCount; i++ {
+		_, err := lw.Write(&r)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = lw.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = lw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dropped, err := l.DropOldestSegment()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !dropped {
+		t.Fatal("oldest segment should have been dropped")
+	}
+
+	stat := l.Stat()
+
+	if stat.StartPosition != 2 {
+		t.Fatalf("log should start at position 2 but starts at %d", stat.StartPosition)
+	}
+
+	for dropped {
+		dropped, err = l.DropOldestSegment()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stat = l.Stat()
+
+	if stat.EndPosition != int64(recordCount) {
+		t.Fatalf("log should end at position %d but ends at %d", recordCount, stat.EndPosition)
+	}
+
+	if stat.StartPosition == stat.EndPosition {
+		t.Fatal("last segment should not have been dropped")
+	}
+}